@@ -4,42 +4,169 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"sync"
+	"time"
 
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+
+	"nser/internal/audit"
 	"nser/internal/db"
+	"nser/internal/secrets"
 	"nser/internal/tool"
+	"nser/internal/vault"
+	"nser/internal/vuln"
 )
 
+// healthTTL is how long cached tool health results are trusted.
+const healthTTL = 6 * time.Hour
+
 // App struct
 type App struct {
 	ctx    context.Context
-	db     *sql.DB
-	runner *tool.Runner
+	dbFlag string      // -db command line value, "" if not given
+	cves   *vuln.Store // offline NVD/KEV copy, shared by every database
+
+	// switching serialises database switches and restores.
+	switching sync.Mutex
+
+	// mu guards the open database and everything backed by it, which are
+	// replaced together when switching databases. Bindings and background
+	// tasks read them through the accessors below.
+	mu        sync.RWMutex
+	dbPath    string // absolute path of the open database
+	db        *sql.DB
+	runner    *tool.Runner
+	health    *tool.HealthCache
+	installer *tool.Installer
+	secrets   *secrets.Store
+	vault     *vault.Vault
+	audit     *audit.Log
 }
 
-// NewApp creates a new App application struct
-func NewApp() *App {
-	return &App{}
+// NewApp creates a new App application struct. dbPath is the -db flag
+// value; see db.ResolvePath.
+func NewApp(dbPath string) *App {
+	return &App{dbFlag: dbPath}
 }
 
 // startup is called when the app starts
 func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
 
-	// Open database (handles path, migrations, seeding internally)
-	conn, err := db.Open()
+	// Open database (handles migrations, seeding internally)
+	path, err := db.ResolvePath(a.dbFlag)
+	if err != nil {
+		fmt.Printf("database path: %v\n", err)
+		return
+	}
+	conn, err := db.Open(path)
 	if err != nil {
 		fmt.Printf("database open: %v\n", err)
 		return
 	}
-	a.db = conn
+
+	// Register user-defined tool files, shared by every database
+	loadToolFiles()
+
+	a.attach(conn, path)
+
+	// Open the offline CVE store, shared by every database
+	if a.cves, err = openCVEStore(); err != nil {
+		fmt.Printf("cve store: %v\n", err)
+	}
+
+	// Snapshot the open database on its configured schedule
+	go a.runSnapshots(ctx)
+
+	// Tell the frontend to refresh the health dashboard when PATH changes.
+	go tool.WatchPath(ctx, func() { //nolint:errcheck
+		runtime.EventsEmit(ctx, "tool:health:changed")
+	})
+}
+
+// attach makes conn the open database and builds everything backed by it.
+// Used at startup and when switching databases.
+func (a *App) attach(conn *sql.DB, path string) {
+	if err := db.AddRecentPath(path); err != nil {
+		fmt.Printf("recent databases: %v\n", err)
+	}
+
+	// Extend $PATH with directories tools were installed into (~/go/bin, ...),
+	// taking over any this database kept from before the search path file
+	searchPath, err := searchPathFile()
+	if err == nil {
+		err = tool.ImportSearchPath(a.ctx, conn, searchPath)
+	}
+	if err == nil {
+		err = tool.LoadSearchPath(searchPath)
+	}
+	if err != nil {
+		fmt.Printf("search path: %v\n", err)
+	}
+
+	// Register tools saved in this database alongside the built-ins
+	a.loadCustomTools(conn)
 
 	// Create tool runner backed by the global registry
-	a.runner = tool.NewRunner(tool.DefaultRegistry, a.db)
+	runner := tool.NewRunner(tool.DefaultRegistry, conn)
+	a.loadElevationPolicy(conn, runner)
+	store := a.openSecrets(conn, runner)
+	v := a.openVault(conn, runner)
+	log := a.openAudit(conn, runner)
+	health := tool.NewHealthCache(tool.DefaultRegistry, conn, healthTTL, runner.ElevationPolicy)
+	installer := tool.NewInstaller(tool.DefaultRegistry, conn, searchPath, runner.ElevationPolicy)
+
+	a.mu.Lock()
+	a.db, a.dbPath = conn, path
+	a.runner, a.health, a.installer = runner, health, installer
+	a.secrets, a.vault, a.audit = store, v, log
+	a.mu.Unlock()
+
+	// Drop runs and workspaces that have been in the trash too long
+	a.purgeExpiredTrash()
+}
+
+// conn returns the open database.
+func (a *App) conn() *sql.DB {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.db
+}
+
+// openPath returns the absolute path of the open database.
+func (a *App) openPath() string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.dbPath
+}
+
+// toolRunner returns the open database's tool runner.
+func (a *App) toolRunner() *tool.Runner {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.runner
+}
+
+// healthCache returns the open database's tool health cache.
+func (a *App) healthCache() *tool.HealthCache {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.health
+}
+
+// toolInstaller returns the open database's tool installer.
+func (a *App) toolInstaller() *tool.Installer {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.installer
 }
 
 // shutdown is called when the app exits
 func (a *App) shutdown(ctx context.Context) {
-	if a.db != nil {
-		a.db.Close()
+	if conn := a.conn(); conn != nil {
+		conn.Close()
+	}
+	if a.cves != nil {
+		a.cves.Close()
 	}
 }
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"nser/internal/audit"
+	"nser/internal/db"
+	"nser/internal/tool"
+)
+
+// ─── Audit Log ───────────────────────────────────────────────────────────────
+
+// openAudit opens the open database's audit log, signed with a key shared
+// by every database, and has the runner record runs in it. If it can't be
+// opened, changes and runs are refused until the database is reopened.
+func (a *App) openAudit(conn *sql.DB, runner *tool.Runner) *audit.Log {
+	log, err := loadAudit(conn)
+	if err != nil {
+		fmt.Printf("audit log: %v\n", err)
+		runner.SetAuditor(auditUnavailable{err})
+		return nil
+	}
+	runner.SetAuditor(log)
+	return log
+}
+
+// loadAudit opens conn's audit log with the shared key.
+func loadAudit(conn *sql.DB) (*audit.Log, error) {
+	dir, err := db.DataDir()
+	if err != nil {
+		return nil, err
+	}
+	return audit.Open(conn, filepath.Join(dir, "audit.key"))
+}
+
+// auditUnavailable is the runner's auditor while the audit log can't be
+// opened. It fails every record, so no run starts unrecorded.
+type auditUnavailable struct{ err error }
+
+func (u auditUnavailable) Record(context.Context, int64, string, any) error {
+	return fmt.Errorf("audit log is unavailable: %w", u.err)
+}
+
+// auditEntry is the audit log entry recording a change made by audited.
+type auditEntry struct {
+	workspaceID int64
+	action      string
+	detail      any
+}
+
+// audited runs change in a transaction and commits it together with the
+// entry change returns, so a change is never made without being recorded.
+// An entry without an action means nothing changed. Changes are refused
+// while the audit log is unavailable.
+func (a *App) audited(change func(tx *sql.Tx) (auditEntry, error)) error {
+	log, err := a.requireAudit()
+	if err != nil {
+		return err
+	}
+	tx, err := a.conn().BeginTx(a.ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+	e, err := change(tx)
+	if err != nil {
+		return err
+	}
+	if e.action == "" {
+		return nil
+	}
+	if err := log.RecordTx(a.ctx, tx, e.workspaceID, e.action, e.detail); err != nil {
+		return fmt.Errorf("audit log: %w", err)
+	}
+	return tx.Commit()
+}
+
+// record appends an action outside the database, such as an export, to
+// the audit log. Callers check requireAudit before acting, so nothing is
+// done that can't be recorded.
+func (a *App) record(workspaceID int64, action string, detail any) error {
+	log, err := a.requireAudit()
+	if err != nil {
+		return err
+	}
+	if err := log.Record(a.ctx, workspaceID, action, detail); err != nil {
+		return fmt.Errorf("audit log: %w", err)
+	}
+	return nil
+}
+
+// deleteRecorded deletes a row of table that has a workspace_id and
+// records the deletion as kind ("note", "evidence", ...).
+func (a *App) deleteRecorded(table, kind string, id int64) error {
+	err := a.audited(func(tx *sql.Tx) (auditEntry, error) {
+		var workspaceID int64
+		err := tx.QueryRowContext(a.ctx,
+			`DELETE FROM `+table+` WHERE id = ? RETURNING workspace_id`, id).Scan(&workspaceID)
+		if err != nil {
+			return auditEntry{}, err
+		}
+		return auditEntry{workspaceID, audit.ActionDelete, map[string]any{"type": kind, "id": id}}, nil
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("deleting %s: %w", kind, err)
+	}
+	return nil
+}
+
+// requireAudit returns the audit log, or an error if it failed to open.
+func (a *App) requireAudit() (*audit.Log, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.audit == nil {
+		return nil, fmt.Errorf("audit log is unavailable")
+	}
+	return a.audit, nil
+}
+
+// GetAuditLog returns a workspace's audit entries, oldest first, or with
+// workspaceID 0 the whole log.
+func (a *App) GetAuditLog(workspaceID int64) ([]audit.Entry, error) {
+	log, err := a.requireAudit()
+	if err != nil {
+		return nil, err
+	}
+	return log.Entries(a.ctx, workspaceID)
+}
+
+// VerifyAuditLog checks the audit log's hash chain for edited or removed
+// entries.
+func (a *App) VerifyAuditLog() (audit.Verification, error) {
+	log, err := a.requireAudit()
+	if err != nil {
+		return audit.Verification{}, err
+	}
+	return log.Verify(a.ctx)
+}
+
+// ExportAuditLog returns a workspace's audit entries (all of them for 0)
+// as a signed JSON document for the engagement file. The export itself is
+// recorded first, so it appears in what it exports.
+func (a *App) ExportAuditLog(workspaceID int64) (string, error) {
+	log, err := a.requireAudit()
+	if err != nil {
+		return "", err
+	}
+	if err := a.record(workspaceID, audit.ActionExport, map[string]any{"type": "audit_log"}); err != nil {
+		return "", err
+	}
+	out, err := log.ExportSigned(a.ctx, workspaceID)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+
+	"nser/internal/audit"
+	"nser/internal/db"
+)
+
+// ─── Backups ─────────────────────────────────────────────────────────────────
+
+// Defaults for automatic snapshots when a database has no saved settings.
+const (
+	defaultSnapshotHours = 24
+	defaultSnapshotKeep  = 7
+)
+
+// snapshotCheckInterval is how often the scheduler looks for a due snapshot.
+const snapshotCheckInterval = 10 * time.Minute
+
+// GetBackupSettings returns the open database's snapshot schedule.
+func (a *App) GetBackupSettings() (BackupSettings, error) {
+	s := BackupSettings{IntervalHours: defaultSnapshotHours, Keep: defaultSnapshotKeep}
+	for _, f := range []struct {
+		key string
+		dst *int
+	}{
+		{db.SettingSnapshotHours, &s.IntervalHours},
+		{db.SettingSnapshotKeep, &s.Keep},
+	} {
+		value, err := db.GetSetting(a.ctx, a.conn(), f.key)
+		if err != nil {
+			return BackupSettings{}, err
+		}
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return BackupSettings{}, fmt.Errorf("setting %s: %w", f.key, err)
+		}
+		*f.dst = n
+	}
+	return s, nil
+}
+
+// SetBackupSettings saves the snapshot schedule for the open database.
+func (a *App) SetBackupSettings(s BackupSettings) error {
+	if s.IntervalHours < 0 {
+		return fmt.Errorf("snapshot interval can't be negative")
+	}
+	if s.Keep < 1 {
+		return fmt.Errorf("keep at least one snapshot")
+	}
+	if err := db.SetSetting(a.ctx, a.conn(), db.SettingSnapshotHours, strconv.Itoa(s.IntervalHours)); err != nil {
+		return err
+	}
+	return db.SetSetting(a.ctx, a.conn(), db.SettingSnapshotKeep, strconv.Itoa(s.Keep))
+}
+
+// BackupDatabase writes a consistent copy of the open database to path.
+func (a *App) BackupDatabase(path string) error {
+	path, err := databasePath(path)
+	if err != nil {
+		return err
+	}
+	if _, err := a.requireAudit(); err != nil {
+		return err
+	}
+	if err := db.Backup(a.ctx, a.conn(), path); err != nil {
+		return err
+	}
+	return a.record(0, audit.ActionExport, map[string]string{"type": "backup", "path": path})
+}
+
+// GetSnapshots lists the open database's snapshots, newest first.
+func (a *App) GetSnapshots() ([]db.Snapshot, error) {
+	return db.ListSnapshots(a.openPath())
+}
+
+// SnapshotDatabase takes a snapshot now and prunes old ones.
+func (a *App) SnapshotDatabase() (db.Snapshot, error) {
+	settings, err := a.GetBackupSettings()
+	if err != nil {
+		return db.Snapshot{}, err
+	}
+	snap, err := db.TakeSnapshot(a.ctx, a.conn(), a.openPath())
+	if err != nil {
+		return db.Snapshot{}, err
+	}
+	if err := db.PruneSnapshots(a.openPath(), settings.Keep); err != nil {
+		return snap, fmt.Errorf("pruning snapshots: %w", err)
+	}
+	return snap, nil
+}
+
+// RestoreSnapshot replaces the open database with a snapshot or backup
+// file. The current state is snapshotted first so the restore can be
+// undone. Like switching databases, it refuses while runs are in progress.
+// Audit entries written since the snapshot are carried over, so the
+// restore can't erase them; a snapshot whose log isn't an earlier state
+// of this one is refused.
+func (a *App) RestoreSnapshot(path string) (DatabaseInfo, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return DatabaseInfo{}, err
+	}
+	a.switching.Lock()
+	defer a.switching.Unlock()
+	log, err := a.requireAudit()
+	if err != nil {
+		return DatabaseInfo{}, err
+	}
+	if err := db.CheckFile(a.ctx, path); err != nil {
+		return DatabaseInfo{}, fmt.Errorf("snapshot %s: %w", path, err)
+	}
+	entries, err := log.Entries(a.ctx, 0)
+	if err != nil {
+		return DatabaseInfo{}, err
+	}
+	head, err := audit.Head(a.ctx, a.conn())
+	if err != nil {
+		return DatabaseInfo{}, err
+	}
+	snapshotHead, err := snapshotAuditHead(a.ctx, path)
+	if err != nil {
+		return DatabaseInfo{}, err
+	}
+	tail, err := audit.EntriesAfter(entries, snapshotHead)
+	if err != nil {
+		return DatabaseInfo{}, fmt.Errorf("can't restore %s over this audit log: %w", path, err)
+	}
+	if _, err := db.TakeSnapshot(a.ctx, a.conn(), a.openPath()); err != nil {
+		return DatabaseInfo{}, fmt.Errorf("snapshotting current database: %w", err)
+	}
+
+	live := a.openPath()
+	if err := a.closeDatabase(); err != nil {
+		return DatabaseInfo{}, fmt.Errorf("can't restore: %w", err)
+	}
+	restoreErr := db.Replace(a.ctx, live, path)
+
+	// Reopen either way: the restored file, or the untouched original.
+	conn, err := db.Open(live)
+	if err != nil {
+		return DatabaseInfo{}, fmt.Errorf("reopening database: %w", err)
+	}
+	a.attach(conn, live)
+	runtime.EventsEmit(a.ctx, "database:changed", live)
+
+	if restoreErr != nil {
+		return DatabaseInfo{}, restoreErr
+	}
+	if err := db.IntegrityCheck(a.ctx, conn); err != nil {
+		return DatabaseInfo{}, fmt.Errorf("restored database: %w", err)
+	}
+	err = a.audited(func(tx *sql.Tx) (auditEntry, error) {
+		if err := audit.AppendTx(a.ctx, tx, tail); err != nil {
+			return auditEntry{}, err
+		}
+		return auditEntry{0, audit.ActionDatabaseRestore, map[string]any{
+			"path": path, "replacedHead": head, "snapshotHead": snapshotHead, "carried": len(tail),
+		}}, nil
+	})
+	if err != nil {
+		return DatabaseInfo{}, fmt.Errorf("restored database: %w", err)
+	}
+	return a.GetDatabaseInfo()
+}
+
+// snapshotAuditHead returns the head of the audit log in a snapshot file.
+func snapshotAuditHead(ctx context.Context, path string) (string, error) {
+	conn, err := db.OpenReadOnly(path)
+	if err != nil {
+		return "", fmt.Errorf("snapshot %s: %w", path, err)
+	}
+	defer conn.Close()
+	return audit.Head(ctx, conn)
+}
+
+// runSnapshots takes automatic snapshots of whichever database is open,
+// when the newest one is older than its configured interval.
+func (a *App) runSnapshots(ctx context.Context) {
+	ticker := time.NewTicker(snapshotCheckInterval)
+	defer ticker.Stop()
+	for {
+		if err := a.snapshotIfDue(); err != nil {
+			fmt.Printf("snapshot: %v\n", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// snapshotIfDue takes a snapshot if automatic snapshots are enabled and due.
+// It holds off database switches so the snapshot is of one database.
+func (a *App) snapshotIfDue() error {
+	a.switching.Lock()
+	defer a.switching.Unlock()
+	if a.conn() == nil {
+		return nil
+	}
+	settings, err := a.GetBackupSettings()
+	if err != nil || settings.IntervalHours == 0 {
+		return err
+	}
+	snaps, err := db.ListSnapshots(a.openPath())
+	if err != nil {
+		return err
+	}
+	interval := time.Duration(settings.IntervalHours) * time.Hour
+	if len(snaps) > 0 && time.Since(snaps[0].CreatedAt) < interval {
+		return nil
+	}
+	_, err = a.SnapshotDatabase()
+	return err
+}
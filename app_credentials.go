@@ -0,0 +1,120 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"nser/internal/audit"
+	"nser/internal/parse"
+)
+
+// ─── Credentials ─────────────────────────────────────────────────────────────
+
+// GetCredentials returns a workspace's credentials with their secrets
+// decrypted, validated ones first. Fails while the database is locked.
+func (a *App) GetCredentials(workspaceID int64) ([]Credential, error) {
+	rows, err := a.conn().QueryContext(a.ctx,
+		`SELECT id, workspace_id, COALESCE(asset_id, 0), host, port, service, realm, username, secret, type,
+		        validated, COALESCE(run_id, 0), created_at, updated_at
+		 FROM credentials WHERE workspace_id = ?
+		 ORDER BY validated DESC, host, port, service, username`,
+		workspaceID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("listing credentials: %w", err)
+	}
+	defer rows.Close()
+
+	result := []Credential{}
+	for rows.Next() {
+		var c Credential
+		var secret []byte
+		if err := rows.Scan(&c.ID, &c.WorkspaceID, &c.AssetID, &c.Host, &c.Port, &c.Service, &c.Realm, &c.Username,
+			&secret, &c.Type, &c.Validated, &c.RunID, &c.CreatedAt, &c.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scanning credential: %w", err)
+		}
+		if secret, err = a.openSealed(secret); err != nil {
+			return nil, fmt.Errorf("reading credential %d: %w", c.ID, err)
+		}
+		c.Secret = string(secret)
+		result = append(result, c)
+	}
+	return result, rows.Err()
+}
+
+// AddCredential stores a credential found outside the tool runner and
+// returns its ID.
+func (a *App) AddCredential(c Credential) (int64, error) {
+	switch c.Type {
+	case "":
+		c.Type = parse.CredentialPassword
+	case parse.CredentialPassword, parse.CredentialHash, parse.CredentialToken:
+	default:
+		return 0, fmt.Errorf("unknown credential type %q", c.Type)
+	}
+	c.Host = strings.TrimSpace(c.Host)
+	if c.Username == "" && c.Secret == "" {
+		return 0, fmt.Errorf("credential needs a username or a secret")
+	}
+	secret, err := a.sealSensitive([]byte(c.Secret))
+	if err != nil {
+		return 0, fmt.Errorf("sealing credential: %w", err)
+	}
+
+	var id int64
+	err = a.audited(func(tx *sql.Tx) (auditEntry, error) {
+		var assetID *int64
+		if c.Host != "" {
+			kind := "domain"
+			if net.ParseIP(c.Host) != nil {
+				kind = "ip"
+			}
+			var existing int64
+			if err := tx.QueryRowContext(a.ctx,
+				`SELECT id FROM assets WHERE workspace_id = ? AND type = ? AND value = ?`, c.WorkspaceID, kind, c.Host,
+			).Scan(&existing); err == nil {
+				assetID = &existing
+			}
+		}
+		now := time.Now()
+		res, err := tx.ExecContext(a.ctx,
+			`INSERT INTO credentials (workspace_id, asset_id, host, port, service, realm, username, secret, type, validated, created_at, updated_at)
+			 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			c.WorkspaceID, assetID, c.Host, c.Port, c.Service, c.Realm, c.Username, secret, c.Type, c.Validated, now, now,
+		)
+		if err != nil {
+			return auditEntry{}, fmt.Errorf("adding credential: %w", err)
+		}
+		if id, err = res.LastInsertId(); err != nil {
+			return auditEntry{}, err
+		}
+		return auditEntry{c.WorkspaceID, audit.ActionCredentialAdd, map[string]any{
+			"id": id, "host": c.Host, "port": c.Port, "service": c.Service, "username": c.Username, "type": c.Type,
+		}}, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// SetCredentialValidated marks whether a credential is known to work.
+func (a *App) SetCredentialValidated(id int64, validated bool) error {
+	res, err := a.conn().ExecContext(a.ctx,
+		`UPDATE credentials SET validated = ?, updated_at = ? WHERE id = ?`, validated, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("updating credential: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("credential %d not found", id)
+	}
+	return nil
+}
+
+// DeleteCredential deletes a credential.
+func (a *App) DeleteCredential(id int64) error {
+	return a.deleteRecorded("credentials", "credential", id)
+}
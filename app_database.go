@@ -0,0 +1,121 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+
+	"nser/internal/db"
+)
+
+// ─── Databases ───────────────────────────────────────────────────────────────
+
+// GetDatabaseInfo returns the open database file and the recent list.
+func (a *App) GetDatabaseInfo() (DatabaseInfo, error) {
+	paths, err := db.RecentPaths()
+	if err != nil {
+		return DatabaseInfo{}, err
+	}
+	info := DatabaseInfo{Path: a.openPath(), Recent: []RecentDatabase{}}
+	for _, p := range paths {
+		_, err := os.Stat(p)
+		info.Recent = append(info.Recent, RecentDatabase{Path: p, Exists: err == nil})
+	}
+	return info, nil
+}
+
+// OpenDatabase switches to an existing database file.
+func (a *App) OpenDatabase(path string) (DatabaseInfo, error) {
+	path, err := databasePath(path)
+	if err != nil {
+		return DatabaseInfo{}, err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return DatabaseInfo{}, fmt.Errorf("opening database: %w", err)
+	}
+	return a.switchDatabase(path)
+}
+
+// CreateDatabase creates a new database file and switches to it.
+func (a *App) CreateDatabase(path string) (DatabaseInfo, error) {
+	path, err := databasePath(path)
+	if err != nil {
+		return DatabaseInfo{}, err
+	}
+	if !strings.HasSuffix(path, ".db") {
+		path += ".db"
+	}
+	if _, err := os.Stat(path); err == nil {
+		return DatabaseInfo{}, fmt.Errorf("creating database: %s already exists", path)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return DatabaseInfo{}, fmt.Errorf("creating database: %w", err)
+	}
+	return a.switchDatabase(path)
+}
+
+// RemoveRecentDatabase drops a file from the recent list without deleting it.
+func (a *App) RemoveRecentDatabase(path string) error {
+	return db.RemoveRecentPath(path)
+}
+
+// databasePath cleans a user-supplied path into an absolute one.
+func databasePath(path string) (string, error) {
+	if strings.TrimSpace(path) == "" {
+		return "", fmt.Errorf("database path is required")
+	}
+	return filepath.Abs(path)
+}
+
+// switchDatabase closes the open database and attaches the one at path.
+// Nothing changes if it can't be closed (see closeDatabase).
+func (a *App) switchDatabase(path string) (DatabaseInfo, error) {
+	a.switching.Lock()
+	defer a.switching.Unlock()
+	if path == a.openPath() {
+		return a.GetDatabaseInfo()
+	}
+	conn, err := db.Open(path)
+	if err != nil {
+		return DatabaseInfo{}, err
+	}
+
+	if err := a.closeDatabase(); err != nil {
+		conn.Close()
+		return DatabaseInfo{}, fmt.Errorf("can't switch databases: %w", err)
+	}
+	a.attach(conn, path)
+
+	// Everything shown is from the old database; have the frontend reload.
+	runtime.EventsEmit(a.ctx, "database:changed", path)
+	return a.GetDatabaseInfo()
+}
+
+// closeDatabase closes the open database and everything backed by it. It
+// refuses while tool runs or installs are in progress, since they still
+// write to it; nothing changes on failure.
+func (a *App) closeDatabase() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.runner != nil {
+		if err := a.runner.Close(); err != nil {
+			return err
+		}
+	}
+	if a.installer != nil {
+		if err := a.installer.Close(); err != nil {
+			a.runner.Reopen()
+			return err
+		}
+	}
+
+	unloadCustomTools()
+	if a.db != nil {
+		a.db.Close()
+	}
+	a.secrets, a.vault, a.audit = nil, nil, nil
+	return nil
+}
@@ -2,7 +2,13 @@ package main
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+
+	"nser/internal/audit"
+	"nser/internal/db"
+	"nser/internal/tool"
 )
 
 // ─── Tool Documentation ─────────────────────────────────────────────────────
@@ -10,16 +16,17 @@ import (
 // GetToolDocs returns the documentation and examples for a tool.
 func (a *App) GetToolDocs(toolName string) (*ToolDocumentation, error) {
 	var docText string
-	err := a.db.QueryRowContext(a.ctx,
+	err := a.conn().QueryRowContext(a.ctx,
 		`SELECT COALESCE(documentation,'') FROM tool_docs WHERE tool_name = ?`, toolName,
 	).Scan(&docText)
 	if err != nil && err != sql.ErrNoRows {
 		return nil, fmt.Errorf("getting tool docs: %w", err)
 	}
 
-	rows, err := a.db.QueryContext(a.ctx,
-		`SELECT id, tool_name, title, COALESCE(description,''), command, sort_order
-		 FROM tool_examples WHERE tool_name = ? ORDER BY sort_order`, toolName,
+	rows, err := a.conn().QueryContext(a.ctx,
+		`SELECT id, tool_name, title, COALESCE(description,''), command, sort_order,
+		        seed_key IS NOT NULL, customized
+		 FROM tool_examples WHERE tool_name = ? AND hidden = 0 ORDER BY sort_order, id`, toolName,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("getting tool examples: %w", err)
@@ -29,7 +36,8 @@ func (a *App) GetToolDocs(toolName string) (*ToolDocumentation, error) {
 	var examples []ToolExample
 	for rows.Next() {
 		var ex ToolExample
-		if err := rows.Scan(&ex.ID, &ex.ToolName, &ex.Title, &ex.Description, &ex.Command, &ex.SortOrder); err != nil {
+		if err := rows.Scan(&ex.ID, &ex.ToolName, &ex.Title, &ex.Description, &ex.Command, &ex.SortOrder,
+			&ex.Shipped, &ex.Customized); err != nil {
 			return nil, fmt.Errorf("scanning example: %w", err)
 		}
 		examples = append(examples, ex)
@@ -40,3 +48,255 @@ func (a *App) GetToolDocs(toolName string) (*ToolDocumentation, error) {
 		Examples:      examples,
 	}, rows.Err()
 }
+
+// UpdateToolDoc replaces a tool's documentation markdown. Edited docs are
+// no longer refreshed by shipped updates until reset.
+func (a *App) UpdateToolDoc(toolName, documentation string) error {
+	_, err := a.conn().ExecContext(a.ctx,
+		`INSERT INTO tool_docs (tool_name, documentation, customized) VALUES (?, ?, 1)
+		 ON CONFLICT(tool_name) DO UPDATE SET
+		     documentation = excluded.documentation, customized = 1, updated_at = CURRENT_TIMESTAMP`,
+		toolName, documentation,
+	)
+	if err != nil {
+		return fmt.Errorf("updating tool docs: %w", err)
+	}
+	return nil
+}
+
+// AddExample adds a user example to a tool. A zero SortOrder appends it.
+func (a *App) AddExample(ex ToolExample) (*ToolExample, error) {
+	if ex.Title == "" || ex.Command == "" {
+		return nil, fmt.Errorf("example needs a title and a command")
+	}
+	if ex.SortOrder == 0 {
+		err := a.conn().QueryRowContext(a.ctx,
+			`SELECT COALESCE(MAX(sort_order), 0) + 1 FROM tool_examples WHERE tool_name = ?`, ex.ToolName,
+		).Scan(&ex.SortOrder)
+		if err != nil {
+			return nil, fmt.Errorf("getting example order: %w", err)
+		}
+	}
+	res, err := a.conn().ExecContext(a.ctx,
+		`INSERT INTO tool_examples (tool_name, title, description, command, sort_order) VALUES (?, ?, ?, ?, ?)`,
+		ex.ToolName, ex.Title, ex.Description, ex.Command, ex.SortOrder,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("adding example: %w", err)
+	}
+	ex.ID, _ = res.LastInsertId()
+	ex.Shipped, ex.Customized = false, false
+	return &ex, nil
+}
+
+// UpdateExample edits an example's title, description and command.
+func (a *App) UpdateExample(ex ToolExample) error {
+	if ex.Title == "" || ex.Command == "" {
+		return fmt.Errorf("example needs a title and a command")
+	}
+	_, err := a.conn().ExecContext(a.ctx,
+		`UPDATE tool_examples SET title = ?, description = ?, command = ?, customized = 1 WHERE id = ?`,
+		ex.Title, ex.Description, ex.Command, ex.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("updating example: %w", err)
+	}
+	return nil
+}
+
+// ReorderExamples sets the display order of a tool's examples to the order
+// of exampleIDs.
+func (a *App) ReorderExamples(toolName string, exampleIDs []int64) error {
+	tx, err := a.conn().BeginTx(a.ctx, nil)
+	if err != nil {
+		return fmt.Errorf("reordering examples: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	for i, id := range exampleIDs {
+		if _, err := tx.ExecContext(a.ctx,
+			`UPDATE tool_examples SET sort_order = ? WHERE id = ? AND tool_name = ?`, i+1, id, toolName,
+		); err != nil {
+			return fmt.Errorf("reordering examples: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// DeleteExample removes an example. Shipped examples are hidden instead so
+// they aren't re-seeded on the next start; ResetToolDocs brings them back.
+func (a *App) DeleteExample(id int64) error {
+	return a.audited(func(tx *sql.Tx) (auditEntry, error) {
+		var toolName, title string
+		var shipped bool
+		err := tx.QueryRowContext(a.ctx,
+			`SELECT tool_name, title, seed_key IS NOT NULL FROM tool_examples WHERE id = ?`, id,
+		).Scan(&toolName, &title, &shipped)
+		if errors.Is(err, sql.ErrNoRows) {
+			return auditEntry{}, nil
+		}
+		if err != nil {
+			return auditEntry{}, fmt.Errorf("deleting example: %w", err)
+		}
+		query := `DELETE FROM tool_examples WHERE id = ?`
+		if shipped {
+			query = `UPDATE tool_examples SET hidden = 1 WHERE id = ?`
+		}
+		if _, err := tx.ExecContext(a.ctx, query, id); err != nil {
+			return auditEntry{}, fmt.Errorf("deleting example: %w", err)
+		}
+		return auditEntry{0, audit.ActionExampleDelete, map[string]any{
+			"id": id, "tool": toolName, "title": title, "shipped": shipped,
+		}}, nil
+	})
+}
+
+// ResetToolDocs restores a tool's shipped documentation and examples,
+// discarding edits. Examples the user added are kept.
+func (a *App) ResetToolDocs(toolName string) error {
+	return db.ResetToolDocs(a.ctx, a.conn(), toolName)
+}
+
+// ExportToolDocs returns the documentation and examples of every tool as a
+// JSON document suitable for ImportToolDocs.
+func (a *App) ExportToolDocs() (string, error) {
+	if _, err := a.requireAudit(); err != nil {
+		return "", err
+	}
+	rows, err := a.conn().QueryContext(a.ctx,
+		`SELECT tool_name FROM tool_docs UNION SELECT tool_name FROM tool_examples WHERE hidden = 0 ORDER BY 1`)
+	if err != nil {
+		return "", fmt.Errorf("listing documented tools: %w", err)
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return "", fmt.Errorf("scanning tool name: %w", err)
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	bundles := make([]ToolDocsBundle, 0, len(names))
+	for _, name := range names {
+		docs, err := a.GetToolDocs(name)
+		if err != nil {
+			return "", err
+		}
+		bundles = append(bundles, ToolDocsBundle{
+			ToolName:      name,
+			Documentation: docs.Documentation,
+			Examples:      docs.Examples,
+		})
+	}
+	out, err := json.MarshalIndent(bundles, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("encoding tool docs: %w", err)
+	}
+	if err := a.record(0, audit.ActionToolDocsExport, map[string]int{"tools": len(bundles)}); err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// ImportToolDocs merges an ExportToolDocs document into the database, all
+// or none. Documentation is replaced, and marked customized only when it
+// differs from what is shipped, so importing an export of unedited docs
+// doesn't hold them back from shipped updates. Examples are added unless a
+// visible example with the same title and command already exists. Returns
+// the number of examples added.
+func (a *App) ImportToolDocs(data string) (int, error) {
+	var bundles []ToolDocsBundle
+	if err := json.Unmarshal([]byte(data), &bundles); err != nil {
+		return 0, fmt.Errorf("decoding tool docs: %w", err)
+	}
+
+	tx, err := a.conn().BeginTx(a.ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("importing tool docs: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	added := 0
+	for _, b := range bundles {
+		if shipped, ok := db.ShippedDoc(b.ToolName); b.Documentation != "" && (!ok || b.Documentation != shipped) {
+			if _, err := tx.ExecContext(a.ctx,
+				`INSERT INTO tool_docs (tool_name, documentation, customized) VALUES (?, ?, 1)
+				 ON CONFLICT(tool_name) DO UPDATE SET
+				     documentation = excluded.documentation, customized = 1, updated_at = CURRENT_TIMESTAMP
+				 WHERE tool_docs.documentation IS NOT excluded.documentation`,
+				b.ToolName, b.Documentation,
+			); err != nil {
+				return 0, fmt.Errorf("importing %s docs: %w", b.ToolName, err)
+			}
+		}
+		for _, ex := range b.Examples {
+			if ex.Title == "" || ex.Command == "" {
+				return 0, fmt.Errorf("%s example needs a title and a command", b.ToolName)
+			}
+			res, err := tx.ExecContext(a.ctx,
+				`INSERT INTO tool_examples (tool_name, title, description, command, sort_order)
+				 SELECT ?, ?, ?, ?, (SELECT COALESCE(MAX(sort_order), 0) + 1 FROM tool_examples WHERE tool_name = ?)
+				 WHERE NOT EXISTS (SELECT 1 FROM tool_examples
+				                   WHERE tool_name = ? AND title = ? AND command = ? AND hidden = 0)`,
+				b.ToolName, ex.Title, ex.Description, ex.Command, b.ToolName,
+				b.ToolName, ex.Title, ex.Command,
+			)
+			if err != nil {
+				return 0, fmt.Errorf("importing example %q: %w", ex.Title, err)
+			}
+			n, _ := res.RowsAffected()
+			added += int(n)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("importing tool docs: %w", err)
+	}
+	return added, nil
+}
+
+// UseExample launches a documented example through RunToolStreaming. The
+// example's placeholder targets (10.0.0.1, example.com, wordlist.txt) are
+// replaced with the selected asset, or the workspace target when assetID is 0.
+// extraArgs are appended to the example's arguments. The example runs as
+// written, without the tool's default args.
+func (a *App) UseExample(exampleID, workspaceID, assetID int64, extraArgs []string) (*tool.StreamStartResult, error) {
+	var toolName, command string
+	err := a.conn().QueryRowContext(a.ctx,
+		`SELECT tool_name, command FROM tool_examples WHERE id = ?`, exampleID,
+	).Scan(&toolName, &command)
+	if err != nil {
+		return nil, fmt.Errorf("getting example: %w", err)
+	}
+
+	def, err := tool.DefaultRegistry.Get(toolName)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := tool.ExampleTemplate(def, command)
+	if err != nil {
+		return nil, err
+	}
+
+	var assetIDs []int64
+	if assetID != 0 {
+		assetIDs = []int64{assetID}
+	}
+	targets, err := a.assetTargets(workspaceID, assetIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.toolRunner().RunStreaming(a.ctx, tool.RunRequest{
+		WorkspaceID: workspaceID,
+		ToolName:    toolName,
+		Target:      targets[0],
+		RawArgs:     extraArgs,
+		ArgTemplate: tmpl,
+	})
+}
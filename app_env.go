@@ -0,0 +1,112 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"nser/internal/audit"
+	"nser/internal/db"
+	"nser/internal/secrets"
+	"nser/internal/tool"
+)
+
+// ─── Secrets ─────────────────────────────────────────────────────────────────
+
+// openSecrets opens the encrypted secrets store and hands it to the runner.
+// Without it, runs still work but secret-backed env vars are left out.
+func (a *App) openSecrets(conn *sql.DB, runner *tool.Runner) *secrets.Store {
+	dir, err := db.DataDir()
+	if err != nil {
+		fmt.Printf("secrets: %v\n", err)
+		return nil
+	}
+	store, err := secrets.Open(conn, filepath.Join(dir, "secrets.key"))
+	if err != nil {
+		fmt.Printf("secrets: %v\n", err)
+		return nil
+	}
+	runner.SetSecrets(store)
+	return store
+}
+
+// requireSecrets returns the store, or an error if it failed to open.
+func (a *App) requireSecrets() (*secrets.Store, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.secrets == nil {
+		return nil, fmt.Errorf("secrets store is unavailable")
+	}
+	return a.secrets, nil
+}
+
+// GetSecrets lists stored secret names. Values are never returned.
+func (a *App) GetSecrets() ([]secrets.Info, error) {
+	s, err := a.requireSecrets()
+	if err != nil {
+		return nil, err
+	}
+	return s.List(a.ctx)
+}
+
+// SetSecret stores a secret, referenced from env vars as {{secret:name}}.
+func (a *App) SetSecret(name, value string) error {
+	s, err := a.requireSecrets()
+	if err != nil {
+		return err
+	}
+	return s.Set(a.ctx, name, value)
+}
+
+// DeleteSecret removes a secret.
+func (a *App) DeleteSecret(name string) error {
+	s, err := a.requireSecrets()
+	if err != nil {
+		return err
+	}
+	return a.audited(func(tx *sql.Tx) (auditEntry, error) {
+		deleted, err := s.DeleteTx(a.ctx, tx, name)
+		if err != nil || !deleted {
+			return auditEntry{}, err
+		}
+		return auditEntry{0, audit.ActionSecretDelete, map[string]string{"name": name}}, nil
+	})
+}
+
+// ─── Environment Variables ───────────────────────────────────────────────────
+
+// GetEnvVars returns the env vars applied to a workspace's runs: its own and
+// the global ones (workspace ID 0).
+func (a *App) GetEnvVars(workspaceID int64) ([]tool.EnvVar, error) {
+	return tool.ListEnvVars(a.ctx, a.conn(), workspaceID)
+}
+
+// SaveEnvVar creates or updates an env var. Workspace ID 0 applies it to
+// every workspace; an empty tool name applies it to every tool.
+func (a *App) SaveEnvVar(v tool.EnvVar) (tool.EnvVar, error) {
+	if v.ToolName != "" {
+		if _, err := tool.DefaultRegistry.Get(v.ToolName); err != nil {
+			return tool.EnvVar{}, err
+		}
+	}
+	return tool.SaveEnvVar(a.ctx, a.conn(), v)
+}
+
+// DeleteEnvVar removes an env var.
+func (a *App) DeleteEnvVar(id int64) error {
+	return a.audited(func(tx *sql.Tx) (auditEntry, error) {
+		var workspaceID int64
+		var toolName, name string
+		err := tx.QueryRowContext(a.ctx,
+			`DELETE FROM run_env WHERE id = ? RETURNING COALESCE(workspace_id, 0), tool_name, name`, id,
+		).Scan(&workspaceID, &toolName, &name)
+		if errors.Is(err, sql.ErrNoRows) {
+			return auditEntry{}, nil
+		}
+		if err != nil {
+			return auditEntry{}, fmt.Errorf("deleting env var: %w", err)
+		}
+		return auditEntry{workspaceID, audit.ActionEnvVarDelete, map[string]any{"id": id, "tool": toolName, "name": name}}, nil
+	})
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"nser/internal/db"
+	"nser/internal/vuln"
+)
+
+// ─── Findings ────────────────────────────────────────────────────────────────
+
+// openCVEStore opens ~/.nser/cve.db.
+func openCVEStore() (*vuln.Store, error) {
+	dir, err := db.DataDir()
+	if err != nil {
+		return nil, err
+	}
+	return vuln.Open(filepath.Join(dir, "cve.db"))
+}
+
+// cveStore returns the open CVE store or an error if it failed to open.
+func (a *App) cveStore() (*vuln.Store, error) {
+	if a.cves == nil {
+		return nil, fmt.Errorf("cve store is not open")
+	}
+	return a.cves, nil
+}
+
+// ImportVulnFeed imports an NVD JSON feed (2.0 or 1.1) or the CISA KEV
+// catalog from path, plain or gzipped, into the offline CVE store.
+func (a *App) ImportVulnFeed(path string) (vuln.ImportResult, error) {
+	store, err := a.cveStore()
+	if err != nil {
+		return vuln.ImportResult{}, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return vuln.ImportResult{}, fmt.Errorf("opening feed: %w", err)
+	}
+	defer f.Close()
+	res, err := store.Import(a.ctx, f, filepath.Base(path))
+	if err != nil {
+		return vuln.ImportResult{}, fmt.Errorf("importing %s: %w", filepath.Base(path), err)
+	}
+	return res, nil
+}
+
+// GetVulnStoreStatus reports how many CVEs and KEV entries are available
+// offline.
+func (a *App) GetVulnStoreStatus() (vuln.Status, error) {
+	store, err := a.cveStore()
+	if err != nil {
+		return vuln.Status{}, err
+	}
+	return store.Status(a.ctx)
+}
+
+// MatchCVEs matches a workspace's open services against the CVE store and
+// records each hit as a suggested finding. Re-running refreshes CVSS and
+// KEV data but keeps the status a tester already gave a finding.
+func (a *App) MatchCVEs(workspaceID int64) (MatchSummary, error) {
+	store, err := a.cveStore()
+	if err != nil {
+		return MatchSummary{}, err
+	}
+
+	type service struct {
+		portID, assetID                int64
+		product, version, cpe, address string
+	}
+	rows, err := a.conn().QueryContext(a.ctx,
+		`SELECT p.id, a.id, a.value, COALESCE(p.product, ''), COALESCE(p.version, ''), COALESCE(p.cpe, '')
+		 FROM ports p JOIN assets a ON a.id = p.asset_id
+		 WHERE a.workspace_id = ? AND p.state = 'open'`,
+		workspaceID,
+	)
+	if err != nil {
+		return MatchSummary{}, fmt.Errorf("listing services: %w", err)
+	}
+	var services []service
+	for rows.Next() {
+		var s service
+		if err := rows.Scan(&s.portID, &s.assetID, &s.address, &s.product, &s.version, &s.cpe); err != nil {
+			rows.Close()
+			return MatchSummary{}, fmt.Errorf("scanning service: %w", err)
+		}
+		services = append(services, s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return MatchSummary{}, err
+	}
+
+	tx, err := a.conn().BeginTx(a.ctx, nil)
+	if err != nil {
+		return MatchSummary{}, err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	var summary MatchSummary
+	now := time.Now()
+	for _, s := range services {
+		if _, ok := vuln.ServiceCPE(s.cpe, s.product, s.version); !ok {
+			continue
+		}
+		summary.Services++
+		matches, err := store.Match(a.ctx, s.cpe, s.product, s.version)
+		if err != nil {
+			return MatchSummary{}, err
+		}
+		for _, m := range matches {
+			title := strings.TrimSpace(fmt.Sprintf("%s in %s %s", m.CVE, s.product, s.version))
+			res, err := tx.ExecContext(a.ctx,
+				`INSERT INTO findings (workspace_id, asset_id, port_id, cve_id, title, description, severity,
+				   cvss, cvss_vector, kev, status, source, created_at, updated_at)
+				 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 'suggested', 'nvd', ?, ?)
+				 ON CONFLICT(port_id, cve_id) DO NOTHING`,
+				workspaceID, s.assetID, s.portID, m.CVE, title, m.Description, m.Severity,
+				m.CVSS, m.Vector, m.KEV, now, now,
+			)
+			if err != nil {
+				return MatchSummary{}, fmt.Errorf("recording %s: %w", m.CVE, err)
+			}
+			if n, _ := res.RowsAffected(); n > 0 {
+				summary.Added++
+			} else if _, err := tx.ExecContext(a.ctx,
+				`UPDATE findings SET description = ?, severity = ?, cvss = ?, cvss_vector = ?, kev = ?, updated_at = ?
+				 WHERE port_id = ? AND cve_id = ?`,
+				m.Description, m.Severity, m.CVSS, m.Vector, m.KEV, now, s.portID, m.CVE,
+			); err != nil {
+				return MatchSummary{}, fmt.Errorf("refreshing %s: %w", m.CVE, err)
+			}
+			summary.Matched++
+		}
+	}
+	return summary, tx.Commit()
+}
+
+// GetFindings returns a workspace's findings, known-exploited and highest
+// CVSS first.
+func (a *App) GetFindings(workspaceID int64) ([]Finding, error) {
+	rows, err := a.conn().QueryContext(a.ctx,
+		`SELECT f.id, f.workspace_id, f.asset_id, a.value, COALESCE(f.port_id, 0), COALESCE(p.port, 0),
+		        COALESCE(p.protocol, ''), COALESCE(p.product, ''), COALESCE(p.version, ''), f.cve_id, f.title,
+		        f.description, f.severity, f.cvss, f.cvss_vector, f.kev, f.status, f.source, f.created_at, f.updated_at
+		 FROM findings f
+		 JOIN assets a ON a.id = f.asset_id
+		 LEFT JOIN ports p ON p.id = f.port_id
+		 WHERE f.workspace_id = ?
+		 ORDER BY f.kev DESC, f.cvss DESC, a.value, p.port`,
+		workspaceID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("listing findings: %w", err)
+	}
+	defer rows.Close()
+
+	result := []Finding{}
+	for rows.Next() {
+		var f Finding
+		if err := rows.Scan(&f.ID, &f.WorkspaceID, &f.AssetID, &f.Address, &f.PortID, &f.Port, &f.Protocol,
+			&f.Product, &f.Version, &f.CVE, &f.Title, &f.Description, &f.Severity, &f.CVSS, &f.CVSSVector,
+			&f.KEV, &f.Status, &f.Source, &f.CreatedAt, &f.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scanning finding: %w", err)
+		}
+		result = append(result, f)
+	}
+	return result, rows.Err()
+}
+
+// SetFindingStatus confirms or dismisses a finding, or puts it back to
+// suggested.
+func (a *App) SetFindingStatus(id int64, status string) error {
+	switch status {
+	case "suggested", "confirmed", "dismissed":
+	default:
+		return fmt.Errorf("unknown finding status %q", status)
+	}
+	res, err := a.conn().ExecContext(a.ctx,
+		`UPDATE findings SET status = ?, updated_at = ? WHERE id = ?`, status, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("updating finding: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("finding %d not found", id)
+	}
+	return nil
+}
@@ -1,20 +1,45 @@
 package main
 
-import "fmt"
+import (
+	"encoding/json"
+	"fmt"
+
+	"nser/internal/audit"
+	"nser/internal/db"
+	"nser/internal/tool"
+)
 
 // ─── Command History ─────────────────────────────────────────────────────────
 
 // GetWorkspaceHistory returns past tool runs for a workspace.
 func (a *App) GetWorkspaceHistory(workspaceID int64) ([]CommandRun, error) {
-	rows, err := a.db.QueryContext(a.ctx,
+	return a.workspaceHistory(workspaceID, nil)
+}
+
+// GetWorkspaceHistoryByTags returns a workspace's runs tagged with every
+// one of tags.
+func (a *App) GetWorkspaceHistoryByTags(workspaceID int64, tags []string) ([]CommandRun, error) {
+	return a.workspaceHistory(workspaceID, tags)
+}
+
+func (a *App) workspaceHistory(workspaceID int64, tags []string) ([]CommandRun, error) {
+	filter, filterArgs, err := tagFilter("run", tags)
+	if err != nil {
+		return nil, err
+	}
+	runTags, err := a.workspaceTags(workspaceID, "run")
+	if err != nil {
+		return nil, err
+	}
+	rows, err := a.conn().QueryContext(a.ctx,
 		`SELECT id, workspace_id, tool_name, target,
-		        COALESCE(args,''), COALESCE(command_line,''),
-		        status, exit_code,
+		        COALESCE(args,''), COALESCE(args_json,''), COALESCE(options_json,''), COALESCE(command_line,''),
+		        COALESCE(elevation,''), COALESCE(network_json,''), status, exit_code,
 		        started_at, COALESCE(completed_at,'')
 		 FROM tool_runs
-		 WHERE workspace_id = ?
+		 WHERE workspace_id = ? AND deleted_at IS NULL`+filter+`
 		 ORDER BY started_at DESC`,
-		workspaceID,
+		append([]any{workspaceID}, filterArgs...)...,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("querying command history: %w", err)
@@ -24,11 +49,22 @@ func (a *App) GetWorkspaceHistory(workspaceID int64) ([]CommandRun, error) {
 	var result []CommandRun
 	for rows.Next() {
 		var r CommandRun
+		var argsJSON, optionsJSON, networkJSON string
 		if err := rows.Scan(&r.ID, &r.WorkspaceID, &r.ToolName, &r.Target,
-			&r.Args, &r.CommandLine, &r.Status, &r.ExitCode,
+			&r.Args, &argsJSON, &optionsJSON, &r.CommandLine, &r.Elevation, &networkJSON, &r.Status, &r.ExitCode,
 			&r.StartedAt, &r.CompletedAt); err != nil {
 			return nil, fmt.Errorf("scanning tool run: %w", err)
 		}
+		if r.RawArgs, r.Options, err = tool.DecodeRunArgs(r.Args, argsJSON, optionsJSON); err != nil {
+			return nil, fmt.Errorf("decoding run %d: %w", r.ID, err)
+		}
+		if networkJSON != "" {
+			r.Network = new(tool.NetworkProfile)
+			if err := json.Unmarshal([]byte(networkJSON), r.Network); err != nil {
+				return nil, fmt.Errorf("decoding run %d network profile: %w", r.ID, err)
+			}
+		}
+		r.Tags = runTags[r.ID]
 		result = append(result, r)
 	}
 	return result, rows.Err()
@@ -37,17 +73,33 @@ func (a *App) GetWorkspaceHistory(workspaceID int64) ([]CommandRun, error) {
 // GetRunOutput returns the raw output of a specific tool run.
 func (a *App) GetRunOutput(runID int64) (string, error) {
 	var output []byte
-	err := a.db.QueryRowContext(a.ctx,
+	err := a.conn().QueryRowContext(a.ctx,
 		`SELECT COALESCE(raw_output, '') FROM tool_runs WHERE id = ?`, runID,
 	).Scan(&output)
 	if err != nil {
 		return "", fmt.Errorf("getting run output: %w", err)
 	}
+	if output, err = a.openSealed(output); err != nil {
+		return "", fmt.Errorf("getting run output: %w", err)
+	}
 	return string(output), nil
 }
 
-// DeleteRun deletes a tool run record.
+// DeleteRun moves a tool run to the trash.
 func (a *App) DeleteRun(runID int64) error {
-	_, err := a.db.ExecContext(a.ctx, `DELETE FROM tool_runs WHERE id = ?`, runID)
-	return err
+	return a.changeTrash(db.MoveToTrash, "run", runID, audit.ActionRunDelete)
+}
+
+// RerunRun replays a past run with the same tool, options and arguments,
+// including the passwords stored sealed apart from them. An empty newTarget
+// reuses the original target. Runs in the trash can't be rerun.
+func (a *App) RerunRun(runID int64, newTarget string) (*tool.StreamStartResult, error) {
+	req, err := a.toolRunner().StoredRequest(a.ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	if newTarget != "" {
+		req.Target = newTarget
+	}
+	return a.toolRunner().RunStreaming(a.ctx, req)
 }
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ─── Service Inventory ───────────────────────────────────────────────────────
+
+// GetServiceInventory groups a workspace's open ports by product and
+// version, most widespread first, for pivoting and report prioritisation.
+// Ports are filled in by the nmap (-sV) and masscan (--banners) parsers.
+func (a *App) GetServiceInventory(workspaceID int64) ([]ServiceGroup, error) {
+	rows, err := a.conn().QueryContext(a.ctx,
+		`SELECT p.id, a.id, a.value, p.port, p.protocol, COALESCE(p.service, ''), COALESCE(p.product, ''),
+		        COALESCE(p.version, ''), COALESCE(p.extra_info, ''), COALESCE(p.cpe, ''), COALESCE(p.banner, ''),
+		        COALESCE(p.tls, 0), COALESCE(p.run_id, 0)
+		 FROM ports p JOIN assets a ON a.id = p.asset_id
+		 WHERE a.workspace_id = ? AND p.state = 'open'
+		 ORDER BY p.product, p.version, p.service, a.value, p.port`,
+		workspaceID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("listing services: %w", err)
+	}
+	defer rows.Close()
+
+	result := []ServiceGroup{}
+	index := make(map[[3]string]int)
+	for rows.Next() {
+		var g ServiceGroup
+		var h ServiceHost
+		if err := rows.Scan(&h.PortID, &h.AssetID, &h.Address, &h.Port, &h.Protocol, &g.Service, &g.Product,
+			&g.Version, &h.ExtraInfo, &g.CPE, &h.Banner, &h.TLS, &h.RunID); err != nil {
+			return nil, fmt.Errorf("scanning service: %w", err)
+		}
+		key := [3]string{g.Product, g.Version, ""}
+		if g.Product == "" {
+			key[2] = g.Service
+		}
+		i, ok := index[key]
+		if !ok {
+			i = len(result)
+			index[key] = i
+			result = append(result, g)
+		}
+		if result[i].CPE == "" {
+			result[i].CPE = g.CPE
+		}
+		result[i].Hosts = append(result[i].Hosts, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(result, func(i, j int) bool { return len(result[i].Hosts) > len(result[j].Hosts) })
+	return result, nil
+}
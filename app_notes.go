@@ -0,0 +1,409 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"nser/internal/audit"
+	"nser/internal/db"
+)
+
+// maxEvidenceSize caps a single evidence attachment.
+const maxEvidenceSize = 32 << 20
+
+// maxTagLength caps a tag's length after normalisation.
+const maxTagLength = 64
+
+// ─── Notes ───────────────────────────────────────────────────────────────────
+
+// GetNotes returns the notes attached to an entity, oldest first. Fails
+// while the database is locked.
+func (a *App) GetNotes(entityType string, entityID int64) ([]Note, error) {
+	rows, err := a.conn().QueryContext(a.ctx,
+		`SELECT id, workspace_id, entity_type, entity_id, body, created_at, updated_at
+		 FROM notes WHERE entity_type = ? AND entity_id = ? ORDER BY created_at, id`,
+		entityType, entityID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("listing notes: %w", err)
+	}
+	defer rows.Close()
+
+	result := []Note{}
+	for rows.Next() {
+		var n Note
+		var body []byte
+		if err := rows.Scan(&n.ID, &n.WorkspaceID, &n.EntityType, &n.EntityID, &body, &n.CreatedAt, &n.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scanning note: %w", err)
+		}
+		if body, err = a.openSealed(body); err != nil {
+			return nil, fmt.Errorf("reading note %d: %w", n.ID, err)
+		}
+		n.Body = string(body)
+		result = append(result, n)
+	}
+	return result, rows.Err()
+}
+
+// AddNote attaches a markdown note to an entity and returns its ID.
+func (a *App) AddNote(entityType string, entityID int64, body string) (int64, error) {
+	workspaceID, err := db.EntityWorkspace(a.ctx, a.conn(), entityType, entityID)
+	if err != nil {
+		return 0, err
+	}
+	sealed, err := a.sealSensitive([]byte(body))
+	if err != nil {
+		return 0, fmt.Errorf("sealing note: %w", err)
+	}
+	now := time.Now()
+	res, err := a.conn().ExecContext(a.ctx,
+		`INSERT INTO notes (workspace_id, entity_type, entity_id, body, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		workspaceID, entityType, entityID, sealed, now, now,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("adding note: %w", err)
+	}
+	return res.LastInsertId()
+}
+
+// UpdateNote replaces a note's text.
+func (a *App) UpdateNote(id int64, body string) error {
+	sealed, err := a.sealSensitive([]byte(body))
+	if err != nil {
+		return fmt.Errorf("sealing note: %w", err)
+	}
+	res, err := a.conn().ExecContext(a.ctx,
+		`UPDATE notes SET body = ?, updated_at = ? WHERE id = ?`, sealed, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("updating note: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("note %d not found", id)
+	}
+	return nil
+}
+
+// DeleteNote deletes a note.
+func (a *App) DeleteNote(id int64) error {
+	return a.deleteRecorded("notes", "note", id)
+}
+
+// ─── Tags ────────────────────────────────────────────────────────────────────
+
+// normalizeTag lowercases a tag and joins its words with '-' ("Prod DMZ" →
+// "prod-dmz").
+func normalizeTag(tag string) (string, error) {
+	t := strings.ToLower(strings.Join(strings.Fields(tag), "-"))
+	if t == "" {
+		return "", fmt.Errorf("tag is empty")
+	}
+	if len(t) > maxTagLength {
+		return "", fmt.Errorf("tag %q is longer than %d characters", tag, maxTagLength)
+	}
+	return t, nil
+}
+
+// GetTags returns an entity's tags, sorted.
+func (a *App) GetTags(entityType string, entityID int64) ([]string, error) {
+	rows, err := a.conn().QueryContext(a.ctx,
+		`SELECT tag FROM tags WHERE entity_type = ? AND entity_id = ? ORDER BY tag`, entityType, entityID)
+	if err != nil {
+		return nil, fmt.Errorf("listing tags: %w", err)
+	}
+	defer rows.Close()
+
+	result := []string{}
+	for rows.Next() {
+		var t string
+		if err := rows.Scan(&t); err != nil {
+			return nil, fmt.Errorf("scanning tag: %w", err)
+		}
+		result = append(result, t)
+	}
+	return result, rows.Err()
+}
+
+// SetTags replaces an entity's tags.
+func (a *App) SetTags(entityType string, entityID int64, tags []string) error {
+	workspaceID, err := db.EntityWorkspace(a.ctx, a.conn(), entityType, entityID)
+	if err != nil {
+		return err
+	}
+	normalized := make([]string, 0, len(tags))
+	for _, t := range tags {
+		n, err := normalizeTag(t)
+		if err != nil {
+			return err
+		}
+		normalized = append(normalized, n)
+	}
+
+	tx, err := a.conn().BeginTx(a.ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if _, err := tx.ExecContext(a.ctx,
+		`DELETE FROM tags WHERE entity_type = ? AND entity_id = ?`, entityType, entityID); err != nil {
+		return fmt.Errorf("clearing tags: %w", err)
+	}
+	now := time.Now()
+	for _, t := range normalized {
+		if _, err := tx.ExecContext(a.ctx,
+			`INSERT INTO tags (workspace_id, entity_type, entity_id, tag, created_at) VALUES (?, ?, ?, ?, ?)
+			 ON CONFLICT DO NOTHING`,
+			workspaceID, entityType, entityID, t, now,
+		); err != nil {
+			return fmt.Errorf("adding tag %q: %w", t, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// GetWorkspaceTags returns every tag used in a workspace with how many
+// items carry it, most used first, for the tag filter.
+func (a *App) GetWorkspaceTags(workspaceID int64) ([]TagCount, error) {
+	rows, err := a.conn().QueryContext(a.ctx,
+		`SELECT tag, COUNT(*) FROM tags WHERE workspace_id = ? GROUP BY tag ORDER BY COUNT(*) DESC, tag`,
+		workspaceID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("listing tags: %w", err)
+	}
+	defer rows.Close()
+
+	result := []TagCount{}
+	for rows.Next() {
+		var t TagCount
+		if err := rows.Scan(&t.Tag, &t.Count); err != nil {
+			return nil, fmt.Errorf("scanning tag: %w", err)
+		}
+		result = append(result, t)
+	}
+	return result, rows.Err()
+}
+
+// workspaceTags returns the tags of every entity of one type in a
+// workspace, keyed by entity ID.
+func (a *App) workspaceTags(workspaceID int64, entityType string) (map[int64][]string, error) {
+	rows, err := a.conn().QueryContext(a.ctx,
+		`SELECT entity_id, tag FROM tags WHERE workspace_id = ? AND entity_type = ? ORDER BY tag`,
+		workspaceID, entityType,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("listing tags: %w", err)
+	}
+	defer rows.Close()
+
+	result := make(map[int64][]string)
+	for rows.Next() {
+		var id int64
+		var t string
+		if err := rows.Scan(&id, &t); err != nil {
+			return nil, fmt.Errorf("scanning tag: %w", err)
+		}
+		result[id] = append(result[id], t)
+	}
+	return result, rows.Err()
+}
+
+// tagFilter returns an " AND id IN (...)" clause and its args selecting
+// entities carrying every one of tags, or "" when tags is empty.
+func tagFilter(entityType string, tags []string) (string, []any, error) {
+	if len(tags) == 0 {
+		return "", nil, nil
+	}
+	set := make(map[string]bool, len(tags))
+	args := []any{entityType}
+	for _, t := range tags {
+		n, err := normalizeTag(t)
+		if err != nil {
+			return "", nil, err
+		}
+		if !set[n] {
+			set[n] = true
+			args = append(args, n)
+		}
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(set)), ", ")
+	args = append(args, len(set))
+	return ` AND id IN (SELECT entity_id FROM tags WHERE entity_type = ? AND tag IN (` + placeholders + `)
+	                   GROUP BY entity_id HAVING COUNT(*) = ?)`, args, nil
+}
+
+// ─── Assets ──────────────────────────────────────────────────────────────────
+
+// GetAssets returns a workspace's assets with their open port count and
+// tags. With tags, only assets tagged with every one of them are returned.
+func (a *App) GetAssets(workspaceID int64, tags []string) ([]Asset, error) {
+	filter, filterArgs, err := tagFilter("asset", tags)
+	if err != nil {
+		return nil, err
+	}
+	assetTags, err := a.workspaceTags(workspaceID, "asset")
+	if err != nil {
+		return nil, err
+	}
+	rows, err := a.conn().QueryContext(a.ctx,
+		`SELECT id, workspace_id, type, value, created_at,
+		        (SELECT COUNT(*) FROM ports p WHERE p.asset_id = assets.id AND p.state = 'open')
+		 FROM assets
+		 WHERE workspace_id = ?`+filter+`
+		 ORDER BY type, value`,
+		append([]any{workspaceID}, filterArgs...)...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("listing assets: %w", err)
+	}
+	defer rows.Close()
+
+	result := []Asset{}
+	for rows.Next() {
+		var as Asset
+		if err := rows.Scan(&as.ID, &as.WorkspaceID, &as.Type, &as.Value, &as.CreatedAt, &as.OpenPorts); err != nil {
+			return nil, fmt.Errorf("scanning asset: %w", err)
+		}
+		as.Tags = assetTags[as.ID]
+		result = append(result, as)
+	}
+	return result, rows.Err()
+}
+
+// ─── Evidence ────────────────────────────────────────────────────────────────
+
+// AddEvidenceFile attaches a file (a screenshot, saved response, ...) to
+// an entity and returns the evidence ID. kind is "screenshot", "http" or
+// "file"; screenshots must be images.
+func (a *App) AddEvidenceFile(entityType string, entityID int64, kind, title, path string) (int64, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return 0, fmt.Errorf("reading evidence: %w", err)
+	}
+	if fi.Size() > maxEvidenceSize {
+		return 0, fmt.Errorf("%s is larger than %d MB", filepath.Base(path), maxEvidenceSize>>20)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("reading evidence: %w", err)
+	}
+	return a.addEvidence(entityType, entityID, kind, title, filepath.Base(path), data)
+}
+
+// AddEvidenceText attaches text evidence, such as a captured HTTP request
+// and response, to an entity and returns the evidence ID.
+func (a *App) AddEvidenceText(entityType string, entityID int64, kind, title, content string) (int64, error) {
+	if len(content) > maxEvidenceSize {
+		return 0, fmt.Errorf("evidence is larger than %d MB", maxEvidenceSize>>20)
+	}
+	return a.addEvidence(entityType, entityID, kind, title, "", []byte(content))
+}
+
+func (a *App) addEvidence(entityType string, entityID int64, kind, title, filename string, data []byte) (int64, error) {
+	mimeType := http.DetectContentType(data)
+	switch kind {
+	case "screenshot":
+		if !strings.HasPrefix(mimeType, "image/") {
+			return 0, fmt.Errorf("screenshot is not an image (%s)", mimeType)
+		}
+	case "http", "file":
+	default:
+		return 0, fmt.Errorf("unknown evidence kind %q", kind)
+	}
+	workspaceID, err := db.EntityWorkspace(a.ctx, a.conn(), entityType, entityID)
+	if err != nil {
+		return 0, err
+	}
+	sealed, err := a.sealSensitive(data)
+	if err != nil {
+		return 0, fmt.Errorf("sealing evidence: %w", err)
+	}
+	if sealed == nil {
+		sealed = []byte{}
+	}
+	res, err := a.conn().ExecContext(a.ctx,
+		`INSERT INTO evidence (workspace_id, entity_type, entity_id, kind, title, filename, mime_type, size, data, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		workspaceID, entityType, entityID, kind, title, filename, mimeType, len(data), sealed, time.Now(),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("adding evidence: %w", err)
+	}
+	return res.LastInsertId()
+}
+
+// GetEvidence lists the evidence attached to an entity, oldest first.
+func (a *App) GetEvidence(entityType string, entityID int64) ([]Evidence, error) {
+	return a.listEvidence(`entity_type = ? AND entity_id = ?`, entityType, entityID)
+}
+
+// GetWorkspaceEvidence lists every piece of evidence in a workspace, for
+// reporting.
+func (a *App) GetWorkspaceEvidence(workspaceID int64) ([]Evidence, error) {
+	return a.listEvidence(`workspace_id = ?`, workspaceID)
+}
+
+func (a *App) listEvidence(where string, args ...any) ([]Evidence, error) {
+	rows, err := a.conn().QueryContext(a.ctx,
+		`SELECT id, workspace_id, entity_type, entity_id, kind, title, filename, mime_type, size, created_at
+		 FROM evidence WHERE `+where+` ORDER BY created_at, id`,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("listing evidence: %w", err)
+	}
+	defer rows.Close()
+
+	result := []Evidence{}
+	for rows.Next() {
+		var e Evidence
+		if err := rows.Scan(&e.ID, &e.WorkspaceID, &e.EntityType, &e.EntityID, &e.Kind, &e.Title,
+			&e.Filename, &e.MimeType, &e.Size, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scanning evidence: %w", err)
+		}
+		result = append(result, e)
+	}
+	return result, rows.Err()
+}
+
+// GetEvidenceData returns an attachment's content. Fails while the
+// database is locked.
+func (a *App) GetEvidenceData(id int64) ([]byte, error) {
+	var data []byte
+	if err := a.conn().QueryRowContext(a.ctx, `SELECT data FROM evidence WHERE id = ?`, id).Scan(&data); err != nil {
+		return nil, fmt.Errorf("getting evidence: %w", err)
+	}
+	data, err := a.openSealed(data)
+	if err != nil {
+		return nil, fmt.Errorf("getting evidence: %w", err)
+	}
+	return data, nil
+}
+
+// SaveEvidence writes an attachment's content to path.
+func (a *App) SaveEvidence(id int64, path string) error {
+	if _, err := a.requireAudit(); err != nil {
+		return err
+	}
+	data, err := a.GetEvidenceData(id)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("saving evidence: %w", err)
+	}
+	var workspaceID int64
+	if err := a.conn().QueryRowContext(a.ctx, `SELECT workspace_id FROM evidence WHERE id = ?`, id).Scan(&workspaceID); err != nil {
+		return fmt.Errorf("saving evidence: %w", err)
+	}
+	return a.record(workspaceID, audit.ActionExport, map[string]any{"type": "evidence", "id": id, "path": path})
+}
+
+// DeleteEvidence deletes an attachment.
+func (a *App) DeleteEvidence(id int64) error {
+	return a.deleteRecorded("evidence", "evidence", id)
+}
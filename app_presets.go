@@ -0,0 +1,145 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"nser/internal/audit"
+	"nser/internal/tool"
+)
+
+// ─── Run Presets ─────────────────────────────────────────────────────────────
+
+// SavePreset validates and stores a preset. ID 0 inserts a new preset,
+// otherwise the existing one is updated.
+func (a *App) SavePreset(p RunPreset) (*RunPreset, error) {
+	saved, err := a.toolRunner().SavePreset(a.ctx, tool.Preset(p))
+	if err != nil {
+		return nil, err
+	}
+	result := RunPreset(saved)
+	return &result, nil
+}
+
+// GetPresets returns the presets usable in a workspace: its own plus the
+// global ones. An empty toolName returns presets for every tool.
+func (a *App) GetPresets(workspaceID int64, toolName string) ([]RunPreset, error) {
+	presets, err := a.toolRunner().ListPresets(a.ctx, workspaceID, toolName)
+	if err != nil {
+		return nil, err
+	}
+	var result []RunPreset
+	for _, p := range presets {
+		result = append(result, RunPreset(p))
+	}
+	return result, nil
+}
+
+// DeletePreset deletes a preset.
+func (a *App) DeletePreset(id int64) error {
+	return a.audited(func(tx *sql.Tx) (auditEntry, error) {
+		var workspaceID int64
+		var toolName, name string
+		err := tx.QueryRowContext(a.ctx,
+			`DELETE FROM run_presets WHERE id = ? RETURNING COALESCE(workspace_id, 0), tool_name, name`, id,
+		).Scan(&workspaceID, &toolName, &name)
+		if errors.Is(err, sql.ErrNoRows) {
+			return auditEntry{}, nil
+		}
+		if err != nil {
+			return auditEntry{}, fmt.Errorf("deleting preset: %w", err)
+		}
+		return auditEntry{workspaceID, audit.ActionPresetDelete, map[string]any{"id": id, "tool": toolName, "name": name}}, nil
+	})
+}
+
+// ExportPresets returns the presets usable in a workspace as a JSON document
+// suitable for ImportPresets. Password values saved before presets refused
+// them are left out.
+func (a *App) ExportPresets(workspaceID int64) (string, error) {
+	if _, err := a.requireAudit(); err != nil {
+		return "", err
+	}
+	presets, err := a.GetPresets(workspaceID, "")
+	if err != nil {
+		return "", err
+	}
+	for i := range presets {
+		presets[i] = RunPreset(tool.Preset(presets[i]).WithoutPasswords(tool.DefaultRegistry))
+		presets[i].ID = 0
+	}
+	out, err := json.MarshalIndent(presets, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("encoding presets: %w", err)
+	}
+	if err := a.record(workspaceID, audit.ActionPresetExport, map[string]int{"count": len(presets)}); err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// ImportPresets stores presets from an ExportPresets document, all or none.
+// Presets keep their global/workspace scope: workspace-scoped ones are
+// attached to workspaceID. Existing presets with the same scope, tool and
+// name are replaced. Returns the number of presets imported.
+func (a *App) ImportPresets(workspaceID int64, data string) (int, error) {
+	var presets []tool.Preset
+	if err := json.Unmarshal([]byte(data), &presets); err != nil {
+		return 0, fmt.Errorf("decoding presets: %w", err)
+	}
+	return a.toolRunner().ImportPresets(a.ctx, workspaceID, presets)
+}
+
+// LaunchPreset runs a preset against the given assets, one streaming run per
+// asset. With no assets selected it runs against the workspace target.
+func (a *App) LaunchPreset(presetID, workspaceID int64, assetIDs []int64) ([]tool.StreamStartResult, error) {
+	p, err := a.toolRunner().GetPreset(a.ctx, presetID)
+	if err != nil {
+		return nil, err
+	}
+
+	targets, err := a.assetTargets(workspaceID, assetIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var started []tool.StreamStartResult
+	for _, target := range targets {
+		res, err := a.toolRunner().RunStreaming(a.ctx, p.Request(workspaceID, target))
+		if err != nil {
+			return started, fmt.Errorf("launching %q against %s: %w", p.Name, target, err)
+		}
+		started = append(started, *res)
+	}
+	return started, nil
+}
+
+// assetTargets resolves asset IDs to their values, falling back to the
+// workspace target when no assets are given.
+func (a *App) assetTargets(workspaceID int64, assetIDs []int64) ([]string, error) {
+	if len(assetIDs) == 0 {
+		ws, err := a.GetWorkspaceByID(workspaceID)
+		if err != nil {
+			return nil, err
+		}
+		if ws.Target == "" {
+			return nil, fmt.Errorf("workspace has no target and no assets were selected")
+		}
+		return []string{ws.Target}, nil
+	}
+
+	targets := make([]string, 0, len(assetIDs))
+	for _, id := range assetIDs {
+		var value string
+		err := a.conn().QueryRowContext(a.ctx,
+			`SELECT value FROM assets WHERE id = ? AND workspace_id = ?`, id, workspaceID,
+		).Scan(&value)
+		if err != nil {
+			return nil, fmt.Errorf("getting asset %d: %w", id, err)
+		}
+		targets = append(targets, value)
+	}
+	return targets, nil
+}
@@ -0,0 +1,12 @@
+package main
+
+import "nser/internal/db"
+
+// ─── Search ──────────────────────────────────────────────────────────────────
+
+// Search finds runs (command lines, output, parsed results) and assets
+// matching query, best first. workspaceID 0 searches every workspace.
+// Matched terms in snippets are wrapped in db.SnippetStart / db.SnippetEnd.
+func (a *App) Search(workspaceID int64, query string) ([]db.SearchHit, error) {
+	return db.Search(a.ctx, a.conn(), workspaceID, query)
+}
@@ -1,13 +1,58 @@
 package main
 
-import "nser/internal/tool"
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"nser/internal/audit"
+	"nser/internal/db"
+	"nser/internal/tool"
+)
 
 // ─── Tool Execution ──────────────────────────────────────────────────────────
 
 // RunToolStreaming starts a tool subprocess and returns immediately.
 // Output is delivered via Wails events.
 func (a *App) RunToolStreaming(workspaceID int64, toolName, target string, userArgs []string) (*tool.StreamStartResult, error) {
-	return a.runner.RunStreaming(a.ctx, toolName, workspaceID, target, userArgs)
+	return a.toolRunner().RunStreaming(a.ctx, tool.RunRequest{
+		WorkspaceID: workspaceID,
+		ToolName:    toolName,
+		Target:      target,
+		RawArgs:     userArgs,
+	})
+}
+
+// RunToolWithOptions starts a streaming run from form values validated
+// against the tool's option schema. rawArgs is appended unvalidated.
+func (a *App) RunToolWithOptions(workspaceID int64, toolName, target string, options map[string]string, rawArgs []string) (*tool.StreamStartResult, error) {
+	return a.toolRunner().RunStreaming(a.ctx, tool.RunRequest{
+		WorkspaceID: workspaceID,
+		ToolName:    toolName,
+		Target:      target,
+		Options:     options,
+		RawArgs:     rawArgs,
+	})
+}
+
+// PreviewToolCommand validates form values and returns the command line
+// that RunToolWithOptions would execute, outside any workspace. Use
+// PreviewRunCommand to include a workspace's network profile.
+func (a *App) PreviewToolCommand(toolName, target string, options map[string]string, rawArgs []string) (string, error) {
+	return a.PreviewRunCommand(0, toolName, target, options, rawArgs)
+}
+
+// PreviewRunCommand returns the command line RunToolWithOptions would
+// execute in a workspace, its proxy flags included.
+func (a *App) PreviewRunCommand(workspaceID int64, toolName, target string, options map[string]string, rawArgs []string) (string, error) {
+	return a.toolRunner().PreviewCommand(a.ctx, tool.RunRequest{
+		WorkspaceID: workspaceID,
+		ToolName:    toolName,
+		Target:      target,
+		Options:     options,
+		RawArgs:     rawArgs,
+	})
 }
 
 // ─── Tool Info ───────────────────────────────────────────────────────────────
@@ -17,12 +62,245 @@ func (a *App) GetTools() []tool.ToolDef {
 	return tool.DefaultRegistry.List()
 }
 
-// GetToolHealth returns the installation status of every registered tool.
-func (a *App) GetToolHealth() []tool.ToolHealth {
-	return tool.DefaultRegistry.CheckAll()
+// GetToolHealth returns the installation status of every registered tool,
+// served from the health cache where still valid.
+func (a *App) GetToolHealth() ([]tool.ToolHealth, error) {
+	return a.healthCache().Get(a.ctx)
+}
+
+// RecheckToolHealth reruns the health checks for the named tools (all tools
+// when names is empty), bypassing the cache.
+func (a *App) RecheckToolHealth(names []string) ([]tool.ToolHealth, error) {
+	return a.healthCache().Recheck(a.ctx, names)
 }
 
-// GetPrivilegeStatus reports whether the app is running with elevated privileges.
+// GetPrivilegeStatus reports whether the app is running with elevated
+// privileges, its capabilities, and the file capabilities of each tool.
 func (a *App) GetPrivilegeStatus() tool.PrivilegeInfo {
-	return tool.CheckPrivileges()
+	return tool.DefaultRegistry.PrivilegeStatus()
+}
+
+// GetElevationPolicy returns how root-requiring runs are elevated:
+// "none", "sudo" or "pkexec".
+func (a *App) GetElevationPolicy() string {
+	return string(a.toolRunner().ElevationPolicy())
+}
+
+// SetElevationPolicy saves and applies the elevation policy.
+func (a *App) SetElevationPolicy(policy string) error {
+	p, err := tool.ParseElevationPolicy(policy)
+	if err != nil {
+		return err
+	}
+	err = a.audited(func(tx *sql.Tx) (auditEntry, error) {
+		if err := db.SetSettingTx(a.ctx, tx, db.SettingElevationPolicy, string(p)); err != nil {
+			return auditEntry{}, err
+		}
+		return auditEntry{0, audit.ActionElevationPolicy, map[string]string{"policy": string(p)}}, nil
+	})
+	if err != nil {
+		return err
+	}
+	a.toolRunner().SetElevationPolicy(p)
+	return nil
+}
+
+// loadElevationPolicy applies the saved elevation policy to the runner.
+func (a *App) loadElevationPolicy(conn *sql.DB, runner *tool.Runner) {
+	value, err := db.GetSetting(a.ctx, conn, db.SettingElevationPolicy)
+	if err != nil {
+		fmt.Printf("elevation policy: %v\n", err)
+		return
+	}
+	p, err := tool.ParseElevationPolicy(value)
+	if err != nil {
+		fmt.Printf("elevation policy: %v\n", err)
+		return
+	}
+	runner.SetElevationPolicy(p)
+}
+
+// ─── Installation ────────────────────────────────────────────────────────────
+
+// PlanToolInstall returns the exact command InstallTool would run for a tool.
+func (a *App) PlanToolInstall(toolName string) (tool.InstallPlan, error) {
+	return a.toolInstaller().Plan(toolName)
+}
+
+// InstallTool runs a tool's Linux install hint as a tracked streaming run.
+// Listen for "tool:install:output:<id>" and "tool:install:done:<id>".
+func (a *App) InstallTool(toolName string) (*tool.InstallStartResult, error) {
+	return a.toolInstaller().Install(a.ctx, toolName)
+}
+
+// searchPathFile returns ~/.nser/search_path. Like the tools it finds, the
+// search path belongs to the install rather than to any one database.
+func searchPathFile() (string, error) {
+	dir, err := db.DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "search_path"), nil
+}
+
+// GetSearchPath returns the extra directories nser appends to $PATH.
+func (a *App) GetSearchPath() ([]string, error) {
+	file, err := searchPathFile()
+	if err != nil {
+		return nil, err
+	}
+	return tool.SearchPath(file)
+}
+
+// AddSearchPath adds a directory to the nser search path.
+func (a *App) AddSearchPath(dir string) error {
+	file, err := searchPathFile()
+	if err != nil {
+		return err
+	}
+	return tool.AddSearchDir(file, dir)
+}
+
+// RemoveSearchPath removes a directory from the nser search path.
+func (a *App) RemoveSearchPath(dir string) error {
+	file, err := searchPathFile()
+	if err != nil {
+		return err
+	}
+	return tool.RemoveSearchDir(file, dir)
+}
+
+// ─── Custom Tools ────────────────────────────────────────────────────────────
+
+// customToolSource marks tools saved from the UI (ToolDef.Source).
+const customToolSource = "db"
+
+// loadToolFiles registers user-defined tools from ~/.nser/tools. They are
+// shared by every database, so this runs once at startup.
+func loadToolFiles() {
+	if dir, err := db.DataDir(); err == nil {
+		tool.DefaultRegistry.LoadToolDir(filepath.Join(dir, "tools"))
+	}
+}
+
+// loadCustomTools registers the tools saved in the open database's
+// custom_tools table. Failures are reported in the health dashboard; the
+// other tools are still registered.
+func (a *App) loadCustomTools(conn *sql.DB) {
+	report := func(name string, err error) {
+		tool.DefaultRegistry.ReportProblem(name, customToolSource, err)
+	}
+	specs, err := a.readCustomTools(conn, report)
+	if err != nil {
+		report("", err)
+	}
+	for _, spec := range specs {
+		def, err := spec.ToDef(customToolSource)
+		if err == nil {
+			err = tool.DefaultRegistry.TryRegister(def)
+		}
+		if err != nil {
+			report(spec.Name, err)
+		}
+	}
+}
+
+// unloadCustomTools unregisters the open database's saved tools, and
+// forgets their load problems, before switching to another database.
+func unloadCustomTools() {
+	for _, def := range tool.DefaultRegistry.List() {
+		if def.Source == customToolSource {
+			tool.DefaultRegistry.Unregister(def.Name) //nolint:errcheck
+		}
+	}
+	tool.DefaultRegistry.ClearProblems(customToolSource)
+}
+
+// GetCustomTools returns the tool specs saved from the UI. Saved specs that
+// no longer parse are left out; they are listed in the health dashboard.
+func (a *App) GetCustomTools() ([]tool.ToolSpec, error) {
+	return a.readCustomTools(a.conn(), func(string, error) {})
+}
+
+// readCustomTools returns the tool specs saved in conn. Rows that don't
+// parse are passed to bad and skipped, so one broken spec doesn't hide the
+// rest.
+func (a *App) readCustomTools(conn *sql.DB, bad func(name string, err error)) ([]tool.ToolSpec, error) {
+	rows, err := conn.QueryContext(a.ctx, `SELECT name, spec_json FROM custom_tools ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("listing custom tools: %w", err)
+	}
+	defer rows.Close()
+
+	var result []tool.ToolSpec
+	for rows.Next() {
+		var name, specJSON string
+		if err := rows.Scan(&name, &specJSON); err != nil {
+			return nil, fmt.Errorf("scanning custom tool: %w", err)
+		}
+		spec, err := tool.ParseToolSpec([]byte(specJSON), ".json")
+		if err != nil {
+			bad(name, err)
+			continue
+		}
+		result = append(result, spec)
+	}
+	return result, rows.Err()
+}
+
+// SaveCustomTool validates a tool spec, registers it (replacing a previous
+// version saved from the UI) and persists it.
+func (a *App) SaveCustomTool(spec tool.ToolSpec) error {
+	def, err := spec.ToDef(customToolSource)
+	if err != nil {
+		return err
+	}
+	if existing, err := tool.DefaultRegistry.Get(def.Name); err == nil && existing.Source != customToolSource {
+		if existing.Source == "" {
+			return fmt.Errorf("tool %q is built-in and cannot be replaced", def.Name)
+		}
+		return fmt.Errorf("tool %q is defined in %s; edit that file instead", def.Name, existing.Source)
+	}
+
+	specJSON, err := json.Marshal(spec)
+	if err != nil {
+		return fmt.Errorf("encoding tool spec: %w", err)
+	}
+	_, err = a.conn().ExecContext(a.ctx,
+		`INSERT INTO custom_tools (name, spec_json) VALUES (?, ?)
+		 ON CONFLICT(name) DO UPDATE SET spec_json = excluded.spec_json, updated_at = CURRENT_TIMESTAMP`,
+		def.Name, string(specJSON),
+	)
+	if err != nil {
+		return fmt.Errorf("saving custom tool: %w", err)
+	}
+	tool.DefaultRegistry.ClearProblems(customToolSource, def.Name)
+	return tool.DefaultRegistry.Replace(def)
+}
+
+// DeleteCustomTool removes a tool saved from the UI.
+func (a *App) DeleteCustomTool(name string) error {
+	existing, lookupErr := tool.DefaultRegistry.Get(name)
+	registered := lookupErr == nil
+	if registered && existing.Source != customToolSource {
+		return fmt.Errorf("tool %q was not created from the UI and cannot be deleted here", name)
+	}
+	err := a.audited(func(tx *sql.Tx) (auditEntry, error) {
+		res, err := tx.ExecContext(a.ctx, `DELETE FROM custom_tools WHERE name = ?`, name)
+		if err != nil {
+			return auditEntry{}, fmt.Errorf("deleting custom tool: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return auditEntry{}, nil
+		}
+		return auditEntry{0, audit.ActionToolDelete, map[string]string{"name": name}}, nil
+	})
+	if err != nil {
+		return err
+	}
+	tool.DefaultRegistry.ClearProblems(customToolSource, name)
+	if registered {
+		return tool.DefaultRegistry.Unregister(name)
+	}
+	return nil
 }
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"nser/internal/audit"
+	"nser/internal/db"
+)
+
+// ─── Trash ───────────────────────────────────────────────────────────────────
+
+// GetTrash returns deleted runs and workspaces, most recently deleted
+// first. Runs in a deleted workspace are only listed if they were deleted
+// themselves; restoring the workspace brings back the rest.
+func (a *App) GetTrash() ([]TrashItem, error) {
+	purgeAfter := fmt.Sprintf("+%d seconds", int64(db.TrashRetention.Seconds()))
+	rows, err := a.conn().QueryContext(a.ctx,
+		`SELECT 'workspace', id, id, name, name,
+		        strftime('%Y-%m-%dT%H:%M:%SZ', deleted_at), strftime('%Y-%m-%dT%H:%M:%SZ', deleted_at, ?1)
+		 FROM workspaces WHERE deleted_at IS NOT NULL
+		 UNION ALL
+		 SELECT 'run', r.id, r.workspace_id, w.name, r.tool_name || ' ' || r.target,
+		        strftime('%Y-%m-%dT%H:%M:%SZ', r.deleted_at), strftime('%Y-%m-%dT%H:%M:%SZ', r.deleted_at, ?1)
+		 FROM tool_runs r JOIN workspaces w ON w.id = r.workspace_id
+		 WHERE r.deleted_at IS NOT NULL
+		 ORDER BY 6 DESC`,
+		purgeAfter,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("listing trash: %w", err)
+	}
+	defer rows.Close()
+
+	result := []TrashItem{}
+	for rows.Next() {
+		var it TrashItem
+		if err := rows.Scan(&it.Kind, &it.ID, &it.WorkspaceID, &it.WorkspaceName, &it.Title, &it.DeletedAt, &it.PurgeAt); err != nil {
+			return nil, fmt.Errorf("scanning trash item: %w", err)
+		}
+		result = append(result, it)
+	}
+	return result, rows.Err()
+}
+
+// RestoreRun takes a run out of the trash (undoes DeleteRun).
+func (a *App) RestoreRun(runID int64) error {
+	return a.changeTrash(db.Restore, "run", runID, audit.ActionRunRestore)
+}
+
+// RestoreWorkspace takes a workspace out of the trash (undoes
+// DeleteWorkspace), with everything in it.
+func (a *App) RestoreWorkspace(id int64) error {
+	return a.changeTrash(db.Restore, "workspace", id, audit.ActionWorkspaceRestore)
+}
+
+// PurgeRun permanently deletes a run in the trash.
+func (a *App) PurgeRun(runID int64) error {
+	return a.changeTrash(db.Purge, "run", runID, audit.ActionRunPurge)
+}
+
+// PurgeWorkspace permanently deletes a workspace in the trash with all its
+// runs, assets, findings, credentials, notes and evidence.
+func (a *App) PurgeWorkspace(id int64) error {
+	return a.changeTrash(db.Purge, "workspace", id, audit.ActionWorkspacePurge)
+}
+
+// changeTrash applies change (db.MoveToTrash, Restore or Purge) to a run or
+// workspace and records it as action.
+func (a *App) changeTrash(change func(context.Context, *sql.Tx, string, int64) error, kind string, id int64, action string) error {
+	workspaceID, err := db.EntityWorkspace(a.ctx, a.conn(), kind, id)
+	if err != nil {
+		return err
+	}
+	err = a.audited(func(tx *sql.Tx) (auditEntry, error) {
+		if err := change(a.ctx, tx, kind, id); err != nil {
+			return auditEntry{}, err
+		}
+		return auditEntry{workspaceID, action, map[string]any{"type": kind, "id": id}}, nil
+	})
+	if err != nil || (action != audit.ActionRunPurge && action != audit.ActionWorkspacePurge) {
+		return err
+	}
+	return db.OptimizeSearch(a.ctx, a.conn())
+}
+
+// EmptyTrash permanently deletes everything in the trash and returns how
+// many runs and workspaces went.
+func (a *App) EmptyTrash() (int64, error) {
+	return a.purgeTrash(0)
+}
+
+// purgeExpiredTrash removes trash items older than db.TrashRetention.
+func (a *App) purgeExpiredTrash() {
+	if _, err := a.purgeTrash(db.TrashRetention); err != nil {
+		fmt.Printf("trash: %v\n", err)
+	}
+}
+
+// purgeTrash purges trash items deleted more than olderThan ago (all of
+// them for 0) and records how many went.
+func (a *App) purgeTrash(olderThan time.Duration) (int64, error) {
+	var n int64
+	err := a.audited(func(tx *sql.Tx) (auditEntry, error) {
+		var err error
+		if n, err = db.PurgeExpired(a.ctx, tx, olderThan); err != nil || n == 0 {
+			return auditEntry{}, err
+		}
+		detail := map[string]any{"count": n}
+		if olderThan > 0 {
+			detail["expired"] = true
+		}
+		return auditEntry{0, audit.ActionTrashPurge, detail}, nil
+	})
+	if err != nil || n == 0 {
+		return n, err
+	}
+	return n, db.OptimizeSearch(a.ctx, a.conn())
+}
@@ -1,29 +1,54 @@
 package main
 
+import "nser/internal/tool"
+
 // ─── Types (exported so Wails generates TS models) ───────────────────────────
 
 // Workspace is the API model for workspaces.
 type Workspace struct {
-	ID          int64  `json:"id"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Target      string `json:"target"`
-	CreatedAt   string `json:"createdAt"`
-	UpdatedAt   string `json:"updatedAt"`
+	ID          int64      `json:"id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Target      string     `json:"target"`
+	Scope       []string   `json:"scope"`      // in-scope hosts, ranges and domains, one per entry
+	ArchivedAt  string     `json:"archivedAt"` // "" unless archived
+	Engagement  Engagement `json:"engagement"`
+	CreatedAt   string     `json:"createdAt"`
+	UpdatedAt   string     `json:"updatedAt"` // last change to the workspace, its runs or assets
+}
+
+// Engagement is the paperwork behind a workspace: who the client is, when
+// testing is authorised and under which terms. WindowStart and WindowEnd
+// are "YYYY-MM-DD", "YYYY-MM-DDTHH:MM" (local time) or RFC 3339; a date as
+// the end covers the whole day. Either may be "" for an open end.
+type Engagement struct {
+	Client            string   `json:"client"`
+	WindowStart       string   `json:"windowStart"`
+	WindowEnd         string   `json:"windowEnd"`
+	WindowPolicy      string   `json:"windowPolicy"` // off, warn or block runs outside the window
+	Testers           []string `json:"testers"`
+	Contact           string   `json:"contact"`           // client contact for the engagement
+	AuthorizationRef  string   `json:"authorizationRef"`  // contract or authorisation letter reference
+	RulesOfEngagement string   `json:"rulesOfEngagement"` // markdown
 }
 
 // CommandRun represents a past tool execution for the history panel.
 type CommandRun struct {
-	ID          int64  `json:"id"`
-	WorkspaceID int64  `json:"workspaceId"`
-	ToolName    string `json:"toolName"`
-	Target      string `json:"target"`
-	Args        string `json:"args"`
-	CommandLine string `json:"commandLine"`
-	Status      string `json:"status"`
-	ExitCode    int    `json:"exitCode"`
-	StartedAt   string `json:"startedAt"`
-	CompletedAt string `json:"completedAt"`
+	ID          int64                `json:"id"`
+	WorkspaceID int64                `json:"workspaceId"`
+	ToolName    string               `json:"toolName"`
+	Target      string               `json:"target"`
+	Args        string               `json:"args"`
+	RawArgs     []string             `json:"rawArgs"`
+	Options     map[string]string    `json:"options"`
+	CommandLine string               `json:"commandLine"`
+	Elevation   string               `json:"elevation"` // "", root, capabilities, sudo or pkexec
+	Network     *tool.NetworkProfile `json:"network"`   // nil for direct connections
+	Status      string               `json:"status"`
+	ExitCode    int                  `json:"exitCode"`
+	StartedAt   string               `json:"startedAt"`
+	CompletedAt string               `json:"completedAt"`
+	Tags        []string             `json:"tags"`
 }
 
 // ToolDocumentation holds a tool's docs and examples.
@@ -40,4 +65,178 @@ type ToolExample struct {
 	Description string `json:"description"`
 	Command     string `json:"command"`
 	SortOrder   int    `json:"sortOrder"`
+	Shipped     bool   `json:"shipped"`
+	Customized  bool   `json:"customized"`
+}
+
+// ToolDocsBundle is the export/import format for one tool's docs and examples.
+type ToolDocsBundle struct {
+	ToolName      string        `json:"toolName"`
+	Documentation string        `json:"documentation"`
+	Examples      []ToolExample `json:"examples"`
+}
+
+// RunPreset is a named set of options and args for one tool ("nmap full TCP").
+// WorkspaceID 0 means the preset is available in every workspace. It has the
+// same fields as tool.Preset, which stores it.
+type RunPreset struct {
+	ID          int64             `json:"id"`
+	WorkspaceID int64             `json:"workspaceId"`
+	ToolName    string            `json:"toolName"`
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Options     map[string]string `json:"options"`
+	RawArgs     []string          `json:"rawArgs"`
+	CreatedAt   string            `json:"createdAt"`
+	UpdatedAt   string            `json:"updatedAt"`
+}
+
+// DatabaseInfo describes the open database and the recently opened ones.
+type DatabaseInfo struct {
+	Path   string           `json:"path"`
+	Recent []RecentDatabase `json:"recent"`
+}
+
+// RecentDatabase is an entry in the recent databases list. Exists is false
+// when the file is gone or its volume isn't mounted.
+type RecentDatabase struct {
+	Path   string `json:"path"`
+	Exists bool   `json:"exists"`
+}
+
+// BackupSettings controls automatic snapshots of the open database.
+type BackupSettings struct {
+	IntervalHours int `json:"intervalHours"` // 0 disables automatic snapshots
+	Keep          int `json:"keep"`          // snapshots kept per database
+}
+
+// ServiceGroup is every open port in a workspace running one product and
+// version ("every host running OpenSSH 7.2p2"). Ports without a product are
+// grouped by service name.
+type ServiceGroup struct {
+	Product string        `json:"product"`
+	Version string        `json:"version"`
+	Service string        `json:"service"`
+	CPE     string        `json:"cpe"`
+	Hosts   []ServiceHost `json:"hosts"`
+}
+
+// ServiceHost is one port in a ServiceGroup.
+type ServiceHost struct {
+	PortID    int64  `json:"portId"`
+	AssetID   int64  `json:"assetId"`
+	Address   string `json:"address"`
+	Port      int    `json:"port"`
+	Protocol  string `json:"protocol"`
+	ExtraInfo string `json:"extraInfo"`
+	Banner    string `json:"banner"`
+	TLS       bool   `json:"tls"`
+	RunID     int64  `json:"runId"` // latest run that reported the port, 0 if deleted
+}
+
+// Finding is a vulnerability on a service. CVE matches start out as
+// "suggested" until a tester confirms or dismisses them.
+type Finding struct {
+	ID          int64   `json:"id"`
+	WorkspaceID int64   `json:"workspaceId"`
+	AssetID     int64   `json:"assetId"`
+	Address     string  `json:"address"`
+	PortID      int64   `json:"portId"` // 0 if not tied to a port
+	Port        int     `json:"port"`
+	Protocol    string  `json:"protocol"`
+	Product     string  `json:"product"`
+	Version     string  `json:"version"`
+	CVE         string  `json:"cve"`
+	Title       string  `json:"title"`
+	Description string  `json:"description"`
+	Severity    string  `json:"severity"`
+	CVSS        float64 `json:"cvss"`
+	CVSSVector  string  `json:"cvssVector"`
+	KEV         bool    `json:"kev"` // listed in CISA Known Exploited Vulnerabilities
+	Status      string  `json:"status"`
+	Source      string  `json:"source"`
+	CreatedAt   string  `json:"createdAt"`
+	UpdatedAt   string  `json:"updatedAt"`
+}
+
+// MatchSummary reports what MatchCVEs found.
+type MatchSummary struct {
+	Services int `json:"services"` // services with a CPE or known product and a version
+	Matched  int `json:"matched"`  // CVE matches, new or refreshed
+	Added    int `json:"added"`    // new suggested findings
+}
+
+// Credential is a login found by a tool (hydra, sqlmap) or added by hand.
+// Secret is decrypted for display.
+type Credential struct {
+	ID          int64  `json:"id"`
+	WorkspaceID int64  `json:"workspaceId"`
+	AssetID     int64  `json:"assetId"` // 0 if not tied to an asset
+	Host        string `json:"host"`
+	Port        int    `json:"port"`
+	Service     string `json:"service"`
+	Realm       string `json:"realm"` // where it was found, e.g. a dumped table
+	Username    string `json:"username"`
+	Secret      string `json:"secret"`
+	Type        string `json:"type"` // password, hash, token
+	Validated   bool   `json:"validated"`
+	RunID       int64  `json:"runId"` // run that found it, 0 if added by hand or deleted
+	CreatedAt   string `json:"createdAt"`
+	UpdatedAt   string `json:"updatedAt"`
+}
+
+// Asset is an IP, domain or URL in a workspace.
+type Asset struct {
+	ID          int64    `json:"id"`
+	WorkspaceID int64    `json:"workspaceId"`
+	Type        string   `json:"type"` // ip, domain, url
+	Value       string   `json:"value"`
+	OpenPorts   int      `json:"openPorts"`
+	Tags        []string `json:"tags"`
+	CreatedAt   string   `json:"createdAt"`
+}
+
+// Note is a markdown note attached to a workspace, asset, port, finding or
+// run (EntityType "workspace", "asset", "port", "finding", "run").
+type Note struct {
+	ID          int64  `json:"id"`
+	WorkspaceID int64  `json:"workspaceId"`
+	EntityType  string `json:"entityType"`
+	EntityID    int64  `json:"entityId"`
+	Body        string `json:"body"`
+	CreatedAt   string `json:"createdAt"`
+	UpdatedAt   string `json:"updatedAt"`
+}
+
+// Evidence describes an attachment: a screenshot, a request/response pair
+// or any file. The content is fetched separately with GetEvidenceData.
+type Evidence struct {
+	ID          int64  `json:"id"`
+	WorkspaceID int64  `json:"workspaceId"`
+	EntityType  string `json:"entityType"`
+	EntityID    int64  `json:"entityId"`
+	Kind        string `json:"kind"` // screenshot, http, file
+	Title       string `json:"title"`
+	Filename    string `json:"filename"`
+	MimeType    string `json:"mimeType"`
+	Size        int64  `json:"size"`
+	CreatedAt   string `json:"createdAt"`
+}
+
+// TagCount is a tag used in a workspace and how many items carry it.
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// TrashItem is a deleted run or workspace that can still be restored,
+// until PurgeAt.
+type TrashItem struct {
+	Kind          string `json:"kind"` // run, workspace
+	ID            int64  `json:"id"`
+	WorkspaceID   int64  `json:"workspaceId"`
+	WorkspaceName string `json:"workspaceName"`
+	Title         string `json:"title"` // workspace name, or tool and target
+	DeletedAt     string `json:"deletedAt"`
+	PurgeAt       string `json:"purgeAt"`
 }
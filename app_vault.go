@@ -0,0 +1,161 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+
+	"nser/internal/audit"
+	"nser/internal/db"
+	"nser/internal/tool"
+	"nser/internal/vault"
+)
+
+// ─── Encryption at rest ──────────────────────────────────────────────────────
+
+// openVault loads the encryption settings and hands the vault to the runner.
+// An encrypted database starts locked: the frontend checks
+// GetEncryptionStatus on load and prompts for the passphrase.
+func (a *App) openVault(conn *sql.DB, runner *tool.Runner) *vault.Vault {
+	v, err := vault.Open(a.ctx, conn)
+	if err != nil {
+		fmt.Printf("vault: %v\n", err)
+		return nil
+	}
+	runner.SetSealer(v)
+	return v
+}
+
+// requireVault returns the vault, or an error if it failed to open.
+func (a *App) requireVault() (*vault.Vault, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.vault == nil {
+		return nil, fmt.Errorf("encryption settings are unavailable")
+	}
+	return a.vault, nil
+}
+
+// GetEncryptionStatus reports whether sensitive data is encrypted and, if
+// so, whether the database is unlocked.
+func (a *App) GetEncryptionStatus() (vault.Status, error) {
+	v, err := a.requireVault()
+	if err != nil {
+		return vault.Status{}, err
+	}
+	return v.Status(), nil
+}
+
+// UnlockDatabase unlocks an encrypted database for this session.
+func (a *App) UnlockDatabase(passphrase string) error {
+	v, err := a.requireVault()
+	if err != nil {
+		return err
+	}
+	return v.Unlock(passphrase)
+}
+
+// LockDatabase forgets the key; output and new runs need UnlockDatabase.
+func (a *App) LockDatabase() error {
+	v, err := a.requireVault()
+	if err != nil {
+		return err
+	}
+	v.Lock()
+	return nil
+}
+
+// EnableEncryption encrypts raw output and parsed results of all runs under
+// a passphrase. There is no recovery if the passphrase is lost. Snapshots
+// taken before stay in plaintext: the frontend lists them with
+// GetPlaintextSnapshots afterwards and offers PurgePlaintextSnapshots.
+func (a *App) EnableEncryption(passphrase string) error {
+	v, err := a.requireVault()
+	if err != nil {
+		return err
+	}
+	if _, err := a.requireAudit(); err != nil {
+		return err
+	}
+	if err := v.Enable(a.ctx, passphrase); err != nil {
+		return err
+	}
+	if err := a.record(0, audit.ActionEncryptionOn, nil); err != nil {
+		return err
+	}
+	if snaps, err := db.PlaintextSnapshots(a.ctx, a.openPath()); err == nil && len(snaps) > 0 {
+		fmt.Printf("vault: %d snapshot(s) taken before encryption are still plaintext\n", len(snaps))
+	}
+	return nil
+}
+
+// GetPlaintextSnapshots lists the open database's snapshots taken while
+// encryption was off, which still hold sensitive data unencrypted.
+func (a *App) GetPlaintextSnapshots() ([]db.Snapshot, error) {
+	return db.PlaintextSnapshots(a.ctx, a.openPath())
+}
+
+// PurgePlaintextSnapshots deletes the snapshots GetPlaintextSnapshots
+// lists and returns how many went.
+func (a *App) PurgePlaintextSnapshots() (int, error) {
+	if _, err := a.requireAudit(); err != nil {
+		return 0, err
+	}
+	snaps, err := db.PlaintextSnapshots(a.ctx, a.openPath())
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, s := range snaps {
+		if err := os.Remove(s.Path); err != nil {
+			return n, fmt.Errorf("deleting snapshot %s: %w", s.Name, err)
+		}
+		n++
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	return n, a.record(0, audit.ActionDelete, map[string]any{"type": "snapshot", "count": n, "plaintext": true})
+}
+
+// DisableEncryption decrypts all sensitive data back to plaintext.
+func (a *App) DisableEncryption(passphrase string) error {
+	v, err := a.requireVault()
+	if err != nil {
+		return err
+	}
+	if _, err := a.requireAudit(); err != nil {
+		return err
+	}
+	if err := v.Disable(a.ctx, passphrase); err != nil {
+		return err
+	}
+	return a.record(0, audit.ActionEncryptionOff, nil)
+}
+
+// ChangeEncryptionPassphrase replaces the passphrase without re-encrypting data.
+func (a *App) ChangeEncryptionPassphrase(oldPassphrase, newPassphrase string) error {
+	v, err := a.requireVault()
+	if err != nil {
+		return err
+	}
+	return v.ChangePassphrase(a.ctx, oldPassphrase, newPassphrase)
+}
+
+// openSealed decrypts a sensitive column value read from the database.
+func (a *App) openSealed(data []byte) ([]byte, error) {
+	v, err := a.requireVault()
+	if err != nil {
+		return nil, err
+	}
+	return v.Open(data)
+}
+
+// sealSensitive encrypts a value for a sensitive column before it is written.
+func (a *App) sealSensitive(data []byte) ([]byte, error) {
+	v, err := a.requireVault()
+	if err != nil {
+		return nil, err
+	}
+	return v.Seal(data)
+}
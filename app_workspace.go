@@ -1,26 +1,96 @@
 package main
 
-import "fmt"
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"nser/internal/audit"
+	"nser/internal/db"
+	"nser/internal/tool"
+)
 
 // ─── Workspace CRUD ──────────────────────────────────────────────────────────
 
 // CreateWorkspace creates a new workspace.
 func (a *App) CreateWorkspace(name, description, target string) (*Workspace, error) {
-	res, err := a.db.ExecContext(a.ctx,
-		`INSERT INTO workspaces (name, description, target) VALUES (?, ?, ?)`,
-		name, description, target,
-	)
+	var id int64
+	err := a.audited(func(tx *sql.Tx) (auditEntry, error) {
+		res, err := tx.ExecContext(a.ctx,
+			`INSERT INTO workspaces (name, description, target) VALUES (?, ?, ?)`,
+			name, description, target,
+		)
+		if err != nil {
+			return auditEntry{}, fmt.Errorf("creating workspace: %w", err)
+		}
+		id, _ = res.LastInsertId()
+		return auditEntry{id, audit.ActionWorkspaceCreate, map[string]string{"name": name, "target": target}}, nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("creating workspace: %w", err)
+		return nil, err
 	}
-	id, _ := res.LastInsertId()
 	return a.GetWorkspaceByID(id)
 }
 
-// GetWorkspaces returns all workspaces.
+// workspaceColumns are the columns scanWorkspace reads, in order.
+const workspaceColumns = `id, name, description, COALESCE(target,''), COALESCE(scope,''), COALESCE(archived_at,''),
+	COALESCE(client,''), COALESCE(window_start,''), COALESCE(window_end,''), COALESCE(window_policy,''),
+	COALESCE(testers,''), COALESCE(contact,''), COALESCE(authorization_ref,''), COALESCE(roe_notes,''),
+	created_at, updated_at`
+
+// scanWorkspace scans a row selected with workspaceColumns.
+func scanWorkspace(row interface{ Scan(...any) error }) (Workspace, error) {
+	var ws Workspace
+	var scope, testers string
+	e := &ws.Engagement
+	if err := row.Scan(&ws.ID, &ws.Name, &ws.Description, &ws.Target, &scope, &ws.ArchivedAt,
+		&e.Client, &e.WindowStart, &e.WindowEnd, &e.WindowPolicy,
+		&testers, &e.Contact, &e.AuthorizationRef, &e.RulesOfEngagement,
+		&ws.CreatedAt, &ws.UpdatedAt); err != nil {
+		return Workspace{}, err
+	}
+	ws.Scope = splitScope(scope)
+	e.Testers = splitScope(testers)
+	return ws, nil
+}
+
+// splitScope turns a stored list (scope, testers: one entry per line) into
+// a list.
+func splitScope(scope string) []string {
+	result := []string{}
+	for _, line := range strings.Split(scope, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			result = append(result, line)
+		}
+	}
+	return result
+}
+
+// joinList is splitScope's inverse, dropping blank entries.
+func joinList(list []string) string {
+	return strings.Join(splitScope(strings.Join(list, "\n")), "\n")
+}
+
+// GetWorkspaces returns the workspaces that aren't archived, most recently
+// active first.
 func (a *App) GetWorkspaces() ([]Workspace, error) {
-	rows, err := a.db.QueryContext(a.ctx,
-		`SELECT id, name, description, COALESCE(target,''), created_at, updated_at FROM workspaces ORDER BY updated_at DESC`,
+	return a.listWorkspaces(false)
+}
+
+// GetArchivedWorkspaces returns archived workspaces, most recently active
+// first.
+func (a *App) GetArchivedWorkspaces() ([]Workspace, error) {
+	return a.listWorkspaces(true)
+}
+
+func (a *App) listWorkspaces(archived bool) ([]Workspace, error) {
+	rows, err := a.conn().QueryContext(a.ctx,
+		`SELECT `+workspaceColumns+` FROM workspaces
+		 WHERE (archived_at IS NOT NULL) = ? AND deleted_at IS NULL ORDER BY updated_at DESC`,
+		archived,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("listing workspaces: %w", err)
@@ -29,8 +99,8 @@ func (a *App) GetWorkspaces() ([]Workspace, error) {
 
 	var result []Workspace
 	for rows.Next() {
-		var ws Workspace
-		if err := rows.Scan(&ws.ID, &ws.Name, &ws.Description, &ws.Target, &ws.CreatedAt, &ws.UpdatedAt); err != nil {
+		ws, err := scanWorkspace(rows)
+		if err != nil {
 			return nil, fmt.Errorf("scanning workspace: %w", err)
 		}
 		result = append(result, ws)
@@ -40,18 +110,180 @@ func (a *App) GetWorkspaces() ([]Workspace, error) {
 
 // GetWorkspaceByID returns a workspace by ID.
 func (a *App) GetWorkspaceByID(id int64) (*Workspace, error) {
-	var ws Workspace
-	err := a.db.QueryRowContext(a.ctx,
-		`SELECT id, name, description, COALESCE(target,''), created_at, updated_at FROM workspaces WHERE id = ?`, id,
-	).Scan(&ws.ID, &ws.Name, &ws.Description, &ws.Target, &ws.CreatedAt, &ws.UpdatedAt)
+	ws, err := scanWorkspace(a.conn().QueryRowContext(a.ctx,
+		`SELECT `+workspaceColumns+` FROM workspaces WHERE id = ?`, id))
 	if err != nil {
 		return nil, fmt.Errorf("getting workspace: %w", err)
 	}
 	return &ws, nil
 }
 
-// DeleteWorkspace deletes a workspace.
+// UpdateWorkspace saves a workspace's name, description, target, scope and
+// engagement details.
+func (a *App) UpdateWorkspace(ws Workspace) (*Workspace, error) {
+	if strings.TrimSpace(ws.Name) == "" {
+		return nil, fmt.Errorf("workspace name is required")
+	}
+	e := ws.Engagement
+	if e.WindowPolicy == "" {
+		e.WindowPolicy = tool.WindowWarn
+	}
+	if !tool.ValidWindowPolicy(e.WindowPolicy) {
+		return nil, fmt.Errorf("invalid test window policy %q", e.WindowPolicy)
+	}
+	e.WindowStart, e.WindowEnd = strings.TrimSpace(e.WindowStart), strings.TrimSpace(e.WindowEnd)
+	start, err := tool.ParseWindowTime(e.WindowStart, false)
+	if err != nil {
+		return nil, fmt.Errorf("test window start: %w", err)
+	}
+	end, err := tool.ParseWindowTime(e.WindowEnd, true)
+	if err != nil {
+		return nil, fmt.Errorf("test window end: %w", err)
+	}
+	if !start.IsZero() && !end.IsZero() && end.Before(start) {
+		return nil, fmt.Errorf("test window ends before it starts")
+	}
+	var updated Workspace
+	err = a.audited(func(tx *sql.Tx) (auditEntry, error) {
+		res, err := tx.ExecContext(a.ctx,
+			`UPDATE workspaces SET name = ?, description = ?, target = ?, scope = ?,
+			     client = ?, window_start = ?, window_end = ?, window_policy = ?,
+			     testers = ?, contact = ?, authorization_ref = ?, roe_notes = ?, updated_at = CURRENT_TIMESTAMP
+			 WHERE id = ?`,
+			strings.TrimSpace(ws.Name), ws.Description, ws.Target, joinList(ws.Scope),
+			strings.TrimSpace(e.Client), e.WindowStart, e.WindowEnd, e.WindowPolicy,
+			joinList(e.Testers), e.Contact, strings.TrimSpace(e.AuthorizationRef), e.RulesOfEngagement, ws.ID,
+		)
+		if err != nil {
+			return auditEntry{}, fmt.Errorf("updating workspace: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return auditEntry{}, fmt.Errorf("workspace %d not found", ws.ID)
+		}
+		if updated, err = scanWorkspace(tx.QueryRowContext(a.ctx,
+			`SELECT `+workspaceColumns+` FROM workspaces WHERE id = ?`, ws.ID)); err != nil {
+			return auditEntry{}, fmt.Errorf("getting workspace: %w", err)
+		}
+		// The saved workspace, scope and test window included, so changes
+		// to what may be tested can be traced.
+		return auditEntry{ws.ID, audit.ActionWorkspaceUpdate, updated}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// SetWorkspaceArchived archives a workspace, hiding it from GetWorkspaces
+// without deleting anything, or brings it back.
+func (a *App) SetWorkspaceArchived(id int64, archived bool) error {
+	archivedAt := sql.NullString{String: time.Now().UTC().Format(time.DateTime), Valid: archived}
+	return a.audited(func(tx *sql.Tx) (auditEntry, error) {
+		res, err := tx.ExecContext(a.ctx,
+			`UPDATE workspaces SET archived_at = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`, archivedAt, id)
+		if err != nil {
+			return auditEntry{}, fmt.Errorf("archiving workspace: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return auditEntry{}, fmt.Errorf("workspace %d not found", id)
+		}
+		return auditEntry{id, audit.ActionWorkspaceArchive, map[string]bool{"archived": archived}}, nil
+	})
+}
+
+// CloneWorkspace copies a workspace's setup into a new workspace named
+// name, for a retest: description, target, scope, engagement details, tags,
+// notes on the workspace itself, run presets, run environment and network
+// profile. The test window and authorisation reference are left blank, as
+// a retest is authorised separately. Results (runs, assets, ports,
+// findings, credentials, evidence) are not copied. A workspace in the
+// trash can't be cloned.
+func (a *App) CloneWorkspace(id int64, name string) (*Workspace, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, fmt.Errorf("workspace name is required")
+	}
+	var cloneID int64
+	err := a.audited(func(tx *sql.Tx) (auditEntry, error) {
+		var trashed bool
+		err := tx.QueryRowContext(a.ctx, `SELECT deleted_at IS NOT NULL FROM workspaces WHERE id = ?`, id).Scan(&trashed)
+		if errors.Is(err, sql.ErrNoRows) {
+			return auditEntry{}, fmt.Errorf("workspace %d not found", id)
+		}
+		if err != nil {
+			return auditEntry{}, fmt.Errorf("cloning workspace: %w", err)
+		}
+		if trashed {
+			return auditEntry{}, fmt.Errorf("workspace %d is in the trash", id)
+		}
+		res, err := tx.ExecContext(a.ctx,
+			`INSERT INTO workspaces (name, description, target, scope, client, window_policy, testers, contact, roe_notes)
+			 SELECT ?, description, target, scope, client, window_policy, testers, contact, roe_notes
+			 FROM workspaces WHERE id = ?`,
+			strings.TrimSpace(name), id,
+		)
+		if err != nil {
+			return auditEntry{}, fmt.Errorf("cloning workspace: %w", err)
+		}
+		cloneID, _ = res.LastInsertId()
+
+		for _, stmt := range []string{
+			`INSERT INTO run_presets (workspace_id, tool_name, name, description, options_json, args_json)
+			 SELECT ?, tool_name, name, description, options_json, args_json FROM run_presets WHERE workspace_id = ?`,
+			`INSERT INTO run_env (workspace_id, tool_name, name, value)
+			 SELECT ?, tool_name, name, value FROM run_env WHERE workspace_id = ?`,
+			`INSERT INTO network_profiles (workspace_id, profile_json, updated_at)
+			 SELECT ?, profile_json, updated_at FROM network_profiles WHERE workspace_id = ?`,
+			`INSERT INTO notes (workspace_id, entity_type, entity_id, body, created_at, updated_at)
+			 SELECT ?1, 'workspace', ?1, body, created_at, updated_at FROM notes WHERE entity_type = 'workspace' AND entity_id = ?2`,
+			`INSERT INTO tags (workspace_id, entity_type, entity_id, tag, created_at)
+			 SELECT ?1, 'workspace', ?1, tag, created_at FROM tags WHERE entity_type = 'workspace' AND entity_id = ?2`,
+		} {
+			if _, err := tx.ExecContext(a.ctx, stmt, cloneID, id); err != nil {
+				return auditEntry{}, fmt.Errorf("cloning workspace: %w", err)
+			}
+		}
+		return auditEntry{cloneID, audit.ActionWorkspaceClone, map[string]any{"from": id, "name": strings.TrimSpace(name)}}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a.GetWorkspaceByID(cloneID)
+}
+
+// DeleteWorkspace moves a workspace and everything in it to the trash. It
+// is refused while runs in the workspace are still executing.
 func (a *App) DeleteWorkspace(id int64) error {
-	_, err := a.db.ExecContext(a.ctx, `DELETE FROM workspaces WHERE id = ?`, id)
-	return err
+	runner := a.toolRunner()
+	moveIfIdle := func(ctx context.Context, tx *sql.Tx, kind string, id int64) error {
+		if err := db.MoveToTrash(ctx, tx, kind, id); err != nil {
+			return err
+		}
+		// Checked inside the move's transaction. The database has one
+		// connection, so a run registered after this check waits for it to
+		// commit and then finds the workspace in the trash.
+		if n := runner.ActiveIn(id); n > 0 {
+			return fmt.Errorf("%d runs still executing in this workspace; wait for them to finish", n)
+		}
+		return nil
+	}
+	return a.changeTrash(moveIfIdle, "workspace", id, audit.ActionWorkspaceDelete)
+}
+
+// ─── Network Profile ─────────────────────────────────────────────────────────
+
+// GetNetworkProfile returns the proxy/pivot settings applied to a
+// workspace's tool runs. Workspaces without one connect directly.
+func (a *App) GetNetworkProfile(workspaceID int64) (tool.NetworkProfile, error) {
+	return tool.LoadNetworkProfile(a.ctx, a.conn(), workspaceID)
+}
+
+// SetNetworkProfile saves a workspace's network profile. An empty profile
+// restores direct connections.
+func (a *App) SetNetworkProfile(workspaceID int64, profile tool.NetworkProfile) error {
+	return a.audited(func(tx *sql.Tx) (auditEntry, error) {
+		if err := tool.SaveNetworkProfile(a.ctx, tx, workspaceID, profile); err != nil {
+			return auditEntry{}, err
+		}
+		return auditEntry{workspaceID, audit.ActionNetworkProfile, profile.Redacted()}, nil
+	})
 }
@@ -0,0 +1,318 @@
+// Package audit keeps an append-only log of what was done in each
+// engagement: workspace changes, tool runs with their full argv, exports
+// and deletions. Every entry carries the SHA-256 of its
+// predecessor, so editing or removing one breaks the chain, and exports are
+// signed with an Ed25519 key kept in a 0600 file, ~/.nser/audit.key, shared
+// by every database.
+package audit
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"nser/internal/secrets"
+)
+
+// Actions recorded in the log.
+const (
+	ActionWorkspaceCreate  = "workspace.create"
+	ActionWorkspaceUpdate  = "workspace.update"
+	ActionWorkspaceArchive = "workspace.archive"
+	ActionWorkspaceClone   = "workspace.clone"
+	ActionWorkspaceDelete  = "workspace.delete"
+	ActionWorkspaceRestore = "workspace.restore"
+	ActionWorkspacePurge   = "workspace.purge"
+	ActionNetworkProfile   = "workspace.network"
+	ActionRunStart         = "run.start" // detail has the full argv and any elevation
+	ActionRunStop          = "run.stop"
+	ActionRunRefused       = "run.refused"        // blocked, e.g. outside the test window
+	ActionRunOutsideWindow = "run.outside_window" // started anyway under the warn policy
+	ActionRunDelete        = "run.delete"
+	ActionRunRestore       = "run.restore"
+	ActionRunPurge         = "run.purge"
+	ActionTrashPurge       = "trash.purge" // emptied, or expired items removed
+	ActionExport           = "export"
+	ActionDelete           = "delete"           // notes, evidence, credentials
+	ActionDatabaseRestore  = "database.restore" // detail has the head hash the restore replaced
+	ActionPresetDelete     = "preset.delete"
+	ActionPresetExport     = "preset.export"
+	ActionToolDocsExport   = "tool_docs.export"
+	ActionExampleDelete    = "tool_docs.example_delete"
+	ActionToolDelete       = "tool.delete" // a tool saved from the UI
+	ActionSecretDelete     = "secret.delete"
+	ActionEnvVarDelete     = "env.delete"
+	ActionCredentialAdd    = "credential.add" // added by hand; detail leaves out the secret
+	ActionEncryptionOn     = "encryption.enable"
+	ActionEncryptionOff    = "encryption.disable"
+	ActionElevationPolicy  = "settings.elevation"
+)
+
+// Entry is one log record. Hash is the SHA-256 over PrevHash and the other
+// fields (see hashEntry); the first entry's PrevHash is "".
+type Entry struct {
+	ID          int64           `json:"id"`
+	At          string          `json:"at"` // RFC 3339, UTC
+	WorkspaceID int64           `json:"workspaceId"`
+	Action      string          `json:"action"`
+	Detail      json.RawMessage `json:"detail"`
+	PrevHash    string          `json:"prevHash"`
+	Hash        string          `json:"hash"`
+}
+
+// Verification is the result of checking the hash chain.
+type Verification struct {
+	Entries  int    `json:"entries"`
+	Head     string `json:"head"`     // hash of the last entry
+	BrokenAt int64  `json:"brokenAt"` // first entry that doesn't chain, 0 if intact
+}
+
+// Log appends to and reads the audit_log table.
+type Log struct {
+	db  *sql.DB
+	key ed25519.PrivateKey
+}
+
+// Open returns a log for db, loading the signing key from keyPath or
+// creating it on first use.
+func Open(db *sql.DB, keyPath string) (*Log, error) {
+	seed, err := secrets.LoadKey(keyPath, ed25519.SeedSize, "audit key")
+	if err != nil {
+		return nil, err
+	}
+	return &Log{db: db, key: ed25519.NewKeyFromSeed(seed)}, nil
+}
+
+// PublicKey returns the base64 public key exports are signed with.
+func (l *Log) PublicKey() string {
+	return base64.StdEncoding.EncodeToString(l.key.Public().(ed25519.PublicKey))
+}
+
+// hashEntry returns the chain hash of e.
+func hashEntry(e Entry) string {
+	sum := sha256.Sum256([]byte(e.PrevHash + "\n" + e.At + "\n" + strconv.FormatInt(e.WorkspaceID, 10) + "\n" +
+		e.Action + "\n" + string(e.Detail)))
+	return hex.EncodeToString(sum[:])
+}
+
+// Record appends an entry in its own transaction. detail is stored as
+// JSON; workspaceID 0 is for actions outside any workspace.
+func (l *Log) Record(ctx context.Context, workspaceID int64, action string, detail any) error {
+	tx, err := l.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("append audit entry: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+	if err := l.RecordTx(ctx, tx, workspaceID, action, detail); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RecordTx appends an entry within tx, so it commits or rolls back with
+// the change it records. Reading the head and appending in one
+// transaction keeps the chain linear: a concurrent append can't commit
+// between them.
+func (l *Log) RecordTx(ctx context.Context, tx *sql.Tx, workspaceID int64, action string, detail any) error {
+	b, err := json.Marshal(detail)
+	if err != nil {
+		return fmt.Errorf("encode audit detail: %w", err)
+	}
+	e := Entry{
+		At:          time.Now().UTC().Format(time.RFC3339Nano),
+		WorkspaceID: workspaceID,
+		Action:      action,
+		Detail:      b,
+	}
+	err = tx.QueryRowContext(ctx, `SELECT hash FROM audit_log ORDER BY id DESC LIMIT 1`).Scan(&e.PrevHash)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("load audit head: %w", err)
+	}
+	e.Hash = hashEntry(e)
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO audit_log (at, workspace_id, action, detail_json, prev_hash, hash) VALUES (?, ?, ?, ?, ?, ?)`,
+		e.At, e.WorkspaceID, e.Action, string(e.Detail), e.PrevHash, e.Hash,
+	); err != nil {
+		return fmt.Errorf("append audit entry: %w", err)
+	}
+	return nil
+}
+
+// Entries returns the log in order, for one workspace or, with
+// workspaceID 0, all of it.
+func (l *Log) Entries(ctx context.Context, workspaceID int64) ([]Entry, error) {
+	rows, err := l.db.QueryContext(ctx,
+		`SELECT id, at, workspace_id, action, detail_json, prev_hash, hash FROM audit_log
+		 WHERE ? = 0 OR workspace_id = ? ORDER BY id`, workspaceID, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("load audit log: %w", err)
+	}
+	defer rows.Close()
+
+	result := []Entry{}
+	for rows.Next() {
+		var e Entry
+		var detail string
+		if err := rows.Scan(&e.ID, &e.At, &e.WorkspaceID, &e.Action, &detail, &e.PrevHash, &e.Hash); err != nil {
+			return nil, fmt.Errorf("scan audit entry: %w", err)
+		}
+		e.Detail = json.RawMessage(detail)
+		result = append(result, e)
+	}
+	return result, rows.Err()
+}
+
+// Verify walks the whole chain and reports the first entry whose hash is
+// wrong or which doesn't follow its predecessor.
+func (l *Log) Verify(ctx context.Context) (Verification, error) {
+	entries, err := l.Entries(ctx, 0)
+	if err != nil {
+		return Verification{}, err
+	}
+	v := Verification{Entries: len(entries)}
+	prev := ""
+	for _, e := range entries {
+		if e.PrevHash != prev || hashEntry(e) != e.Hash {
+			v.BrokenAt = e.ID
+			return v, nil
+		}
+		prev = e.Hash
+	}
+	v.Head = prev
+	return v, nil
+}
+
+// Head returns the hash of the last entry in db's log, "" when the log is
+// empty or db predates it. db may be a snapshot opened read-only.
+func Head(ctx context.Context, db *sql.DB) (string, error) {
+	var exists bool
+	if err := db.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'audit_log')`,
+	).Scan(&exists); err != nil {
+		return "", fmt.Errorf("load audit head: %w", err)
+	}
+	if !exists {
+		return "", nil
+	}
+	var head string
+	err := db.QueryRowContext(ctx, `SELECT hash FROM audit_log ORDER BY id DESC LIMIT 1`).Scan(&head)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("load audit head: %w", err)
+	}
+	return head, nil
+}
+
+// EntriesAfter returns the entries of a whole log that follow the one
+// hashed head, or all of them when head is "". It fails when head isn't in
+// entries: the log doesn't continue the chain that ends there.
+func EntriesAfter(entries []Entry, head string) ([]Entry, error) {
+	if head == "" {
+		return entries, nil
+	}
+	for i, e := range entries {
+		if e.Hash == head {
+			return entries[i+1:], nil
+		}
+	}
+	return nil, fmt.Errorf("audit log doesn't contain entry %s", head)
+}
+
+// AppendTx appends entries copied from another copy of the log, such as
+// the one a restored snapshot replaced, keeping their times and hashes.
+// They must continue the chain from the current head.
+func AppendTx(ctx context.Context, tx *sql.Tx, entries []Entry) error {
+	var head string
+	err := tx.QueryRowContext(ctx, `SELECT hash FROM audit_log ORDER BY id DESC LIMIT 1`).Scan(&head)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("load audit head: %w", err)
+	}
+	for _, e := range entries {
+		if e.PrevHash != head || hashEntry(e) != e.Hash {
+			return fmt.Errorf("audit entry %d doesn't continue the log", e.ID)
+		}
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO audit_log (at, workspace_id, action, detail_json, prev_hash, hash) VALUES (?, ?, ?, ?, ?, ?)`,
+			e.At, e.WorkspaceID, e.Action, string(e.Detail), e.PrevHash, e.Hash,
+		); err != nil {
+			return fmt.Errorf("append audit entry: %w", err)
+		}
+		head = e.Hash
+	}
+	return nil
+}
+
+// Export is the signed document ExportSigned produces. Signature is the
+// base64 Ed25519 signature of Log in compact form (no insignificant
+// whitespace), as json.Compact produces it.
+type Export struct {
+	Log       json.RawMessage `json:"log"`
+	PublicKey string          `json:"publicKey"`
+	Signature string          `json:"signature"`
+}
+
+// exportLog is the signed part of an Export.
+type exportLog struct {
+	ExportedAt   string       `json:"exportedAt"`
+	WorkspaceID  int64        `json:"workspaceId"` // 0 for the whole log
+	Verification Verification `json:"verification"`
+	Entries      []Entry      `json:"entries"`
+}
+
+// ExportSigned returns a workspace's entries (all entries for 0) with the
+// chain's verification result, signed. Each entry keeps its hashes, so
+// entries can be checked against a full export or the database.
+func (l *Log) ExportSigned(ctx context.Context, workspaceID int64) ([]byte, error) {
+	v, err := l.Verify(ctx)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := l.Entries(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(exportLog{
+		ExportedAt:   time.Now().UTC().Format(time.RFC3339),
+		WorkspaceID:  workspaceID,
+		Verification: v,
+		Entries:      entries,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("encode audit export: %w", err)
+	}
+	return json.MarshalIndent(Export{
+		Log:       body,
+		PublicKey: l.PublicKey(),
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(l.key, body)),
+	}, "", "  ")
+}
+
+// VerifyExport checks an export's signature against its embedded public
+// key. Compare the key with PublicKey to know who signed it.
+func VerifyExport(data []byte) (bool, error) {
+	var x Export
+	if err := json.Unmarshal(data, &x); err != nil {
+		return false, fmt.Errorf("decode audit export: %w", err)
+	}
+	pub, err := base64.StdEncoding.DecodeString(x.PublicKey)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return false, fmt.Errorf("invalid public key")
+	}
+	sig, err := base64.StdEncoding.DecodeString(x.Signature)
+	if err != nil {
+		return false, fmt.Errorf("invalid signature: %w", err)
+	}
+	var body bytes.Buffer
+	if err := json.Compact(&body, x.Log); err != nil {
+		return false, fmt.Errorf("decode audit export: %w", err)
+	}
+	return ed25519.Verify(pub, body.Bytes(), sig), nil
+}
@@ -0,0 +1,140 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+
+	"nser/internal/db"
+)
+
+func TestLog(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	conn, err := db.Open(filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	l, err := Open(conn, filepath.Join(dir, "audit.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, e := range []struct {
+		ws     int64
+		action string
+	}{{1, ActionWorkspaceCreate}, {1, ActionRunStart}, {2, ActionWorkspaceCreate}, {1, ActionRunStop}} {
+		if err := l.Record(ctx, e.ws, e.action, map[string]any{"argv": []string{"nmap", "-sV", "10.0.0.1"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if v, err := l.Verify(ctx); err != nil || v.Entries != 4 || v.BrokenAt != 0 || v.Head == "" {
+		t.Fatalf("Verify = %+v, %v", v, err)
+	}
+	if entries, _ := l.Entries(ctx, 1); len(entries) != 3 || entries[2].Action != ActionRunStop {
+		t.Errorf("workspace 1 entries = %+v", entries)
+	}
+
+	// The key is reused, so exports from one install share a public key.
+	again, err := Open(conn, filepath.Join(dir, "audit.key"))
+	if err != nil || again.PublicKey() != l.PublicKey() {
+		t.Fatalf("reopened key differs: %v", err)
+	}
+	out, err := l.ExportSigned(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := VerifyExport(out); err != nil || !ok {
+		t.Fatalf("VerifyExport = %v, %v", ok, err)
+	}
+	forged := bytes.Replace(out, []byte("10.0.0.1"), []byte("10.0.0.2"), 1)
+	if ok, _ := VerifyExport(forged); ok {
+		t.Error("VerifyExport accepted an edited export")
+	}
+
+	if _, err := conn.Exec(`UPDATE audit_log SET action = 'x' WHERE id = 2`); err == nil {
+		t.Fatal("audit_log accepted an UPDATE")
+	}
+	if _, err := conn.Exec(`DELETE FROM audit_log WHERE id = 2`); err == nil {
+		t.Fatal("audit_log accepted a DELETE")
+	}
+	// Edit behind the triggers' back: the chain shows where.
+	if _, err := conn.Exec(`DROP TRIGGER audit_log_no_update;
+		UPDATE audit_log SET detail_json = '{}' WHERE id = 3`); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := l.Verify(ctx); v.BrokenAt != 3 {
+		t.Errorf("BrokenAt = %d, want 3", v.BrokenAt)
+	}
+}
+
+// A restore carries the entries written since the snapshot onto the
+// snapshot's copy of the log.
+func TestAppendTx(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	open := func(name string) (*Log, func()) {
+		conn, err := db.Open(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		l, err := Open(conn, filepath.Join(dir, "audit.key"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return l, func() { conn.Close() }
+	}
+	live, closeLive := open("live.db")
+	defer closeLive()
+	restored, closeRestored := open("restored.db")
+	defer closeRestored()
+
+	if head, err := Head(ctx, restored.db); err != nil || head != "" {
+		t.Fatalf("empty Head = %q, %v", head, err)
+	}
+	for _, action := range []string{ActionWorkspaceCreate, ActionRunStart, ActionRunStop} {
+		if err := live.Record(ctx, 1, action, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, _ := live.Entries(ctx, 0)
+
+	// The snapshot was taken after the first entry.
+	if err := appendEntries(ctx, restored, entries[:1]); err != nil {
+		t.Fatal(err)
+	}
+	head, _ := Head(ctx, restored.db)
+	tail, err := EntriesAfter(entries, head)
+	if err != nil || len(tail) != 2 {
+		t.Fatalf("EntriesAfter = %d entries, %v", len(tail), err)
+	}
+	if err := appendEntries(ctx, restored, tail[1:]); err == nil {
+		t.Error("AppendTx accepted entries with a gap")
+	}
+	if err := appendEntries(ctx, restored, tail); err != nil {
+		t.Fatal(err)
+	}
+	if err := restored.Record(ctx, 0, ActionDatabaseRestore, map[string]string{"replacedHead": entries[2].Hash}); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := restored.Verify(ctx); err != nil || v.Entries != 4 || v.BrokenAt != 0 {
+		t.Errorf("Verify after restore = %+v, %v", v, err)
+	}
+	if _, err := EntriesAfter(entries, "unknown"); err == nil {
+		t.Error("EntriesAfter accepted a head from another log")
+	}
+}
+
+func appendEntries(ctx context.Context, l *Log, entries []Entry) error {
+	tx, err := l.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+	if err := AppendTx(ctx, tx, entries); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
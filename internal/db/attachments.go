@@ -0,0 +1,59 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// attachmentEntities maps the entity types notes, tags and evidence can be
+// attached to onto the query returning an entity's workspace.
+var attachmentEntities = map[string]string{
+	"workspace": `SELECT id FROM workspaces WHERE id = ?`,
+	"asset":     `SELECT workspace_id FROM assets WHERE id = ?`,
+	"port":      `SELECT a.workspace_id FROM ports p JOIN assets a ON a.id = p.asset_id WHERE p.id = ?`,
+	"finding":   `SELECT workspace_id FROM findings WHERE id = ?`,
+	"run":       `SELECT workspace_id FROM tool_runs WHERE id = ?`,
+}
+
+// attachmentTables hold rows attached to an entity by entity_type and
+// entity_id. There is no foreign key, so attachmentCleanup removes them
+// when the entity goes.
+var attachmentTables = []string{"notes", "tags", "evidence"}
+
+// EntityWorkspace returns the workspace an entity belongs to, or an error
+// if the type is unknown or the entity doesn't exist.
+func EntityWorkspace(ctx context.Context, db *sql.DB, entityType string, entityID int64) (int64, error) {
+	query, ok := attachmentEntities[entityType]
+	if !ok {
+		return 0, fmt.Errorf("unknown entity type %q", entityType)
+	}
+	var workspaceID int64
+	err := db.QueryRowContext(ctx, query, entityID).Scan(&workspaceID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("%s %d not found", entityType, entityID)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("load %s %d: %w", entityType, entityID, err)
+	}
+	return workspaceID, nil
+}
+
+// attachmentCleanup returns triggers deleting an entity's notes, tags and
+// evidence along with it. Workspaces need none: their attachments go by
+// ON DELETE CASCADE on workspace_id.
+func attachmentCleanup() []string {
+	var stmts []string
+	for _, e := range []struct{ entity, table string }{
+		{"asset", "assets"}, {"port", "ports"}, {"finding", "findings"}, {"run", "tool_runs"},
+	} {
+		body := ""
+		for _, t := range attachmentTables {
+			body += fmt.Sprintf("DELETE FROM %s WHERE entity_type = '%s' AND entity_id = OLD.id; ", t, e.entity)
+		}
+		stmts = append(stmts, fmt.Sprintf(
+			`CREATE TRIGGER IF NOT EXISTS %s_attachments_delete AFTER DELETE ON %s BEGIN %sEND`, e.entity, e.table, body))
+	}
+	return stmts
+}
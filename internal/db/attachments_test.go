@@ -0,0 +1,45 @@
+package db
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestAttachments(t *testing.T) {
+	ctx := context.Background()
+	conn, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.Exec(`
+		INSERT INTO workspaces (id, name) VALUES (1, 'acme');
+		INSERT INTO assets (id, workspace_id, type, value) VALUES (7, 1, 'ip', '10.0.0.7');
+		INSERT INTO ports (id, asset_id, port) VALUES (3, 7, 443);
+		INSERT INTO notes (workspace_id, entity_type, entity_id, body, created_at, updated_at)
+		VALUES (1, 'port', 3, 'custom login page', 0, 0), (1, 'asset', 7, 'prod', 0, 0);
+		INSERT INTO tags (workspace_id, entity_type, entity_id, tag, created_at) VALUES (1, 'asset', 7, 'dmz', 0);`); err != nil {
+		t.Fatal(err)
+	}
+
+	if ws, err := EntityWorkspace(ctx, conn, "port", 3); err != nil || ws != 1 {
+		t.Errorf("EntityWorkspace(port 3) = %d, %v", ws, err)
+	}
+	if _, err := EntityWorkspace(ctx, conn, "port", 4); err == nil {
+		t.Error("EntityWorkspace found a missing port")
+	}
+	if _, err := EntityWorkspace(ctx, conn, "host", 7); err == nil {
+		t.Error("EntityWorkspace accepted an unknown entity type")
+	}
+
+	// Deleting the asset cascades to its port; both lose their attachments.
+	if _, err := conn.Exec(`DELETE FROM assets WHERE id = 7`); err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	conn.QueryRow(`SELECT (SELECT COUNT(*) FROM notes) + (SELECT COUNT(*) FROM tags)`).Scan(&n)
+	if n != 0 {
+		t.Errorf("%d attachments left after deleting their asset", n)
+	}
+}
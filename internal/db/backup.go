@@ -0,0 +1,256 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// snapshotTimeFormat is embedded in snapshot file names; it sorts by time.
+// Microseconds keep two snapshots in the same second (RestoreSnapshot
+// takes one right before restoring) from colliding.
+const snapshotTimeFormat = "20060102-150405.000000"
+
+// snapshotParseFormat reads snapshotTimeFormat back. Parsing accepts the
+// fractional seconds without listing them, so names from before they were
+// added still parse.
+const snapshotParseFormat = "20060102-150405"
+
+// Snapshot is a backup file in a database's snapshot directory.
+type Snapshot struct {
+	Path      string    `json:"path"`
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// SnapshotDir returns the directory holding dbPath's snapshots:
+// backups/<name> next to the database, so each database file has its own
+// and snapshots stay on the same volume as what they back up.
+func SnapshotDir(dbPath string) (string, error) {
+	abs, err := filepath.Abs(dbPath)
+	if err != nil {
+		return "", fmt.Errorf("snapshot dir: %w", err)
+	}
+	return filepath.Join(filepath.Dir(abs), "backups", snapshotStem(abs)), nil
+}
+
+// snapshotStem is dbPath's file name without its extension.
+func snapshotStem(dbPath string) string {
+	return strings.TrimSuffix(filepath.Base(dbPath), filepath.Ext(dbPath))
+}
+
+// IntegrityCheck runs PRAGMA integrity_check and returns the problems it
+// reports, if any.
+func IntegrityCheck(ctx context.Context, db *sql.DB) error {
+	rows, err := db.QueryContext(ctx, `PRAGMA integrity_check`)
+	if err != nil {
+		return fmt.Errorf("integrity check: %w", err)
+	}
+	defer rows.Close()
+
+	var problems []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return fmt.Errorf("integrity check: %w", err)
+		}
+		if line != "ok" {
+			problems = append(problems, line)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("integrity check: %w", err)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("integrity check failed: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Backup writes a consistent copy of db to dest with VACUUM INTO, which is
+// safe while the app keeps writing (a file copy of a WAL database isn't).
+// Both the source and the copy are integrity-checked. dest must not exist.
+func Backup(ctx context.Context, db *sql.DB, dest string) error {
+	if err := IntegrityCheck(ctx, db); err != nil {
+		return fmt.Errorf("source database: %w", err)
+	}
+	if _, err := os.Stat(dest); err == nil {
+		return fmt.Errorf("backup %s already exists", dest)
+	}
+	if _, err := db.ExecContext(ctx, `VACUUM INTO ?`, dest); err != nil {
+		return fmt.Errorf("write backup: %w", err)
+	}
+	if err := os.Chmod(dest, 0o600); err != nil {
+		return fmt.Errorf("secure backup: %w", err)
+	}
+	if err := CheckFile(ctx, dest); err != nil {
+		os.Remove(dest)
+		return fmt.Errorf("backup: %w", err)
+	}
+	return nil
+}
+
+// OpenReadOnly opens a database file without creating or migrating it.
+func OpenReadOnly(path string) (*sql.DB, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("open %s: %w", path, err)
+	}
+	db, err := sql.Open("sqlite", fileURI(path)+"?mode=ro")
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", path, err)
+	}
+	db.SetMaxOpenConns(1)
+	return db, nil
+}
+
+// CheckFile integrity-checks the database file at path.
+func CheckFile(ctx context.Context, path string) error {
+	db, err := OpenReadOnly(path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return IntegrityCheck(ctx, db)
+}
+
+// TakeSnapshot backs db (opened from dbPath) up into SnapshotDir.
+func TakeSnapshot(ctx context.Context, db *sql.DB, dbPath string) (Snapshot, error) {
+	dir, err := SnapshotDir(dbPath)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return Snapshot{}, fmt.Errorf("create snapshot dir: %w", err)
+	}
+	now := time.Now()
+	name := snapshotStem(dbPath) + "-" + now.Format(snapshotTimeFormat) + ".db"
+	path := filepath.Join(dir, name)
+	if err := Backup(ctx, db, path); err != nil {
+		return Snapshot{}, err
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("stat snapshot: %w", err)
+	}
+	return Snapshot{Path: path, Name: name, Size: fi.Size(), CreatedAt: now}, nil
+}
+
+// ListSnapshots returns dbPath's snapshots, newest first.
+func ListSnapshots(dbPath string) ([]Snapshot, error) {
+	dir, err := SnapshotDir(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("list snapshots: %w", err)
+	}
+
+	prefix := snapshotStem(dbPath) + "-"
+	var result []Snapshot
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ".db") {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".db")
+		created, err := time.ParseInLocation(snapshotParseFormat, stamp, time.Local)
+		if err != nil {
+			continue // not a snapshot file
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		result = append(result, Snapshot{
+			Path: filepath.Join(dir, name), Name: name, Size: info.Size(), CreatedAt: created,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].CreatedAt.After(result[j].CreatedAt) })
+	return result, nil
+}
+
+// PlaintextSnapshots returns dbPath's snapshots taken while encryption was
+// off. Enabling encryption doesn't rewrite them, so they keep sensitive
+// columns in the clear.
+func PlaintextSnapshots(ctx context.Context, dbPath string) ([]Snapshot, error) {
+	snaps, err := ListSnapshots(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	var result []Snapshot
+	for _, s := range snaps {
+		conn, err := OpenReadOnly(s.Path)
+		if err != nil {
+			return nil, err
+		}
+		enc, err := GetSetting(ctx, conn, SettingEncryption)
+		conn.Close()
+		if err != nil {
+			return nil, fmt.Errorf("snapshot %s: %w", s.Name, err)
+		}
+		if enc == "" {
+			result = append(result, s)
+		}
+	}
+	return result, nil
+}
+
+// PruneSnapshots deletes all but the newest keep snapshots of dbPath.
+func PruneSnapshots(dbPath string, keep int) error {
+	snaps, err := ListSnapshots(dbPath)
+	if err != nil {
+		return err
+	}
+	if keep < 1 {
+		keep = 1
+	}
+	var errs []error
+	for i := keep; i < len(snaps); i++ {
+		if err := os.Remove(snaps[i].Path); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Replace restores the database file at path from the backup at src. The
+// database must be closed. The backup is integrity-checked and copied next
+// to path with VACUUM INTO, then renamed over it, so path is never left
+// half-written. Stale WAL and SHM files are removed so they aren't replayed
+// onto the restored file.
+func Replace(ctx context.Context, path, src string) error {
+	backup, err := OpenReadOnly(src)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".restore"
+	os.Remove(tmp)
+	err = Backup(ctx, backup, tmp)
+	backup.Close()
+	if err != nil {
+		return fmt.Errorf("restore from %s: %w", src, err)
+	}
+
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if err := os.Remove(path + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			os.Remove(tmp)
+			return fmt.Errorf("remove %s: %w", path+suffix, err)
+		}
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("replace database: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package db
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSnapshotAndReplace(t *testing.T) {
+	ctx := context.Background()
+	live := filepath.Join(t.TempDir(), "client.db")
+
+	conn, err := Open(live)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Exec(`INSERT INTO workspaces (name) VALUES ('before')`); err != nil {
+		t.Fatal(err)
+	}
+	snap, err := TakeSnapshot(ctx, conn, live)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(filepath.Dir(live), "backups", "client", snap.Name); snap.Path != want {
+		t.Errorf("snapshot path = %s, want %s", snap.Path, want)
+	}
+	if fi, err := os.Stat(snap.Path); err != nil || fi.Mode().Perm() != 0o600 {
+		t.Errorf("snapshot mode = %v, %v", fi.Mode(), err)
+	}
+	if err := Backup(ctx, conn, snap.Path); err == nil {
+		t.Error("Backup overwrote an existing file")
+	}
+
+	if _, err := conn.Exec(`INSERT INTO workspaces (name) VALUES ('after')`); err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	if err := Replace(ctx, live, snap.Path); err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err = Open(live)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	var n int
+	conn.QueryRow(`SELECT COUNT(*) FROM workspaces`).Scan(&n)
+	if n != 1 {
+		t.Errorf("restored database has %d workspaces, want 1", n)
+	}
+
+	snaps, err := ListSnapshots(live)
+	if err != nil || len(snaps) != 1 || snaps[0].Name != snap.Name {
+		t.Fatalf("ListSnapshots = %+v, %v", snaps, err)
+	}
+	for _, other := range []string{
+		filepath.Join(t.TempDir(), "client.db"), // same name, another directory
+		filepath.Join(filepath.Dir(live), "client-b.db"),
+	} {
+		if snaps, _ := ListSnapshots(other); len(snaps) != 0 {
+			t.Errorf("%s lists another database's snapshots: %+v", other, snaps)
+		}
+	}
+
+	if plain, err := PlaintextSnapshots(ctx, live); err != nil || len(plain) != 1 {
+		t.Fatalf("PlaintextSnapshots = %+v, %v", plain, err)
+	}
+	sealed, err := Open(snap.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = SetSetting(ctx, sealed, SettingEncryption, `{"kdf":"argon2id"}`)
+	sealed.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain, err := PlaintextSnapshots(ctx, live); err != nil || len(plain) != 0 {
+		t.Errorf("PlaintextSnapshots after encryption = %+v, %v", plain, err)
+	}
+}
+
+// Paths with URI characters open as files, and snapshots taken in the same
+// second get their own names.
+func TestSnapshotNames(t *testing.T) {
+	ctx := context.Background()
+	live := filepath.Join(t.TempDir(), "acme?#50%.db")
+	conn, err := Open(live)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.Exec(`INSERT INTO workspaces (name) VALUES ('acme')`); err != nil {
+		t.Fatal(err)
+	}
+	ro, err := OpenReadOnly(live)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	ro.QueryRow(`SELECT COUNT(*) FROM workspaces`).Scan(&n)
+	ro.Close()
+	if n != 1 {
+		t.Errorf("read-only open of %s sees %d workspaces, want 1", live, n)
+	}
+	first, err := TakeSnapshot(ctx, conn, live)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := TakeSnapshot(ctx, conn, live)
+	if err != nil || second.Name == first.Name {
+		t.Fatalf("second snapshot = %+v, %v", second, err)
+	}
+	if err := CheckFile(ctx, second.Path); err != nil {
+		t.Error(err)
+	}
+
+	// Names from before sub-second stamps are still listed.
+	dir, _ := SnapshotDir(live)
+	if err := os.WriteFile(filepath.Join(dir, "acme?#50%-20240101-120000.db"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	snaps, err := ListSnapshots(live)
+	if err != nil || len(snaps) != 3 || snaps[0].Name != second.Name || snaps[2].CreatedAt.Year() != 2024 {
+		t.Errorf("ListSnapshots = %+v, %v", snaps, err)
+	}
+}
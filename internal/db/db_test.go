@@ -0,0 +1,67 @@
+package db
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestActivityTriggers(t *testing.T) {
+	conn, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	const stale = "2000-01-01 00:00:00"
+	for _, stmt := range []string{
+		`INSERT INTO assets (workspace_id, type, value) VALUES (1, 'ip', '10.0.0.1')`,
+		`INSERT INTO ports (asset_id, port) VALUES (1, 22)`,
+		`UPDATE ports SET banner = 'SSH-2.0' WHERE id = 1`,
+		`INSERT INTO tool_runs (workspace_id, tool_name, target) VALUES (1, 'nmap', 'x')`,
+		`UPDATE tool_runs SET status = 'completed' WHERE id = 1`,
+	} {
+		if _, err := conn.Exec(`INSERT OR IGNORE INTO workspaces (id, name) VALUES (1, 'acme');
+			UPDATE workspaces SET updated_at = ? WHERE id = 1`, stale); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := conn.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+		var updated time.Time
+		if err := conn.QueryRow(`SELECT updated_at FROM workspaces WHERE id = 1`).Scan(&updated); err != nil {
+			t.Fatal(err)
+		}
+		if updated.Year() == 2000 {
+			t.Errorf("%s: workspace updated_at = %s, not bumped", stmt, updated)
+		}
+	}
+}
+
+func TestGlobalPresetsUnique(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	conn, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Databases from before the index may hold duplicates.
+	if _, err := conn.Exec(`DROP INDEX idx_run_presets_global;
+		INSERT INTO run_presets (tool_name, name, args_json) VALUES ('nmap', 'full', '["old"]'), ('nmap', 'full', '["new"]')`); err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+
+	if conn, err = Open(path); err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	var n int
+	var args string
+	conn.QueryRow(`SELECT COUNT(*), MAX(args_json) FROM run_presets`).Scan(&n, &args)
+	if n != 1 || args != `["new"]` {
+		t.Errorf("presets = %d (%s), want the newest only", n, args)
+	}
+	if _, err := conn.Exec(`INSERT INTO run_presets (tool_name, name) VALUES ('nmap', 'full')`); err == nil {
+		t.Error("inserted a duplicate global preset")
+	}
+}
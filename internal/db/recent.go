@@ -0,0 +1,89 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// maxRecent caps the recent databases list.
+const maxRecent = 10
+
+// recentFile returns ~/.nser/recent.json. The list lives outside any one
+// database since it is used to pick between them.
+func recentFile() (string, error) {
+	dir, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "recent.json"), nil
+}
+
+// RecentPaths returns recently opened database files, most recent first.
+func RecentPaths() ([]string, error) {
+	file, err := recentFile()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read recent databases: %w", err)
+	}
+	var paths []string
+	if err := json.Unmarshal(data, &paths); err != nil {
+		return nil, fmt.Errorf("decode recent databases: %w", err)
+	}
+	return paths, nil
+}
+
+// AddRecentPath moves path to the front of the recent databases list.
+func AddRecentPath(path string) error {
+	paths, err := RecentPaths()
+	if err != nil {
+		return err
+	}
+	return saveRecent(append([]string{path}, without(paths, path)...))
+}
+
+// RemoveRecentPath drops path from the recent databases list. The file
+// itself is left alone.
+func RemoveRecentPath(path string) error {
+	paths, err := RecentPaths()
+	if err != nil {
+		return err
+	}
+	return saveRecent(without(paths, path))
+}
+
+func saveRecent(paths []string) error {
+	if len(paths) > maxRecent {
+		paths = paths[:maxRecent]
+	}
+	file, err := recentFile()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(paths, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode recent databases: %w", err)
+	}
+	if err := os.WriteFile(file, data, 0o600); err != nil {
+		return fmt.Errorf("write recent databases: %w", err)
+	}
+	return nil
+}
+
+func without(paths []string, path string) []string {
+	var out []string
+	for _, p := range paths {
+		if p != path {
+			out = append(out, p)
+		}
+	}
+	return out
+}
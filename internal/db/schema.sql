@@ -1,10 +1,21 @@
 CREATE TABLE IF NOT EXISTS workspaces (
-    id          INTEGER PRIMARY KEY AUTOINCREMENT,
-    name        TEXT NOT NULL UNIQUE,
-    description TEXT DEFAULT '',
-    target      TEXT DEFAULT '',
-    created_at  DATETIME DEFAULT CURRENT_TIMESTAMP,
-    updated_at  DATETIME DEFAULT CURRENT_TIMESTAMP
+    id                INTEGER PRIMARY KEY AUTOINCREMENT,
+    name              TEXT NOT NULL UNIQUE,
+    description       TEXT DEFAULT '',
+    target            TEXT DEFAULT '',
+    scope             TEXT DEFAULT '',
+    archived_at       DATETIME,
+    deleted_at        DATETIME,
+    client            TEXT DEFAULT '',
+    window_start      TEXT DEFAULT '',
+    window_end        TEXT DEFAULT '',
+    window_policy     TEXT DEFAULT 'warn',
+    testers           TEXT DEFAULT '',
+    contact           TEXT DEFAULT '',
+    authorization_ref TEXT DEFAULT '',
+    roe_notes         TEXT DEFAULT '',
+    created_at        DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at        DATETIME DEFAULT CURRENT_TIMESTAMP
 );
 
 CREATE TABLE IF NOT EXISTS assets (
@@ -17,44 +28,226 @@ CREATE TABLE IF NOT EXISTS assets (
 );
 
 CREATE TABLE IF NOT EXISTS ports (
-    id       INTEGER PRIMARY KEY AUTOINCREMENT,
-    asset_id INTEGER NOT NULL REFERENCES assets(id) ON DELETE CASCADE,
-    port     INTEGER NOT NULL,
-    protocol TEXT DEFAULT 'tcp',
-    service  TEXT DEFAULT '',
-    state    TEXT DEFAULT 'open',
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    asset_id   INTEGER NOT NULL REFERENCES assets(id) ON DELETE CASCADE,
+    port       INTEGER NOT NULL,
+    protocol   TEXT DEFAULT 'tcp',
+    service    TEXT DEFAULT '',
+    state      TEXT DEFAULT 'open',
+    product    TEXT DEFAULT '',
+    version    TEXT DEFAULT '',
+    extra_info TEXT DEFAULT '',
+    cpe        TEXT DEFAULT '',
+    banner     TEXT DEFAULT '',
+    tls        INTEGER DEFAULT 0,
+    run_id     INTEGER REFERENCES tool_runs(id) ON DELETE SET NULL,
+    updated_at DATETIME,
     UNIQUE(asset_id, port, protocol)
 );
 
 CREATE TABLE IF NOT EXISTS tool_runs (
-    id           INTEGER PRIMARY KEY AUTOINCREMENT,
-    workspace_id INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
-    tool_name    TEXT NOT NULL,
-    target       TEXT NOT NULL,
-    args         TEXT DEFAULT '',
-    command_line TEXT DEFAULT '',
-    raw_output   BLOB,
-    parsed_json  TEXT,
-    status       TEXT DEFAULT 'running' CHECK(status IN ('running', 'completed', 'failed')),
-    exit_code    INTEGER DEFAULT 0,
-    started_at   DATETIME DEFAULT CURRENT_TIMESTAMP,
-    completed_at DATETIME
+    id            INTEGER PRIMARY KEY AUTOINCREMENT,
+    workspace_id  INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    tool_name     TEXT NOT NULL,
+    target        TEXT NOT NULL,
+    args          TEXT DEFAULT '',
+    args_json     TEXT,
+    options_json  TEXT,
+    template_json TEXT,
+    command_line  TEXT DEFAULT '',
+    elevation     TEXT DEFAULT '',
+    network_json  TEXT,
+    secrets_json  BLOB,
+    raw_output    BLOB,
+    parsed_json   TEXT,
+    status        TEXT DEFAULT 'running' CHECK(status IN ('running', 'completed', 'failed')),
+    exit_code     INTEGER DEFAULT 0,
+    started_at    DATETIME DEFAULT CURRENT_TIMESTAMP,
+    completed_at  DATETIME,
+    deleted_at    DATETIME
 );
 
 CREATE TABLE IF NOT EXISTS tool_docs (
     id            INTEGER PRIMARY KEY AUTOINCREMENT,
     tool_name     TEXT NOT NULL UNIQUE,
     documentation TEXT DEFAULT '',
+    customized    INTEGER DEFAULT 0,
+    seed_version  INTEGER DEFAULT 0,
     created_at    DATETIME DEFAULT CURRENT_TIMESTAMP,
     updated_at    DATETIME DEFAULT CURRENT_TIMESTAMP
 );
 
 CREATE TABLE IF NOT EXISTS tool_examples (
-    id          INTEGER PRIMARY KEY AUTOINCREMENT,
-    tool_name   TEXT NOT NULL,
-    title       TEXT NOT NULL,
-    description TEXT DEFAULT '',
-    command     TEXT NOT NULL,
-    sort_order  INTEGER DEFAULT 0,
-    created_at  DATETIME DEFAULT CURRENT_TIMESTAMP
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    tool_name    TEXT NOT NULL,
+    title        TEXT NOT NULL,
+    description  TEXT DEFAULT '',
+    command      TEXT NOT NULL,
+    sort_order   INTEGER DEFAULT 0,
+    seed_key     TEXT,
+    seed_version INTEGER DEFAULT 0,
+    customized   INTEGER DEFAULT 0,
+    hidden       INTEGER DEFAULT 0,
+    created_at   DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS custom_tools (
+    name       TEXT PRIMARY KEY,
+    spec_json  TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS run_presets (
+    id            INTEGER PRIMARY KEY AUTOINCREMENT,
+    workspace_id  INTEGER REFERENCES workspaces(id) ON DELETE CASCADE,
+    tool_name     TEXT NOT NULL,
+    name          TEXT NOT NULL,
+    description   TEXT DEFAULT '',
+    options_json  TEXT,
+    args_json     TEXT,
+    created_at    DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at    DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(workspace_id, tool_name, name)
+);
+
+CREATE TABLE IF NOT EXISTS tool_health (
+    tool_name   TEXT PRIMARY KEY,
+    path        TEXT DEFAULT '',
+    mtime       INTEGER DEFAULT 0,
+    health_json TEXT NOT NULL,
+    checked_at  DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS tool_installs (
+    id            INTEGER PRIMARY KEY AUTOINCREMENT,
+    tool_name     TEXT NOT NULL,
+    manager       TEXT NOT NULL,
+    command_line  TEXT NOT NULL,
+    status        TEXT NOT NULL DEFAULT 'running',
+    exit_code     INTEGER,
+    output        BLOB,
+    path          TEXT DEFAULT '',
+    added_to_path TEXT DEFAULT '',
+    started_at    DATETIME NOT NULL,
+    completed_at  DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS search_paths (
+    dir      TEXT PRIMARY KEY,
+    added_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS settings (
+    key   TEXT PRIMARY KEY,
+    value TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS network_profiles (
+    workspace_id INTEGER PRIMARY KEY REFERENCES workspaces(id) ON DELETE CASCADE,
+    profile_json TEXT NOT NULL,
+    updated_at   DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS secrets (
+    name       TEXT PRIMARY KEY,
+    nonce      BLOB NOT NULL,
+    ciphertext BLOB NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS run_env (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    workspace_id INTEGER REFERENCES workspaces(id) ON DELETE CASCADE,
+    tool_name    TEXT NOT NULL DEFAULT '',
+    name         TEXT NOT NULL,
+    value        TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS findings (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    workspace_id INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    asset_id     INTEGER NOT NULL REFERENCES assets(id) ON DELETE CASCADE,
+    port_id      INTEGER REFERENCES ports(id) ON DELETE CASCADE,
+    cve_id       TEXT DEFAULT '',
+    title        TEXT NOT NULL,
+    description  TEXT DEFAULT '',
+    severity     TEXT DEFAULT '',
+    cvss         REAL DEFAULT 0,
+    cvss_vector  TEXT DEFAULT '',
+    kev          INTEGER DEFAULT 0,
+    status       TEXT NOT NULL DEFAULT 'suggested' CHECK (status IN ('suggested', 'confirmed', 'dismissed')),
+    source       TEXT DEFAULT '',
+    created_at   DATETIME NOT NULL,
+    updated_at   DATETIME NOT NULL,
+    UNIQUE(port_id, cve_id)
+);
+
+CREATE TABLE IF NOT EXISTS credentials (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    workspace_id INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    asset_id     INTEGER REFERENCES assets(id) ON DELETE SET NULL,
+    host         TEXT NOT NULL DEFAULT '',
+    port         INTEGER NOT NULL DEFAULT 0,
+    service      TEXT NOT NULL DEFAULT '',
+    realm        TEXT NOT NULL DEFAULT '',
+    username     TEXT NOT NULL DEFAULT '',
+    secret       BLOB NOT NULL,
+    type         TEXT NOT NULL DEFAULT 'password' CHECK (type IN ('password', 'hash', 'token')),
+    validated    INTEGER NOT NULL DEFAULT 0,
+    run_id       INTEGER REFERENCES tool_runs(id) ON DELETE SET NULL,
+    created_at   DATETIME NOT NULL,
+    updated_at   DATETIME NOT NULL,
+    UNIQUE(workspace_id, host, port, service, realm, username, type)
 );
+
+CREATE TABLE IF NOT EXISTS notes (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    workspace_id INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    entity_type  TEXT NOT NULL CHECK (entity_type IN ('workspace', 'asset', 'port', 'finding', 'run')),
+    entity_id    INTEGER NOT NULL,
+    body         BLOB NOT NULL,
+    created_at   DATETIME NOT NULL,
+    updated_at   DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_notes_entity ON notes(entity_type, entity_id);
+
+CREATE TABLE IF NOT EXISTS tags (
+    workspace_id INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    entity_type  TEXT NOT NULL CHECK (entity_type IN ('workspace', 'asset', 'port', 'finding', 'run')),
+    entity_id    INTEGER NOT NULL,
+    tag          TEXT NOT NULL,
+    created_at   DATETIME NOT NULL,
+    PRIMARY KEY (entity_type, entity_id, tag)
+);
+
+CREATE INDEX IF NOT EXISTS idx_tags_tag ON tags(workspace_id, tag);
+
+CREATE TABLE IF NOT EXISTS evidence (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    workspace_id INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    entity_type  TEXT NOT NULL CHECK (entity_type IN ('workspace', 'asset', 'port', 'finding', 'run')),
+    entity_id    INTEGER NOT NULL,
+    kind         TEXT NOT NULL CHECK (kind IN ('screenshot', 'http', 'file')),
+    title        TEXT NOT NULL DEFAULT '',
+    filename     TEXT NOT NULL DEFAULT '',
+    mime_type    TEXT NOT NULL DEFAULT '',
+    size         INTEGER NOT NULL DEFAULT 0,
+    data         BLOB NOT NULL,
+    created_at   DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_evidence_entity ON evidence(entity_type, entity_id);
+
+CREATE TABLE IF NOT EXISTS audit_log (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    at           TEXT NOT NULL,
+    workspace_id INTEGER NOT NULL DEFAULT 0,
+    action       TEXT NOT NULL,
+    detail_json  TEXT NOT NULL,
+    prev_hash    TEXT NOT NULL,
+    hash         TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_workspace ON audit_log(workspace_id);
@@ -0,0 +1,189 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Markers around matched terms in SearchHit.Snippet. They are control
+// characters rather than HTML because snippets quote untrusted tool output.
+const (
+	SnippetStart = "\x02"
+	SnippetEnd   = "\x03"
+)
+
+// searchLimit caps the number of hits Search returns.
+const searchLimit = 100
+
+// SearchHit is one ranked match. Kind and ID link back to the source row
+// ("run" → tool_runs.id, "asset" → assets.id, "finding" → findings.id,
+// "note" → notes.id).
+type SearchHit struct {
+	Kind        string  `json:"kind"`
+	ID          int64   `json:"id"`
+	WorkspaceID int64   `json:"workspaceId"`
+	Title       string  `json:"title"`
+	Snippet     string  `json:"snippet"`
+	Rank        float64 `json:"rank"` // bm25; lower is better
+}
+
+// searchSource is an FTS5 table indexing one source table. Its rowid is the
+// source row's ID, and triggers keep it in sync. Append-only.
+type searchSource struct {
+	kind, table string
+	schema      []string // run by migrate after columnMigrations
+}
+
+// searchText returns the text to index for a possibly sealed column of
+// row ("NEW" in triggers). Values encrypted by internal/vault ("nser:enc:"
+// prefix) are left out so the index never holds their plaintext.
+func searchText(row, column string) string {
+	return fmt.Sprintf(`CASE WHEN CAST(%[1]s.%[2]s AS TEXT) GLOB 'nser:enc:*' THEN '' ELSE COALESCE(CAST(%[1]s.%[2]s AS TEXT), '') END`, row, column)
+}
+
+func runSearchValues(row string) string {
+	return fmt.Sprintf(`%[1]s.id, %[1]s.workspace_id, %[1]s.tool_name || ' ' || %[1]s.target,
+		COALESCE(%[1]s.command_line, '') || char(10) || %[2]s || char(10) || %[3]s`,
+		row, searchText(row, "raw_output"), searchText(row, "parsed_json"))
+}
+
+var searchSources = []searchSource{
+	{"run", "run_search", []string{
+		`CREATE VIRTUAL TABLE IF NOT EXISTS run_search USING fts5(title, body, workspace_id UNINDEXED)`,
+		`CREATE TRIGGER IF NOT EXISTS run_search_insert AFTER INSERT ON tool_runs BEGIN
+			INSERT INTO run_search (rowid, workspace_id, title, body) VALUES (` + runSearchValues("NEW") + `);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS run_search_update AFTER UPDATE ON tool_runs BEGIN
+			DELETE FROM run_search WHERE rowid = OLD.id;
+			INSERT INTO run_search (rowid, workspace_id, title, body) VALUES (` + runSearchValues("NEW") + `);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS run_search_delete AFTER DELETE ON tool_runs BEGIN
+			DELETE FROM run_search WHERE rowid = OLD.id;
+		END`,
+		`INSERT INTO run_search (rowid, workspace_id, title, body)
+		 SELECT ` + runSearchValues("tool_runs") + ` FROM tool_runs
+		 WHERE id NOT IN (SELECT rowid FROM run_search)`,
+	}},
+	{"asset", "asset_search", []string{
+		`CREATE VIRTUAL TABLE IF NOT EXISTS asset_search USING fts5(title, body, workspace_id UNINDEXED)`,
+		`CREATE TRIGGER IF NOT EXISTS asset_search_insert AFTER INSERT ON assets BEGIN
+			INSERT INTO asset_search (rowid, workspace_id, title, body) VALUES (NEW.id, NEW.workspace_id, NEW.value, NEW.type);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS asset_search_update AFTER UPDATE ON assets BEGIN
+			DELETE FROM asset_search WHERE rowid = OLD.id;
+			INSERT INTO asset_search (rowid, workspace_id, title, body) VALUES (NEW.id, NEW.workspace_id, NEW.value, NEW.type);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS asset_search_delete AFTER DELETE ON assets BEGIN
+			DELETE FROM asset_search WHERE rowid = OLD.id;
+		END`,
+		`INSERT INTO asset_search (rowid, workspace_id, title, body)
+		 SELECT id, workspace_id, value, type FROM assets
+		 WHERE id NOT IN (SELECT rowid FROM asset_search)`,
+	}},
+	{"finding", "finding_search", []string{
+		`CREATE VIRTUAL TABLE IF NOT EXISTS finding_search USING fts5(title, body, workspace_id UNINDEXED)`,
+		`CREATE TRIGGER IF NOT EXISTS finding_search_insert AFTER INSERT ON findings BEGIN
+			INSERT INTO finding_search (rowid, workspace_id, title, body) VALUES (NEW.id, NEW.workspace_id, NEW.title, NEW.cve_id || char(10) || NEW.description);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS finding_search_update AFTER UPDATE ON findings BEGIN
+			DELETE FROM finding_search WHERE rowid = OLD.id;
+			INSERT INTO finding_search (rowid, workspace_id, title, body) VALUES (NEW.id, NEW.workspace_id, NEW.title, NEW.cve_id || char(10) || NEW.description);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS finding_search_delete AFTER DELETE ON findings BEGIN
+			DELETE FROM finding_search WHERE rowid = OLD.id;
+		END`,
+		`INSERT INTO finding_search (rowid, workspace_id, title, body)
+		 SELECT id, workspace_id, title, cve_id || char(10) || description FROM findings
+		 WHERE id NOT IN (SELECT rowid FROM finding_search)`,
+	}},
+	{"note", "note_search", []string{
+		`CREATE VIRTUAL TABLE IF NOT EXISTS note_search USING fts5(title, body, workspace_id UNINDEXED)`,
+		`CREATE TRIGGER IF NOT EXISTS note_search_insert AFTER INSERT ON notes BEGIN
+			INSERT INTO note_search (rowid, workspace_id, title, body) VALUES (NEW.id, NEW.workspace_id, NEW.entity_type || ' note', ` + searchText("NEW", "body") + `);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS note_search_update AFTER UPDATE ON notes BEGIN
+			DELETE FROM note_search WHERE rowid = OLD.id;
+			INSERT INTO note_search (rowid, workspace_id, title, body) VALUES (NEW.id, NEW.workspace_id, NEW.entity_type || ' note', ` + searchText("NEW", "body") + `);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS note_search_delete AFTER DELETE ON notes BEGIN
+			DELETE FROM note_search WHERE rowid = OLD.id;
+		END`,
+		`INSERT INTO note_search (rowid, workspace_id, title, body)
+		 SELECT id, workspace_id, entity_type || ' note', ` + searchText("notes", "body") + ` FROM notes
+		 WHERE id NOT IN (SELECT rowid FROM note_search)`,
+	}},
+}
+
+// matchQuery turns user input into an FTS5 query: every whitespace-separated
+// term must match, as a literal phrase, so input like "Apache/2.4.49" or
+// "a-b" isn't parsed as FTS syntax. A trailing '*' keeps prefix matching.
+func matchQuery(input string) string {
+	var terms []string
+	for _, f := range strings.Fields(input) {
+		prefix := strings.HasSuffix(f, "*") && len(f) > 1
+		f = strings.TrimSuffix(f, "*")
+		term := `"` + strings.ReplaceAll(f, `"`, `""`) + `"`
+		if prefix {
+			term += "*"
+		}
+		terms = append(terms, term)
+	}
+	return strings.Join(terms, " ")
+}
+
+// Search returns the best matches for query across every indexed source,
+// in one workspace or, with workspaceID 0, in all of them.
+func Search(ctx context.Context, db *sql.DB, workspaceID int64, query string) ([]SearchHit, error) {
+	match := matchQuery(query)
+	if match == "" {
+		return []SearchHit{}, nil
+	}
+
+	parts := make([]string, 0, len(searchSources))
+	var args []any
+	for _, src := range searchSources {
+		// Items in the trash, or in a workspace in the trash, are left out.
+		hidden := ""
+		if q, ok := trashedRows[src.kind]; ok {
+			hidden = " AND rowid NOT IN (" + q + ")"
+		}
+		parts = append(parts, fmt.Sprintf(
+			`SELECT '%[1]s', rowid, workspace_id, title, snippet(%[2]s, 1, ?, ?, '…', 16), bm25(%[2]s)
+			 FROM %[2]s WHERE %[2]s MATCH ? AND (? = 0 OR workspace_id = ?)
+			   AND workspace_id NOT IN (SELECT id FROM workspaces WHERE deleted_at IS NOT NULL)%[3]s`,
+			src.kind, src.table, hidden))
+		args = append(args, SnippetStart, SnippetEnd, match, workspaceID, workspaceID)
+	}
+	args = append(args, searchLimit)
+
+	rows, err := db.QueryContext(ctx, strings.Join(parts, " UNION ALL ")+" ORDER BY 6 LIMIT ?", args...)
+	if err != nil {
+		return nil, fmt.Errorf("search: %w", err)
+	}
+	defer rows.Close()
+
+	result := []SearchHit{}
+	for rows.Next() {
+		var h SearchHit
+		if err := rows.Scan(&h.Kind, &h.ID, &h.WorkspaceID, &h.Title, &h.Snippet, &h.Rank); err != nil {
+			return nil, fmt.Errorf("scan search hit: %w", err)
+		}
+		result = append(result, h)
+	}
+	return result, rows.Err()
+}
+
+// OptimizeSearch merges the search indexes, dropping entries for deleted
+// rows. Run after removing sensitive text so no trace of it stays on disk.
+func OptimizeSearch(ctx context.Context, db *sql.DB) error {
+	for _, src := range searchSources {
+		if _, err := db.ExecContext(ctx,
+			fmt.Sprintf(`INSERT INTO %[1]s (%[1]s) VALUES ('optimize')`, src.table),
+		); err != nil {
+			return fmt.Errorf("optimize %s: %w", src.table, err)
+		}
+	}
+	return nil
+}
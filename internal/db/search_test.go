@@ -0,0 +1,54 @@
+package db
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	ctx := context.Background()
+	conn, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.Exec(`
+		INSERT INTO workspaces (id, name) VALUES (1, 'acme'), (2, 'globex');
+		INSERT INTO tool_runs (id, workspace_id, tool_name, target, command_line) VALUES (1, 1, 'nmap', '10.0.0.1', 'nmap -sV 10.0.0.1');
+		INSERT INTO assets (id, workspace_id, type, value) VALUES (7, 2, 'domain', 'apache.globex.test');`); err != nil {
+		t.Fatal(err)
+	}
+	// Output arrives when the run finishes; the index follows the update.
+	if _, err := conn.Exec(`UPDATE tool_runs SET raw_output = ? WHERE id = 1`,
+		[]byte("80/tcp open http\nServer: Apache/2.4.49 (Unix)")); err != nil {
+		t.Fatal(err)
+	}
+
+	hits, err := Search(ctx, conn, 0, "Apache/2.4.49")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 1 || hits[0].Kind != "run" || hits[0].ID != 1 {
+		t.Fatalf("hits = %+v", hits)
+	}
+	if !strings.Contains(hits[0].Snippet, SnippetStart+"Apache/2.4.49"+SnippetEnd) {
+		t.Errorf("snippet = %q", hits[0].Snippet)
+	}
+
+	if hits, _ := Search(ctx, conn, 0, "apache"); len(hits) != 2 {
+		t.Errorf("all workspaces: %d hits, want 2", len(hits))
+	}
+	if hits, _ := Search(ctx, conn, 2, "apach*"); len(hits) != 1 || hits[0].Kind != "asset" {
+		t.Errorf("workspace 2: %+v", hits)
+	}
+
+	conn.Exec(`DELETE FROM tool_runs WHERE id = 1`)
+	if hits, _ := Search(ctx, conn, 1, "apache"); len(hits) != 0 {
+		t.Errorf("deleted run still found: %+v", hits)
+	}
+	if hits, err := Search(ctx, conn, 0, `"unbalanced AND (`); err != nil || len(hits) != 0 {
+		t.Errorf("odd input: %+v, %v", hits, err)
+	}
+}
@@ -0,0 +1,104 @@
+package db
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestSeedToolDocs(t *testing.T) {
+	conn, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	count := func(query string, args ...any) int {
+		t.Helper()
+		var n int
+		if err := conn.QueryRow(query, args...).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	if n := count(`SELECT COUNT(*) FROM tool_examples WHERE seed_key IS NOT NULL`); n != len(shippedExamples) {
+		t.Fatalf("seeded %d examples, want %d", n, len(shippedExamples))
+	}
+
+	// An older seed version: untouched rows are refreshed, customized and
+	// hidden ones are left alone.
+	for _, stmt := range []string{
+		`UPDATE tool_docs SET seed_version = 0, documentation = 'old' WHERE tool_name IN ('nmap', 'dig')`,
+		`UPDATE tool_docs SET customized = 1 WHERE tool_name = 'dig'`,
+		`UPDATE tool_examples SET seed_version = 0, title = 'old' WHERE seed_key IN ('nmap:1', 'nmap:2', 'nmap:3')`,
+		`UPDATE tool_examples SET customized = 1 WHERE seed_key = 'nmap:2'`,
+		`UPDATE tool_examples SET hidden = 1 WHERE seed_key = 'nmap:3'`,
+	} {
+		if _, err := conn.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	seedToolDocs(conn)
+	if n := count(`SELECT COUNT(*) FROM tool_docs WHERE documentation = 'old'`); n != 1 {
+		t.Errorf("%d docs still old, want only the customized one", n)
+	}
+	for key, want := range map[string]string{"nmap:1": shippedExamples[0].title, "nmap:2": "old", "nmap:3": "old"} {
+		var title string
+		conn.QueryRow(`SELECT title FROM tool_examples WHERE seed_key = ?`, key).Scan(&title)
+		if title != want {
+			t.Errorf("example %s title = %q, want %q", key, title, want)
+		}
+	}
+
+	// Databases from before seed keys: the oldest untagged copy of a shipped
+	// example is adopted, the other copies removed; user examples stay.
+	e := shippedExamples[0]
+	if _, err := conn.Exec(`DELETE FROM tool_examples WHERE seed_key = ?`, e.key()); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := conn.Exec(`INSERT INTO tool_examples (tool_name, title, command) VALUES (?, ?, ?)`,
+			e.tool, e.title, e.cmd); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := conn.Exec(`INSERT INTO tool_examples (tool_name, title, command) VALUES ('nmap', 'mine', 'nmap -Pn 10.0.0.1')`); err != nil {
+		t.Fatal(err)
+	}
+	var oldest int64
+	conn.QueryRow(`SELECT MIN(id) FROM tool_examples WHERE seed_key IS NULL AND title = ?`, e.title).Scan(&oldest)
+	seedToolDocs(conn)
+	if n := count(`SELECT COUNT(*) FROM tool_examples WHERE tool_name = ? AND title = ?`, e.tool, e.title); n != 1 {
+		t.Errorf("%d copies of %q after seeding, want 1", n, e.title)
+	}
+	if n := count(`SELECT COUNT(*) FROM tool_examples WHERE id = ? AND seed_key = ?`, oldest, e.key()); n != 1 {
+		t.Errorf("oldest copy %d not adopted as %s", oldest, e.key())
+	}
+	if n := count(`SELECT COUNT(*) FROM tool_examples WHERE title = 'mine' AND seed_key IS NULL`); n != 1 {
+		t.Error("user example removed or tagged")
+	}
+
+	// Reset restores one tool's shipped content, keeping user examples.
+	if err := ResetToolDocs(context.Background(), conn, "nmap"); err != nil {
+		t.Fatal(err)
+	}
+	if n := count(`SELECT COUNT(*) FROM tool_examples WHERE tool_name = 'nmap' AND (title = 'old' OR hidden = 1 OR customized = 1)`); n != 0 {
+		t.Errorf("%d nmap examples not reset", n)
+	}
+	if n := count(`SELECT COUNT(*) FROM tool_examples WHERE title = 'mine'`); n != 1 {
+		t.Error("reset removed a user example")
+	}
+	if n := count(`SELECT COUNT(*) FROM tool_docs WHERE tool_name = 'dig' AND documentation = 'old'`); n != 1 {
+		t.Error("resetting nmap touched dig's docs")
+	}
+	if err := ResetToolDocs(context.Background(), conn, "nope"); err == nil {
+		t.Error("ResetToolDocs for an unknown tool succeeded")
+	}
+
+	if doc, ok := ShippedDoc(shippedDocs[0].name); !ok || doc != shippedDocs[0].doc {
+		t.Errorf("ShippedDoc(%s) = %v", shippedDocs[0].name, ok)
+	}
+	if _, ok := ShippedDoc("nope"); ok {
+		t.Error("ShippedDoc found docs for an unknown tool")
+	}
+}
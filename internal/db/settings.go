@@ -0,0 +1,53 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// Setting keys stored in the settings table.
+const (
+	SettingElevationPolicy = "elevation_policy"
+	SettingEncryption      = "encryption"
+	SettingSnapshotHours   = "snapshot_interval_hours"
+	SettingSnapshotKeep    = "snapshot_keep"
+)
+
+// GetSetting returns the value stored under key, or "" if it was never set.
+func GetSetting(ctx context.Context, db *sql.DB, key string) (string, error) {
+	var value string
+	err := db.QueryRowContext(ctx, `SELECT value FROM settings WHERE key = ?`, key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("get setting %q: %w", key, err)
+	}
+	return value, nil
+}
+
+// SetSetting stores value under key, replacing any previous value.
+func SetSetting(ctx context.Context, db *sql.DB, key, value string) error {
+	return setSetting(ctx, db, key, value)
+}
+
+// SetSettingTx is SetSetting inside tx.
+func SetSettingTx(ctx context.Context, tx *sql.Tx, key, value string) error {
+	return setSetting(ctx, tx, key, value)
+}
+
+func setSetting(ctx context.Context, db interface {
+	ExecContext(context.Context, string, ...any) (sql.Result, error)
+}, key, value string) error {
+	_, err := db.ExecContext(ctx,
+		`INSERT INTO settings (key, value) VALUES (?, ?)
+		 ON CONFLICT(key) DO UPDATE SET value = excluded.value`,
+		key, value,
+	)
+	if err != nil {
+		return fmt.Errorf("set setting %q: %w", key, err)
+	}
+	return nil
+}
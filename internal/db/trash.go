@@ -0,0 +1,104 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// TrashRetention is how long deleted runs and workspaces stay in the trash,
+// where they can be restored, before PurgeExpired removes them for good.
+const TrashRetention = 30 * 24 * time.Hour
+
+// trashTables maps the kinds of item that are soft deleted onto their
+// table. Deleting one sets deleted_at; its rows (and for a workspace,
+// everything in it) stay until it is purged.
+var trashTables = map[string]string{
+	"run":       "tool_runs",
+	"workspace": "workspaces",
+}
+
+// trashedRows selects, per search source kind, the IDs of rows in the trash
+// other than by their workspace, so Search can leave them out.
+var trashedRows = map[string]string{
+	"run": `SELECT id FROM tool_runs WHERE deleted_at IS NOT NULL`,
+}
+
+func trashTable(kind string) (string, error) {
+	table, ok := trashTables[kind]
+	if !ok {
+		return "", fmt.Errorf("unknown trash item type %q", kind)
+	}
+	return table, nil
+}
+
+// MoveToTrash soft deletes a run or workspace.
+func MoveToTrash(ctx context.Context, tx *sql.Tx, kind string, id int64) error {
+	table, err := trashTable(kind)
+	if err != nil {
+		return err
+	}
+	res, err := tx.ExecContext(ctx,
+		`UPDATE `+table+` SET deleted_at = CURRENT_TIMESTAMP WHERE id = ? AND deleted_at IS NULL`, id)
+	if err != nil {
+		return fmt.Errorf("delete %s %d: %w", kind, id, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("%s %d not found", kind, id)
+	}
+	return nil
+}
+
+// Restore takes a run or workspace out of the trash.
+func Restore(ctx context.Context, tx *sql.Tx, kind string, id int64) error {
+	table, err := trashTable(kind)
+	if err != nil {
+		return err
+	}
+	res, err := tx.ExecContext(ctx,
+		`UPDATE `+table+` SET deleted_at = NULL WHERE id = ? AND deleted_at IS NOT NULL`, id)
+	if err != nil {
+		return fmt.Errorf("restore %s %d: %w", kind, id, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("%s %d is not in the trash", kind, id)
+	}
+	return nil
+}
+
+// Purge permanently deletes a run or workspace in the trash. Items that
+// aren't in the trash are refused, so nothing is purged by mistake. Run
+// OptimizeSearch after committing so no trace stays in the search index.
+func Purge(ctx context.Context, tx *sql.Tx, kind string, id int64) error {
+	table, err := trashTable(kind)
+	if err != nil {
+		return err
+	}
+	res, err := tx.ExecContext(ctx, `DELETE FROM `+table+` WHERE id = ? AND deleted_at IS NOT NULL`, id)
+	if err != nil {
+		return fmt.Errorf("purge %s %d: %w", kind, id, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("%s %d is not in the trash", kind, id)
+	}
+	return nil
+}
+
+// PurgeExpired permanently deletes trash items deleted more than olderThan
+// ago (all of them for 0) and returns how many went. As with Purge, run
+// OptimizeSearch after committing.
+func PurgeExpired(ctx context.Context, tx *sql.Tx, olderThan time.Duration) (int64, error) {
+	cutoff := fmt.Sprintf("-%d seconds", int64(olderThan.Seconds()))
+	var total int64
+	for _, kind := range []string{"run", "workspace"} {
+		res, err := tx.ExecContext(ctx,
+			`DELETE FROM `+trashTables[kind]+` WHERE deleted_at IS NOT NULL AND deleted_at <= datetime('now', ?)`, cutoff)
+		if err != nil {
+			return total, fmt.Errorf("purge %ss: %w", kind, err)
+		}
+		n, _ := res.RowsAffected()
+		total += n
+	}
+	return total, nil
+}
@@ -0,0 +1,93 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// inTx runs fn in a transaction on conn and commits it if fn succeeds.
+func inTx(conn *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// change applies a trash function in its own transaction.
+func change(ctx context.Context, conn *sql.DB, fn func(context.Context, *sql.Tx, string, int64) error, kind string, id int64) error {
+	return inTx(conn, func(tx *sql.Tx) error { return fn(ctx, tx, kind, id) })
+}
+
+// purgeExpired runs PurgeExpired in its own transaction.
+func purgeExpired(ctx context.Context, conn *sql.DB, olderThan time.Duration) (n int64, err error) {
+	err = inTx(conn, func(tx *sql.Tx) error {
+		n, err = PurgeExpired(ctx, tx, olderThan)
+		return err
+	})
+	return n, err
+}
+
+func TestTrash(t *testing.T) {
+	ctx := context.Background()
+	conn, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.Exec(`
+		INSERT INTO workspaces (id, name) VALUES (1, 'acme'), (2, 'globex');
+		INSERT INTO tool_runs (id, workspace_id, tool_name, target, command_line)
+		VALUES (1, 1, 'nmap', '10.0.0.1', 'nmap apache'), (2, 2, 'nmap', '10.0.0.2', 'nmap apache');
+		INSERT INTO assets (id, workspace_id, type, value) VALUES (7, 2, 'domain', 'apache.globex.test');`); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := change(ctx, conn, MoveToTrash, "run", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := change(ctx, conn, MoveToTrash, "workspace", 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := change(ctx, conn, MoveToTrash, "run", 1); err == nil {
+		t.Error("MoveToTrash succeeded twice")
+	}
+	if hits, _ := Search(ctx, conn, 0, "apache"); len(hits) != 0 {
+		t.Errorf("trashed items still found: %+v", hits)
+	}
+
+	// Undo brings everything back, including the workspace's contents.
+	if err := change(ctx, conn, Restore, "workspace", 2); err != nil {
+		t.Fatal(err)
+	}
+	if hits, _ := Search(ctx, conn, 0, "apache"); len(hits) != 2 {
+		t.Errorf("after restore: %d hits, want 2", len(hits))
+	}
+	if err := change(ctx, conn, Purge, "workspace", 2); err == nil {
+		t.Error("Purge deleted a workspace that isn't in the trash")
+	}
+
+	// Only items past the retention period expire.
+	if err := change(ctx, conn, MoveToTrash, "workspace", 2); err != nil {
+		t.Fatal(err)
+	}
+	conn.Exec(`UPDATE tool_runs SET deleted_at = datetime('now', '-40 days') WHERE id = 1`)
+	if n, err := purgeExpired(ctx, conn, TrashRetention); err != nil || n != 1 {
+		t.Fatalf("PurgeExpired = %d, %v; want 1", n, err)
+	}
+	if n, err := purgeExpired(ctx, conn, 0); err != nil || n != 1 {
+		t.Fatalf("PurgeExpired(0) = %d, %v; want 1", n, err)
+	}
+	var left int
+	conn.QueryRow(`SELECT (SELECT COUNT(*) FROM tool_runs) + (SELECT COUNT(*) FROM assets) + (SELECT COUNT(*) FROM workspaces)`).Scan(&left)
+	if left != 1 {
+		t.Errorf("%d rows left after purging, want workspace 1 only", left)
+	}
+}
@@ -0,0 +1,43 @@
+package parse
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// hydraFound matches the line hydra prints for each valid login:
+// "[22][ssh] host: 10.0.0.1   login: root   password: toor".
+var hydraFound = regexp.MustCompile(`^\[(\d+)\]\[([\w-]+)\] host: (\S+)\s+login: (.*?)\s+password: ?(.*)$`)
+
+// HydraOutput parses hydra's stdout for valid logins. Every login hydra
+// reports has been tried against the service, so they are validated.
+func HydraOutput(data []byte) (*Result, error) {
+	result := &Result{Hosts: []Host{}}
+	seen := make(map[Credential]bool)
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for sc.Scan() {
+		m := hydraFound.FindStringSubmatch(strings.TrimRight(sc.Text(), "\r"))
+		if m == nil {
+			continue
+		}
+		port, _ := strconv.Atoi(m[1])
+		c := Credential{
+			Host:      m[3],
+			Port:      port,
+			Service:   m[2],
+			Username:  m[4],
+			Secret:    m[5],
+			Type:      CredentialPassword,
+			Validated: true,
+		}
+		if !seen[c] {
+			seen[c] = true
+			result.Credentials = append(result.Credentials, c)
+		}
+	}
+	return result, sc.Err()
+}
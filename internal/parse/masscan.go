@@ -0,0 +1,107 @@
+package parse
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// masscanRecord is one line of masscan's JSON output (-oJ). A port is
+// reported once for its status and again for every banner grabbed with
+// --banners.
+type masscanRecord struct {
+	IP    string `json:"ip"`
+	Ports []struct {
+		Port    int    `json:"port"`
+		Proto   string `json:"proto"`
+		Status  string `json:"status"`
+		Service *struct {
+			Name   string `json:"name"`
+			Banner string `json:"banner"`
+		} `json:"service"`
+	} `json:"ports"`
+}
+
+// MasscanJSON parses masscan's JSON output (-oJ), merging status and banner
+// records per port. It reads record by record because older masscan
+// versions write a trailing comma that makes the whole file invalid JSON.
+func MasscanJSON(data []byte) (*Result, error) {
+	hosts := make(map[string]*Host)
+	services := make(map[string]*Service)
+
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for sc.Scan() {
+		line := strings.TrimSuffix(strings.TrimSpace(sc.Text()), ",")
+		if !strings.HasPrefix(line, "{") {
+			continue
+		}
+		var rec masscanRecord
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			if strings.Contains(line, "finished") {
+				continue // "{finished: 1}" trailer of old versions
+			}
+			return nil, fmt.Errorf("masscan json: %w", err)
+		}
+		if rec.IP == "" {
+			continue
+		}
+		if hosts[rec.IP] == nil {
+			hosts[rec.IP] = &Host{Address: rec.IP}
+		}
+		for _, p := range rec.Ports {
+			key := fmt.Sprintf("%s/%d/%s", rec.IP, p.Port, p.Proto)
+			svc := services[key]
+			if svc == nil {
+				svc = &Service{Port: p.Port, Protocol: p.Proto, State: "open"}
+				services[key] = svc
+			}
+			if p.Status != "" {
+				svc.State = p.Status
+			}
+			if p.Service != nil {
+				mergeBanner(svc, p.Service.Name, p.Service.Banner)
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("masscan json: %w", err)
+	}
+
+	for key, svc := range services {
+		ip := key[:strings.Index(key, "/")]
+		hosts[ip].Services = append(hosts[ip].Services, *svc)
+	}
+	result := &Result{Hosts: make([]Host, 0, len(hosts))}
+	for _, h := range hosts {
+		result.Hosts = append(result.Hosts, *h)
+	}
+	sortHosts(result.Hosts)
+	return result, nil
+}
+
+// mergeBanner adds one masscan banner record to a service.
+func mergeBanner(svc *Service, name, banner string) {
+	switch name {
+	case "ssl", "X509":
+		svc.TLS = true
+	case "title", "":
+		// The HTTP title says nothing about the service.
+	default:
+		if svc.Name == "" {
+			svc.Name = name
+		}
+	}
+	if banner == "" {
+		return
+	}
+	if svc.Banner != "" {
+		svc.Banner += "\n"
+	}
+	svc.Banner += banner
+	if svc.Product == "" {
+		svc.Product, svc.Version = productFromBanner(banner)
+	}
+}
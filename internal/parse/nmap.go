@@ -0,0 +1,97 @@
+package parse
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// nmapRun mirrors the parts of nmap's -oX output we use.
+type nmapRun struct {
+	Hosts []struct {
+		Status struct {
+			State string `xml:"state,attr"`
+		} `xml:"status"`
+		Addresses []struct {
+			Addr     string `xml:"addr,attr"`
+			AddrType string `xml:"addrtype,attr"`
+		} `xml:"address"`
+		Hostnames []struct {
+			Name string `xml:"name,attr"`
+		} `xml:"hostnames>hostname"`
+		Ports []struct {
+			Protocol string `xml:"protocol,attr"`
+			PortID   string `xml:"portid,attr"`
+			State    struct {
+				State string `xml:"state,attr"`
+			} `xml:"state"`
+			Service struct {
+				Name      string   `xml:"name,attr"`
+				Product   string   `xml:"product,attr"`
+				Version   string   `xml:"version,attr"`
+				ExtraInfo string   `xml:"extrainfo,attr"`
+				Tunnel    string   `xml:"tunnel,attr"`
+				CPEs      []string `xml:"cpe"`
+			} `xml:"service"`
+			Scripts []struct {
+				ID     string `xml:"id,attr"`
+				Output string `xml:"output,attr"`
+			} `xml:"script"`
+		} `xml:"ports>port"`
+	} `xml:"host"`
+}
+
+// NmapXML parses nmap's XML output (-oX). Hosts that are down are skipped.
+func NmapXML(data []byte) (*Result, error) {
+	var run nmapRun
+	if err := xml.Unmarshal(data, &run); err != nil {
+		return nil, fmt.Errorf("nmap xml: %w", err)
+	}
+
+	result := &Result{Hosts: []Host{}}
+	for _, h := range run.Hosts {
+		if h.Status.State == "down" {
+			continue
+		}
+		var host Host
+		for _, a := range h.Addresses {
+			if a.AddrType == "ipv4" || a.AddrType == "ipv6" {
+				host.Address = a.Addr
+				break
+			}
+		}
+		if host.Address == "" {
+			continue
+		}
+		for _, n := range h.Hostnames {
+			host.Hostnames = append(host.Hostnames, n.Name)
+		}
+		for _, p := range h.Ports {
+			port, err := strconv.Atoi(p.PortID)
+			if err != nil {
+				return nil, fmt.Errorf("nmap xml: bad port %q", p.PortID)
+			}
+			svc := Service{
+				Port:      port,
+				Protocol:  p.Protocol,
+				State:     p.State.State,
+				Name:      p.Service.Name,
+				Product:   p.Service.Product,
+				Version:   p.Service.Version,
+				ExtraInfo: p.Service.ExtraInfo,
+				CPE:       pickCPE(p.Service.CPEs),
+				TLS:       p.Service.Tunnel == "ssl" || p.Service.Name == "https" || strings.HasPrefix(p.Service.Name, "ssl"),
+			}
+			for _, s := range p.Scripts {
+				if s.ID == "banner" {
+					svc.Banner = s.Output
+				}
+			}
+			host.Services = append(host.Services, svc)
+		}
+		result.Hosts = append(result.Hosts, host)
+	}
+	sortHosts(result.Hosts)
+	return result, nil
+}
@@ -0,0 +1,111 @@
+// Package parse turns tool output into structured results that the runner
+// stores as tool_runs.parsed_json and merges into the workspace inventory
+// (assets, ports, services and credentials).
+package parse
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Result is everything a parser extracted from one run.
+type Result struct {
+	Hosts       []Host       `json:"hosts"`
+	Credentials []Credential `json:"credentials,omitempty"`
+}
+
+// Host is a scanned address and what was found on it.
+type Host struct {
+	Address   string    `json:"address"`
+	Hostnames []string  `json:"hostnames"`
+	Services  []Service `json:"services"`
+}
+
+// Service is a port and, when the tool identified it, what runs there.
+type Service struct {
+	Port      int    `json:"port"`
+	Protocol  string `json:"protocol"` // tcp, udp
+	State     string `json:"state"`    // open, filtered, ...
+	Name      string `json:"name"`     // ssh, http, ...
+	Product   string `json:"product"`  // "OpenSSH", "Apache httpd"
+	Version   string `json:"version"`
+	ExtraInfo string `json:"extraInfo"`
+	CPE       string `json:"cpe"` // application CPE when known, e.g. cpe:/a:openbsd:openssh:7.2p2
+	Banner    string `json:"banner"`
+	TLS       bool   `json:"tls"`
+}
+
+// Credential types.
+const (
+	CredentialPassword = "password"
+	CredentialHash     = "hash"
+	CredentialToken    = "token"
+)
+
+// Credential is a login a tool found or confirmed.
+type Credential struct {
+	Host      string `json:"host"` // "" = the run's target
+	Port      int    `json:"port"`
+	Service   string `json:"service"` // ssh, http-get, mysql, ...
+	Realm     string `json:"realm"`   // where it was found, e.g. the dumped table
+	Username  string `json:"username"`
+	Secret    string `json:"secret"`
+	Type      string `json:"type"`      // CredentialPassword, CredentialHash, CredentialToken
+	Validated bool   `json:"validated"` // the tool logged in with it
+}
+
+// Func parses a tool's machine-readable output.
+type Func func(data []byte) (*Result, error)
+
+// bannerProducts recognise products in raw banners, for tools that report
+// banners without identifying the service. Product names follow nmap's so
+// the inventory groups results from both.
+var bannerProducts = []struct {
+	pattern *regexp.Regexp
+	product string // "" = use the first capture group
+}{
+	{regexp.MustCompile(`^SSH-[\d.]+-OpenSSH_([\w.]+)`), "OpenSSH"},
+	{regexp.MustCompile(`(?m)^Server: Apache/([\w.]+)`), "Apache httpd"},
+	{regexp.MustCompile(`(?m)^Server: nginx/([\w.]+)`), "nginx"},
+	{regexp.MustCompile(`(?m)^Server: Microsoft-IIS/([\w.]+)`), "Microsoft IIS httpd"},
+	{regexp.MustCompile(`^220[ -].*ProFTPD ([\w.]+)`), "ProFTPD"},
+	{regexp.MustCompile(`^220 \(vsFTPd ([\w.]+)\)`), "vsftpd"},
+}
+
+// productFromBanner returns the product and version a banner announces.
+func productFromBanner(banner string) (product, version string) {
+	for _, b := range bannerProducts {
+		if m := b.pattern.FindStringSubmatch(banner); m != nil {
+			return b.product, m[1]
+		}
+	}
+	return "", ""
+}
+
+// sortHosts orders hosts by address and their services by port, so the
+// stored JSON is stable.
+func sortHosts(hosts []Host) {
+	sort.Slice(hosts, func(i, j int) bool { return hosts[i].Address < hosts[j].Address })
+	for _, h := range hosts {
+		sort.Slice(h.Services, func(i, j int) bool {
+			if h.Services[i].Port != h.Services[j].Port {
+				return h.Services[i].Port < h.Services[j].Port
+			}
+			return h.Services[i].Protocol < h.Services[j].Protocol
+		})
+	}
+}
+
+// pickCPE returns the application CPE from cpes, else the first one.
+func pickCPE(cpes []string) string {
+	for _, c := range cpes {
+		if strings.HasPrefix(c, "cpe:/a:") {
+			return c
+		}
+	}
+	if len(cpes) > 0 {
+		return cpes[0]
+	}
+	return ""
+}
@@ -0,0 +1,143 @@
+package parse
+
+import (
+	"reflect"
+	"testing"
+)
+
+const nmapSample = `<?xml version="1.0"?>
+<nmaprun scanner="nmap" args="nmap -sV -oX out.xml 10.0.0.0/30">
+<host><status state="up"/>
+<address addr="10.0.0.2" addrtype="ipv4"/><address addr="AA:BB:CC:DD:EE:FF" addrtype="mac"/>
+<hostnames><hostname name="web.acme.test" type="PTR"/></hostnames>
+<ports>
+<port protocol="tcp" portid="443"><state state="open"/>
+<service name="http" product="nginx" version="1.18.0" tunnel="ssl"><cpe>cpe:/a:igor_sysoev:nginx:1.18.0</cpe></service></port>
+<port protocol="tcp" portid="22"><state state="open"/>
+<service name="ssh" product="OpenSSH" version="7.2p2 Ubuntu 4ubuntu2.8" extrainfo="Ubuntu Linux; protocol 2.0">
+<cpe>cpe:/o:linux:linux_kernel</cpe><cpe>cpe:/a:openbsd:openssh:7.2p2</cpe></service>
+<script id="banner" output="SSH-2.0-OpenSSH_7.2p2 Ubuntu-4ubuntu2.8"/></port>
+</ports></host>
+<host><status state="down"/><address addr="10.0.0.3" addrtype="ipv4"/></host>
+</nmaprun>`
+
+func TestNmapXML(t *testing.T) {
+	got, err := NmapXML([]byte(nmapSample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Result{Hosts: []Host{{
+		Address:   "10.0.0.2",
+		Hostnames: []string{"web.acme.test"},
+		Services: []Service{
+			{Port: 22, Protocol: "tcp", State: "open", Name: "ssh", Product: "OpenSSH", Version: "7.2p2 Ubuntu 4ubuntu2.8",
+				ExtraInfo: "Ubuntu Linux; protocol 2.0", CPE: "cpe:/a:openbsd:openssh:7.2p2", Banner: "SSH-2.0-OpenSSH_7.2p2 Ubuntu-4ubuntu2.8"},
+			{Port: 443, Protocol: "tcp", State: "open", Name: "http", Product: "nginx", Version: "1.18.0",
+				CPE: "cpe:/a:igor_sysoev:nginx:1.18.0", TLS: true},
+		},
+	}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NmapXML =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+// masscanSample has the trailing comma and trailer older versions write.
+const masscanSample = `[
+{   "ip": "10.0.0.5",   "timestamp": "1700000000", "ports": [ {"port": 22, "proto": "tcp", "status": "open", "reason": "syn-ack", "ttl": 64} ] }
+,
+{   "ip": "10.0.0.5",   "timestamp": "1700000001", "ports": [ {"port": 22, "proto": "tcp", "service": {"name": "ssh", "banner": "SSH-2.0-OpenSSH_8.9p1 Ubuntu-3"} } ] }
+,
+{   "ip": "10.0.0.5",   "timestamp": "1700000002", "ports": [ {"port": 443, "proto": "tcp", "service": {"name": "X509", "banner": "MIIB"} } ] }
+,
+{finished: 1}
+]`
+
+func TestMasscanJSON(t *testing.T) {
+	got, err := MasscanJSON([]byte(masscanSample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Result{Hosts: []Host{{
+		Address: "10.0.0.5",
+		Services: []Service{
+			{Port: 22, Protocol: "tcp", State: "open", Name: "ssh", Product: "OpenSSH", Version: "8.9p1",
+				Banner: "SSH-2.0-OpenSSH_8.9p1 Ubuntu-3"},
+			{Port: 443, Protocol: "tcp", State: "open", Banner: "MIIB", TLS: true},
+		},
+	}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MasscanJSON =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestProductFromBanner(t *testing.T) {
+	for banner, want := range map[string][2]string{
+		"HTTP/1.1 200 OK\r\nServer: Apache/2.4.49 (Unix)\r\n": {"Apache httpd", "2.4.49"},
+		"220 (vsFTPd 2.3.4)": {"vsftpd", "2.3.4"},
+		"hello":              {"", ""},
+	} {
+		product, version := productFromBanner(banner)
+		if product != want[0] || version != want[1] {
+			t.Errorf("productFromBanner(%q) = %q, %q", banner, product, version)
+		}
+	}
+}
+
+const hydraSample = `Hydra v9.5 (c) 2023 by van Hauser/THC & David Maciejak
+[DATA] attacking ssh://10.0.0.5:22/
+[22][ssh] host: 10.0.0.5   login: root   password: toor
+[22][ssh] host: 10.0.0.5   login: backup   password: 
+1 of 1 target successfully completed, 2 valid passwords found`
+
+func TestHydraOutput(t *testing.T) {
+	got, err := HydraOutput([]byte(hydraSample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Credential{
+		{Host: "10.0.0.5", Port: 22, Service: "ssh", Username: "root", Secret: "toor", Type: CredentialPassword, Validated: true},
+		{Host: "10.0.0.5", Port: 22, Service: "ssh", Username: "backup", Secret: "", Type: CredentialPassword, Validated: true},
+	}
+	if !reflect.DeepEqual(got.Credentials, want) {
+		t.Errorf("HydraOutput =\n%+v\nwant\n%+v", got.Credentials, want)
+	}
+}
+
+const sqlmapSample = `[12:00:01] [INFO] the back-end DBMS is MySQL
+back-end DBMS: MySQL >= 5.0.12
+[12:00:02] [INFO] fetching database users password hashes
+database management system users password hashes:
+[*] root [1]:
+    password hash: *81F5E21E35407D884A6CD4A731AEBFB6AF209E1B
+        clear-text password: testpass
+[*] guest [1]:
+    password hash: NULL
+
+Database: shop
+Table: users
+[3 entries]
++----+-------+----------------------------------------------+
+| id | login | password                                     |
++----+-------+----------------------------------------------+
+| 1  | admin | 5f4dcc3b5aa765d61d8327deb882cf99 (password)  |
+| 2  | alice | Summer2024!                                  |
+| 3  | bob   | NULL                                         |
++----+-------+----------------------------------------------+
+`
+
+func TestSqlmapOutput(t *testing.T) {
+	got, err := SqlmapOutput([]byte(sqlmapSample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Credential{
+		{Service: "mysql", Realm: "dbms", Username: "root", Secret: "*81F5E21E35407D884A6CD4A731AEBFB6AF209E1B", Type: CredentialHash},
+		{Service: "mysql", Realm: "dbms", Username: "root", Secret: "testpass", Type: CredentialPassword},
+		{Service: "mysql", Realm: "shop.users", Username: "admin", Secret: "5f4dcc3b5aa765d61d8327deb882cf99", Type: CredentialHash},
+		{Service: "mysql", Realm: "shop.users", Username: "admin", Secret: "password", Type: CredentialPassword},
+		{Service: "mysql", Realm: "shop.users", Username: "alice", Secret: "Summer2024!", Type: CredentialPassword},
+	}
+	if !reflect.DeepEqual(got.Credentials, want) {
+		t.Errorf("SqlmapOutput =\n%+v\nwant\n%+v", got.Credentials, want)
+	}
+}
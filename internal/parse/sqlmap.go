@@ -0,0 +1,134 @@
+package parse
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+var (
+	sqlmapDBMS      = regexp.MustCompile(`^back-end DBMS: (\w+)`)
+	sqlmapTable     = regexp.MustCompile(`^Table: (\S+)`)
+	sqlmapDatabase  = regexp.MustCompile(`^Database: (\S+)`)
+	sqlmapUser      = regexp.MustCompile(`^\[\*\] '?([^'\s]+)'?(?:@'?[^'\s]*'?)? \[\d+\]:`)
+	sqlmapHashLine  = regexp.MustCompile(`^\s+password hash: (\S+)(?:\s+\(([^)]*)\))?`)
+	sqlmapClearLine = regexp.MustCompile(`^\s+clear-text password: (.*)$`)
+	sqlmapCracked   = regexp.MustCompile(`^(\S+) \((.*)\)$`) // "5f4d... (password)"
+
+	// hashLike recognises hex digests (optionally MySQL's leading '*') and
+	// crypt-style hashes ("$2y$10$...").
+	hashLike = regexp.MustCompile(`^(\*?[0-9A-Fa-f]{32,128}|\$[\w-]+\$\S+)$`)
+)
+
+// Column names sqlmap dumps that hold logins and secrets.
+var (
+	userColumns   = []string{"username", "user_name", "user", "login", "email", "name", "uname"}
+	secretColumns = []string{"password", "passwd", "pass", "pwd", "password_hash", "hash", "token", "api_key", "apikey", "secret"}
+)
+
+// SqlmapOutput parses credentials from sqlmap's stdout: DBMS account
+// hashes (--passwords) and rows of dumped tables (--dump) that have a user
+// and a password-like column. Cracked hashes yield both the hash and the
+// password. Nothing is validated; the host is the run's target.
+func SqlmapOutput(data []byte) (*Result, error) {
+	result := &Result{Hosts: []Host{}}
+	seen := make(map[Credential]bool)
+	add := func(c Credential) {
+		if c.Secret == "" || c.Secret == "NULL" || c.Secret == "<blank>" || seen[c] {
+			return
+		}
+		seen[c] = true
+		result.Credentials = append(result.Credentials, c)
+	}
+
+	var dbms, database, table, dbUser string
+	var header []string
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), "\r")
+		switch {
+		case sqlmapDBMS.MatchString(line):
+			dbms = strings.ToLower(sqlmapDBMS.FindStringSubmatch(line)[1])
+		case sqlmapDatabase.MatchString(line):
+			database, table, header = sqlmapDatabase.FindStringSubmatch(line)[1], "", nil
+		case sqlmapTable.MatchString(line):
+			table, header = sqlmapTable.FindStringSubmatch(line)[1], nil
+		case sqlmapUser.MatchString(line):
+			dbUser = sqlmapUser.FindStringSubmatch(line)[1]
+		case dbUser != "" && sqlmapHashLine.MatchString(line):
+			m := sqlmapHashLine.FindStringSubmatch(line)
+			add(Credential{Service: dbms, Realm: "dbms", Username: dbUser, Secret: m[1], Type: CredentialHash})
+			if m[2] != "" {
+				add(Credential{Service: dbms, Realm: "dbms", Username: dbUser, Secret: m[2], Type: CredentialPassword})
+			}
+		case dbUser != "" && sqlmapClearLine.MatchString(line):
+			add(Credential{Service: dbms, Realm: "dbms", Username: dbUser,
+				Secret: sqlmapClearLine.FindStringSubmatch(line)[1], Type: CredentialPassword})
+		case table != "" && strings.HasPrefix(line, "|"):
+			cells := tableCells(line)
+			if header == nil {
+				header = cells
+				continue
+			}
+			userCol, secretCol := columnIndex(header, userColumns), columnIndex(header, secretColumns)
+			if userCol < 0 || secretCol < 0 || len(cells) != len(header) {
+				continue
+			}
+			realm := table
+			if database != "" {
+				realm = database + "." + table
+			}
+			c := Credential{Service: dbms, Realm: realm, Username: cells[userCol]}
+			secret := cells[secretCol]
+			if m := sqlmapCracked.FindStringSubmatch(secret); m != nil && hashLike.MatchString(m[1]) {
+				add(withSecret(c, m[1], CredentialHash))
+				add(withSecret(c, m[2], CredentialPassword))
+				continue
+			}
+			add(withSecret(c, secret, secretType(header[secretCol], secret)))
+		case !strings.HasPrefix(line, " "):
+			dbUser = "" // account details are indented under their "[*] user" line
+		}
+	}
+	return result, sc.Err()
+}
+
+func withSecret(c Credential, secret, kind string) Credential {
+	c.Secret, c.Type = secret, kind
+	return c
+}
+
+// secretType classifies a dumped value by its column and shape.
+func secretType(column, value string) string {
+	switch {
+	case hashLike.MatchString(value):
+		return CredentialHash
+	case strings.Contains(column, "token") || strings.Contains(column, "key") || column == "secret":
+		return CredentialToken
+	}
+	return CredentialPassword
+}
+
+// tableCells splits an ASCII table row ("| 1  | admin |") into trimmed cells.
+func tableCells(line string) []string {
+	parts := strings.Split(strings.Trim(line, "|"), "|")
+	for i, p := range parts {
+		parts[i] = strings.TrimSpace(p)
+	}
+	return parts
+}
+
+// columnIndex returns the first header column whose lowercased name is in
+// names, in the order names lists them, or -1.
+func columnIndex(header, names []string) int {
+	for _, name := range names {
+		for i, h := range header {
+			if strings.ToLower(h) == name {
+				return i
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,251 @@
+// Package secrets stores API keys and other credentials encrypted in the
+// database. Values are sealed with AES-256-GCM under a random key kept in a
+// 0600 file outside it (~/.nser/secrets.key, shared by every database), so
+// a copied nser.db alone doesn't reveal them.
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+)
+
+// keySize is the AES-256 key length in bytes.
+const keySize = 32
+
+// namePattern restricts secret names to what fits in {{secret:name}}.
+var namePattern = regexp.MustCompile(`^[a-z0-9_.-]+$`)
+
+// Info describes a stored secret without its value.
+type Info struct {
+	Name      string `json:"name"`
+	UpdatedAt string `json:"updatedAt"`
+}
+
+// Store reads and writes the secrets table.
+type Store struct {
+	db   *sql.DB
+	aead cipher.AEAD
+}
+
+// Open returns a store for db, loading the key from keyPath or creating it
+// on first use.
+func Open(db *sql.DB, keyPath string) (*Store, error) {
+	key, err := LoadKey(keyPath, keySize, "secrets key")
+	if err != nil {
+		return nil, err
+	}
+	return newStore(db, key)
+}
+
+func newStore(db *sql.DB, key []byte) (*Store, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("secrets cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("secrets cipher: %w", err)
+	}
+	return &Store{db: db, aead: aead}, nil
+}
+
+// LoadKey reads a random key of size bytes from path, creating the file
+// (mode 0600) on first use. label names the key in errors ("secrets key").
+// The audit log's signing seed is kept the same way.
+func LoadKey(path string, size int, label string) ([]byte, error) {
+	key, err := os.ReadFile(path)
+	if err == nil {
+		if len(key) != size {
+			return nil, fmt.Errorf("%s %s: want %d bytes, got %d", label, path, size, len(key))
+		}
+		return key, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("read %s: %w", label, err)
+	}
+
+	key = make([]byte, size)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("generate %s: %w", label, err)
+	}
+	// O_EXCL: never overwrite a key another process just created.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("create %s: %w", label, err)
+	}
+	_, err = f.Write(key)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		// A short key file would be refused on every later start.
+		os.Remove(path) //nolint:errcheck
+		return nil, fmt.Errorf("write %s: %w", label, err)
+	}
+	return key, nil
+}
+
+// ValidName reports whether name can be used for a secret.
+func ValidName(name string) bool {
+	return namePattern.MatchString(name)
+}
+
+// Set encrypts and stores value under name, replacing any previous value.
+func (s *Store) Set(ctx context.Context, name, value string) error {
+	if !ValidName(name) {
+		return fmt.Errorf("invalid secret name %q: use lowercase letters, digits, '_', '.' or '-'", name)
+	}
+	if value == "" {
+		return fmt.Errorf("secret %q has no value", name)
+	}
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("generate nonce: %w", err)
+	}
+	// The name is authenticated so ciphertexts can't be swapped between rows.
+	ciphertext := s.aead.Seal(nil, nonce, []byte(value), []byte(name))
+
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO secrets (name, nonce, ciphertext, updated_at) VALUES (?, ?, ?, ?)
+		 ON CONFLICT(name) DO UPDATE SET nonce = excluded.nonce, ciphertext = excluded.ciphertext, updated_at = excluded.updated_at`,
+		name, nonce, ciphertext, time.Now(),
+	)
+	if err != nil {
+		return fmt.Errorf("store secret %q: %w", name, err)
+	}
+	return nil
+}
+
+// Lookup returns the value stored under name; ok is false if there is none.
+func (s *Store) Lookup(ctx context.Context, name string) (string, bool, error) {
+	var nonce, ciphertext []byte
+	err := s.db.QueryRowContext(ctx,
+		`SELECT nonce, ciphertext FROM secrets WHERE name = ?`, name,
+	).Scan(&nonce, &ciphertext)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("load secret %q: %w", name, err)
+	}
+	value, err := s.open(name, nonce, ciphertext)
+	if err != nil {
+		return "", false, err
+	}
+	return value, true, nil
+}
+
+// Values returns every stored value, for masking tool output.
+func (s *Store) Values(ctx context.Context) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT name, nonce, ciphertext FROM secrets`)
+	if err != nil {
+		return nil, fmt.Errorf("load secrets: %w", err)
+	}
+	defer rows.Close()
+
+	var values []string
+	for rows.Next() {
+		var name string
+		var nonce, ciphertext []byte
+		if err := rows.Scan(&name, &nonce, &ciphertext); err != nil {
+			return nil, fmt.Errorf("scan secret: %w", err)
+		}
+		value, err := s.open(name, nonce, ciphertext)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
+
+// List returns the stored secret names, never their values.
+func (s *Store) List(ctx context.Context) ([]Info, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT name, updated_at FROM secrets ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("list secrets: %w", err)
+	}
+	defer rows.Close()
+
+	var result []Info
+	for rows.Next() {
+		var info Info
+		if err := rows.Scan(&info.Name, &info.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan secret: %w", err)
+		}
+		result = append(result, info)
+	}
+	return result, rows.Err()
+}
+
+// Delete removes a secret.
+func (s *Store) Delete(ctx context.Context, name string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM secrets WHERE name = ?`, name); err != nil {
+		return fmt.Errorf("delete secret %q: %w", name, err)
+	}
+	return nil
+}
+
+// DeleteTx removes a secret in tx and reports whether it existed.
+func (s *Store) DeleteTx(ctx context.Context, tx *sql.Tx, name string) (bool, error) {
+	res, err := tx.ExecContext(ctx, `DELETE FROM secrets WHERE name = ?`, name)
+	if err != nil {
+		return false, fmt.Errorf("delete secret %q: %w", name, err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// sealedPrefix marks values sealed with Seal. Values without it were
+// stored before sealing and are returned as is.
+var sealedPrefix = []byte("nser:key:v1:")
+
+// Seal encrypts a value kept outside the secrets table, such as the
+// password values of a stored run, under the same key. label is
+// authenticated, so a value can't be moved to a column with another label.
+func (s *Store) Seal(label string, plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generate nonce: %w", err)
+	}
+	out := append(append([]byte{}, sealedPrefix...), nonce...)
+	return s.aead.Seal(out, nonce, plaintext, []byte(label)), nil
+}
+
+// Open decrypts a value sealed with Seal under the same label.
+func (s *Store) Open(label string, data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, sealedPrefix) {
+		return data, nil
+	}
+	body := data[len(sealedPrefix):]
+	if len(body) < s.aead.NonceSize() {
+		return nil, fmt.Errorf("%s: sealed value is truncated", label)
+	}
+	nonce, ciphertext := body[:s.aead.NonceSize()], body[s.aead.NonceSize():]
+	plaintext, err := s.aead.Open(nil, nonce, ciphertext, []byte(label))
+	if err != nil {
+		return nil, fmt.Errorf("decrypt %s: wrong key or corrupted value", label)
+	}
+	return plaintext, nil
+}
+
+// open decrypts one stored value.
+func (s *Store) open(name string, nonce, ciphertext []byte) (string, error) {
+	if len(nonce) != s.aead.NonceSize() {
+		return "", fmt.Errorf("secret %q: bad nonce", name)
+	}
+	plaintext, err := s.aead.Open(nil, nonce, ciphertext, []byte(name))
+	if err != nil {
+		return "", fmt.Errorf("decrypt secret %q: wrong key or corrupted value", name)
+	}
+	return string(plaintext), nil
+}
@@ -0,0 +1,101 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "modernc.org/sqlite"
+)
+
+func openTestStore(t *testing.T, keyPath string) (*Store, *sql.DB) {
+	t.Helper()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.Exec(`CREATE TABLE secrets (name TEXT PRIMARY KEY, nonce BLOB NOT NULL,
+		ciphertext BLOB NOT NULL, updated_at DATETIME NOT NULL)`); err != nil {
+		t.Fatal(err)
+	}
+	s, err := Open(db, keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, db
+}
+
+func TestStoreRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	keyPath := filepath.Join(t.TempDir(), "secrets.key")
+	s, db := openTestStore(t, keyPath)
+
+	if err := s.Set(ctx, "pdcp_api_key", "hunter2-key"); err != nil {
+		t.Fatal(err)
+	}
+	got, ok, err := s.Lookup(ctx, "pdcp_api_key")
+	if err != nil || !ok || got != "hunter2-key" {
+		t.Fatalf("Lookup = %q, %v, %v", got, ok, err)
+	}
+	if _, ok, _ := s.Lookup(ctx, "missing"); ok {
+		t.Error("Lookup(missing) found a value")
+	}
+
+	var stored []byte
+	db.QueryRow(`SELECT ciphertext FROM secrets`).Scan(&stored)
+	if string(stored) == "hunter2-key" {
+		t.Error("value stored in plaintext")
+	}
+
+	if fi, err := os.Stat(keyPath); err != nil || fi.Mode().Perm() != 0o600 {
+		t.Errorf("key file mode = %v, %v", fi.Mode(), err)
+	}
+
+	// A different key can't read the value.
+	other, err := newStore(db, make([]byte, keySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := other.Lookup(ctx, "pdcp_api_key"); err == nil {
+		t.Error("Lookup with the wrong key succeeded")
+	}
+
+	if err := s.Set(ctx, "Bad Name", "x"); err == nil {
+		t.Error("Set accepted an invalid name")
+	}
+}
+
+func TestSeal(t *testing.T) {
+	s, _ := openTestStore(t, filepath.Join(t.TempDir(), "secrets.key"))
+	sealed, err := s.Seal("runs", []byte(`{"password":"hunter2"}`))
+	if err != nil || bytes.Contains(sealed, []byte("hunter2")) {
+		t.Fatalf("Seal = %q, %v", sealed, err)
+	}
+	if got, err := s.Open("runs", sealed); err != nil || string(got) != `{"password":"hunter2"}` {
+		t.Errorf("Open = %q, %v", got, err)
+	}
+	if _, err := s.Open("presets", sealed); err == nil {
+		t.Error("Open under another label succeeded")
+	}
+	if got, err := s.Open("runs", []byte("{}")); err != nil || string(got) != "{}" {
+		t.Errorf("Open of an unsealed value = %q, %v", got, err)
+	}
+}
+
+func TestLoadKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.key")
+	key, err := LoadKey(path, 16, "test key")
+	if err != nil || len(key) != 16 {
+		t.Fatalf("LoadKey = %d bytes, %v", len(key), err)
+	}
+	if again, err := LoadKey(path, 16, "test key"); err != nil || !bytes.Equal(again, key) {
+		t.Errorf("reloaded key differs: %v", err)
+	}
+	if _, err := LoadKey(path, 32, "test key"); err == nil {
+		t.Error("LoadKey accepted a key of the wrong size")
+	}
+}
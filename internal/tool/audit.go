@@ -0,0 +1,108 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"nser/internal/audit"
+)
+
+// Auditor records actions in the audit log. Implemented by audit.Log.
+type Auditor interface {
+	Record(ctx context.Context, workspaceID int64, action string, detail any) error
+}
+
+// SetAuditor sets where runs are recorded. Without one nothing is.
+func (r *Runner) SetAuditor(a Auditor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.auditor = a
+}
+
+// record appends to the audit log, if there is one.
+func (r *Runner) record(ctx context.Context, workspaceID int64, action string, detail any) error {
+	r.mu.RLock()
+	a := r.auditor
+	r.mu.RUnlock()
+	if a == nil {
+		return nil
+	}
+	return a.Record(ctx, workspaceID, action, detail)
+}
+
+// runStart is the audit detail of a started run: the argv actually
+// executed, wrapper included, and how it was elevated and routed.
+type runStart struct {
+	RunID     int64           `json:"runId"`
+	Tool      string          `json:"tool"`
+	Target    string          `json:"target"`
+	Argv      []string        `json:"argv"`
+	Elevation string          `json:"elevation,omitempty"`
+	Network   json.RawMessage `json:"network,omitempty"`
+	Warning   string          `json:"warning,omitempty"`
+}
+
+// auditArgv returns p's argv as recorded: secret values masked, and
+// password options and proxy passwords redacted. The log can't be edited
+// afterwards, so nothing that must stay secret may reach it.
+func auditArgv(p preparedRun) []string {
+	argv := redactArgv(p.def, append([]string{p.path}, p.args...))
+	for i, a := range argv {
+		argv[i] = redactURLPassword(p.mask(a))
+	}
+	return argv
+}
+
+// recordStart records a run that is about to execute, and whether it
+// started outside the test window. The network profile is the one stored
+// on the run, with its passwords already redacted.
+func (r *Runner) recordStart(ctx context.Context, req RunRequest, runID int64, p preparedRun) error {
+	d := runStart{
+		RunID:     runID,
+		Tool:      req.ToolName,
+		Target:    p.mask(req.Target),
+		Argv:      auditArgv(p),
+		Elevation: p.elevation,
+		Warning:   p.warning,
+	}
+	if p.networkJSON.Valid {
+		d.Network = json.RawMessage(p.networkJSON.String)
+	}
+	if err := r.record(ctx, req.WorkspaceID, audit.ActionRunStart, d); err != nil {
+		return err
+	}
+	if p.warning != "" {
+		return r.record(ctx, req.WorkspaceID, audit.ActionRunOutsideWindow, map[string]any{"runId": runID, "warning": p.warning})
+	}
+	return nil
+}
+
+// recordRefused records a run refused before it started. Best effort: the
+// caller is already returning an error.
+func (r *Runner) recordRefused(ctx context.Context, req RunRequest, reason string) {
+	r.record(ctx, req.WorkspaceID, audit.ActionRunRefused, map[string]string{ //nolint:errcheck
+		"tool": req.ToolName, "target": req.Target, "reason": reason,
+	})
+}
+
+// recordStop records how a run ended. A failure is returned as a warning
+// for the result rather than an error, since the run itself is over.
+func (r *Runner) recordStop(ctx context.Context, workspaceID, runID int64, status string, exitCode int) string {
+	err := r.record(ctx, workspaceID, audit.ActionRunStop, map[string]any{"runId": runID, "status": status, "exitCode": exitCode})
+	if err != nil {
+		return "audit log: " + err.Error()
+	}
+	return ""
+}
+
+// joinWarnings joins the non-empty warnings with "; ".
+func joinWarnings(warnings ...string) string {
+	var out []string
+	for _, w := range warnings {
+		if w != "" {
+			out = append(out, w)
+		}
+	}
+	return strings.Join(out, "; ")
+}
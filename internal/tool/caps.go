@@ -0,0 +1,84 @@
+package tool
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// capNames maps Linux capability numbers to the names getcap/setcap use.
+var capNames = [...]string{
+	"cap_chown", "cap_dac_override", "cap_dac_read_search", "cap_fowner",
+	"cap_fsetid", "cap_kill", "cap_setgid", "cap_setuid", "cap_setpcap",
+	"cap_linux_immutable", "cap_net_bind_service", "cap_net_broadcast",
+	"cap_net_admin", "cap_net_raw", "cap_ipc_lock", "cap_ipc_owner",
+	"cap_sys_module", "cap_sys_rawio", "cap_sys_chroot", "cap_sys_ptrace",
+	"cap_sys_pacct", "cap_sys_admin", "cap_sys_boot", "cap_sys_nice",
+	"cap_sys_resource", "cap_sys_time", "cap_sys_tty_config", "cap_mknod",
+	"cap_lease", "cap_audit_write", "cap_audit_control", "cap_setfcap",
+	"cap_mac_override", "cap_mac_admin", "cap_syslog", "cap_wake_alarm",
+	"cap_block_suspend", "cap_audit_read", "cap_perfmon", "cap_bpf",
+	"cap_checkpoint_restore",
+}
+
+// defaultRootCaps is what a NeedsRoot tool is assumed to need when its
+// definition doesn't list Capabilities: raw sockets.
+var defaultRootCaps = []string{"cap_net_raw"}
+
+// capsFromMask returns the names of the capabilities set in mask (never
+// nil). Unknown bits are reported as "cap_<n>".
+func capsFromMask(mask uint64) []string {
+	caps := []string{}
+	for i := 0; i < 64; i++ {
+		if mask&(1<<i) == 0 {
+			continue
+		}
+		if i < len(capNames) {
+			caps = append(caps, capNames[i])
+		} else {
+			caps = append(caps, "cap_"+strconv.Itoa(i))
+		}
+	}
+	return caps
+}
+
+// rootCaps returns the capabilities that let def run without root.
+func rootCaps(def ToolDef) []string {
+	if len(def.Capabilities) > 0 {
+		return def.Capabilities
+	}
+	return defaultRootCaps
+}
+
+// hasCaps reports whether have includes every capability in want.
+func hasCaps(have, want []string) bool {
+	for _, c := range want {
+		if !slices.Contains(have, c) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseStatusCaps extracts the effective (CapEff) and ambient (CapAmb)
+// capability sets from /proc/<pid>/status. Ambient capabilities are the
+// ones processes started by nser inherit.
+func parseStatusCaps(status string) (effective, ambient []string) {
+	for _, line := range strings.Split(status, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		mask, err := strconv.ParseUint(strings.TrimSpace(value), 16, 64)
+		if err != nil {
+			continue
+		}
+		switch key {
+		case "CapEff":
+			effective = capsFromMask(mask)
+		case "CapAmb":
+			ambient = capsFromMask(mask)
+		}
+	}
+	return effective, ambient
+}
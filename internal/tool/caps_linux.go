@@ -0,0 +1,76 @@
+//go:build linux
+
+package tool
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// VFS capability header versions (linux/capability.h).
+const (
+	vfsCapRevisionMask   = 0xFF000000
+	vfsCapRevision1      = 0x01000000
+	vfsCapRevision2      = 0x02000000
+	vfsCapRevision3      = 0x03000000
+	vfsCapFlagsEffective = 0x000001
+)
+
+// FileCapabilities returns the permitted file capabilities set on a binary
+// (setcap cap_net_raw+ep). Capabilities without the effective bit are not
+// reported, since the tool would have to raise them itself. A binary with
+// no capabilities returns nil, nil.
+func FileCapabilities(path string) ([]string, error) {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	buf := make([]byte, 32)
+	n, err := syscall.Getxattr(path, "security.capability", buf)
+	if err != nil {
+		if errors.Is(err, syscall.ENODATA) || errors.Is(err, syscall.ENOTSUP) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read capabilities of %s: %w", path, err)
+	}
+	buf = buf[:n]
+	if len(buf) < 4 {
+		return nil, fmt.Errorf("capabilities of %s: short xattr", path)
+	}
+
+	magic := binary.LittleEndian.Uint32(buf)
+	if magic&vfsCapFlagsEffective == 0 {
+		return nil, nil
+	}
+	var permitted uint64
+	switch magic & vfsCapRevisionMask {
+	case vfsCapRevision1:
+		if len(buf) < 12 {
+			return nil, fmt.Errorf("capabilities of %s: short v1 xattr", path)
+		}
+		permitted = uint64(binary.LittleEndian.Uint32(buf[4:]))
+	case vfsCapRevision2, vfsCapRevision3:
+		if len(buf) < 20 {
+			return nil, fmt.Errorf("capabilities of %s: short xattr", path)
+		}
+		permitted = uint64(binary.LittleEndian.Uint32(buf[4:])) |
+			uint64(binary.LittleEndian.Uint32(buf[12:]))<<32
+	default:
+		return nil, fmt.Errorf("capabilities of %s: unknown revision %#x", path, magic&vfsCapRevisionMask)
+	}
+	return capsFromMask(permitted), nil
+}
+
+// processCapabilities returns the effective and ambient capability sets of
+// the nser process.
+func processCapabilities() (effective, ambient []string, err error) {
+	data, err := os.ReadFile("/proc/self/status")
+	if err != nil {
+		return nil, nil, fmt.Errorf("read process capabilities: %w", err)
+	}
+	effective, ambient = parseStatusCaps(string(data))
+	return effective, ambient, nil
+}
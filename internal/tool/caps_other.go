@@ -0,0 +1,13 @@
+//go:build !linux
+
+package tool
+
+// FileCapabilities always returns nil: file capabilities are Linux-only.
+func FileCapabilities(path string) ([]string, error) {
+	return nil, nil
+}
+
+// processCapabilities always returns nil: capability sets are Linux-only.
+func processCapabilities() (effective, ambient []string, err error) {
+	return nil, nil, nil
+}
@@ -0,0 +1,106 @@
+package tool
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Credential sets an OptionCredentials value can name besides a service.
+const (
+	CredentialsAll       = "all"
+	CredentialsValidated = "validated"
+)
+
+// comboFiles writes the credential sets chosen by def's OptionCredentials
+// options to temp files and returns req.Options with each value replaced
+// by its file's path. remove deletes the files; call it once the run ends.
+func (r *Runner) comboFiles(ctx context.Context, def ToolDef, req RunRequest) (options map[string]string, remove func(), err error) {
+	remove = func() {}
+	var files []string
+	for _, o := range def.Options {
+		set := req.Options[o.Name]
+		if o.Type != OptionCredentials || set == "" {
+			continue
+		}
+		if options == nil {
+			options = make(map[string]string, len(req.Options))
+			for k, v := range req.Options {
+				options[k] = v
+			}
+		}
+		path, err := r.writeCombo(ctx, req.WorkspaceID, set)
+		if err != nil {
+			for _, f := range files {
+				os.Remove(f)
+			}
+			return nil, remove, fmt.Errorf("option %q: %w", o.Name, err)
+		}
+		files = append(files, path)
+		options[o.Name] = path
+	}
+	if options == nil {
+		return req.Options, remove, nil
+	}
+	return options, func() {
+		for _, f := range files {
+			os.Remove(f)
+		}
+	}, nil
+}
+
+// writeCombo writes a workspace's passwords in set (see OptionCredentials)
+// to a new temp file, one login:password per line.
+func (r *Runner) writeCombo(ctx context.Context, workspaceID int64, set string) (string, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT username, secret FROM credentials
+		 WHERE workspace_id = ? AND type = 'password'
+		   AND (? = 'all' OR (? = 'validated' AND validated = 1) OR service = ?)
+		 ORDER BY validated DESC, id`,
+		workspaceID, set, set, set,
+	)
+	if err != nil {
+		return "", fmt.Errorf("load credentials: %w", err)
+	}
+	defer rows.Close()
+
+	var b strings.Builder
+	seen := make(map[string]bool)
+	for rows.Next() {
+		var user string
+		var secret []byte
+		if err := rows.Scan(&user, &secret); err != nil {
+			return "", fmt.Errorf("scan credential: %w", err)
+		}
+		if secret, err = r.open(secret); err != nil {
+			return "", fmt.Errorf("open credential: %w", err)
+		}
+		line := user + ":" + string(secret)
+		if strings.ContainsAny(line, "\r\n") || seen[line] {
+			continue
+		}
+		seen[line] = true
+		b.WriteString(line + "\n")
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	if len(seen) == 0 {
+		return "", fmt.Errorf("no stored passwords match %q", set)
+	}
+
+	f, err := os.CreateTemp("", "nser-combo-*.txt") // 0600
+	if err != nil {
+		return "", fmt.Errorf("create combo file: %w", err)
+	}
+	_, werr := f.WriteString(b.String())
+	if err := f.Close(); werr == nil {
+		werr = err
+	}
+	if werr != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("write combo file: %w", werr)
+	}
+	return f.Name(), nil
+}
@@ -0,0 +1,170 @@
+package tool
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ToolSpec is the file and API representation of a user-defined tool.
+// It mirrors ToolDef with explicit field names so YAML/JSON files stay
+// stable even if ToolDef grows.
+//
+//	name: feroxbuster
+//	category: scanning
+//	binary: feroxbuster
+//	argTemplate: ["-u", "{{url}}", "-w", "{{wordlist}}", "{{args}}"]
+//	defaultWordlist: /usr/share/wordlists/dirb/common.txt
+type ToolSpec struct {
+	Name            string            `json:"name" yaml:"name"`
+	Category        string            `json:"category" yaml:"category"`
+	Binary          string            `json:"binary" yaml:"binary"`
+	Description     string            `json:"description" yaml:"description"`
+	DefaultArgs     []string          `json:"defaultArgs" yaml:"defaultArgs"`
+	ArgTemplate     []string          `json:"argTemplate" yaml:"argTemplate"`
+	DefaultWordlist string            `json:"defaultWordlist" yaml:"defaultWordlist"`
+	NeedsRoot       bool              `json:"needsRoot" yaml:"needsRoot"`
+	Capabilities    []string          `json:"capabilities" yaml:"capabilities"`
+	CapabilityArgs  []string          `json:"capabilityArgs" yaml:"capabilityArgs"`
+	VersionFlag     string            `json:"versionFlag" yaml:"versionFlag"`
+	VersionRegex    string            `json:"versionRegex" yaml:"versionRegex"`
+	MinVersion      string            `json:"minVersion" yaml:"minVersion"`
+	MaxVersion      string            `json:"maxVersion" yaml:"maxVersion"`
+	InstallHint     map[string]string `json:"installHint" yaml:"installHint"`
+	ProxyFlag       string            `json:"proxyFlag" yaml:"proxyFlag"`
+	Env             map[string]string `json:"env" yaml:"env"`
+	Options         []OptionSpec      `json:"options" yaml:"options"`
+}
+
+// OptionSpec is the file and API representation of an Option.
+type OptionSpec struct {
+	Name      string   `json:"name" yaml:"name"`
+	Flag      string   `json:"flag" yaml:"flag"`
+	Type      string   `json:"type" yaml:"type"`
+	Default   string   `json:"default" yaml:"default"`
+	Help      string   `json:"help" yaml:"help"`
+	Choices   []string `json:"choices" yaml:"choices"`
+	Conflicts []string `json:"conflicts" yaml:"conflicts"`
+	NeedsRoot bool     `json:"needsRoot" yaml:"needsRoot"`
+}
+
+// ToDef converts a spec into a validated ToolDef tagged with source.
+func (s ToolSpec) ToDef(source string) (ToolDef, error) {
+	def := ToolDef{
+		Name:            s.Name,
+		Category:        Category(s.Category),
+		Binary:          s.Binary,
+		Description:     s.Description,
+		DefaultArgs:     s.DefaultArgs,
+		ArgTemplate:     s.ArgTemplate,
+		DefaultWordlist: s.DefaultWordlist,
+		NeedsRoot:       s.NeedsRoot,
+		Capabilities:    s.Capabilities,
+		CapabilityArgs:  s.CapabilityArgs,
+		VersionFlag:     s.VersionFlag,
+		VersionRegex:    s.VersionRegex,
+		MinVersion:      s.MinVersion,
+		MaxVersion:      s.MaxVersion,
+		InstallHint:     s.InstallHint,
+		ProxyFlag:       s.ProxyFlag,
+		Env:             s.Env,
+		Source:          source,
+	}
+	for _, o := range s.Options {
+		def.Options = append(def.Options, Option{
+			Name:      o.Name,
+			Flag:      o.Flag,
+			Type:      OptionType(o.Type),
+			Default:   o.Default,
+			Help:      o.Help,
+			Choices:   o.Choices,
+			Conflicts: o.Conflicts,
+			NeedsRoot: o.NeedsRoot,
+		})
+	}
+	if err := def.Validate(); err != nil {
+		return ToolDef{}, err
+	}
+	return def, nil
+}
+
+// ParseToolSpec decodes a spec from YAML or JSON, chosen by file extension.
+// Unknown fields are rejected so typos don't silently drop settings.
+func ParseToolSpec(data []byte, ext string) (ToolSpec, error) {
+	var spec ToolSpec
+	switch strings.ToLower(ext) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(&spec); err != nil {
+			return ToolSpec{}, fmt.Errorf("parse yaml: %w", err)
+		}
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&spec); err != nil {
+			return ToolSpec{}, fmt.Errorf("parse json: %w", err)
+		}
+	default:
+		return ToolSpec{}, fmt.Errorf("unsupported tool file extension %q", ext)
+	}
+	return spec, nil
+}
+
+// LoadToolDir registers every *.yaml, *.yml and *.json tool spec in dir.
+// A missing directory is not an error. Files that fail to parse, validate or
+// register are recorded with ReportProblem and skipped. Returns the number of
+// tools registered.
+func (r *Registry) LoadToolDir(dir string) int {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			r.ReportProblem("", dir, err)
+		}
+		return 0
+	}
+
+	var files []string
+	for _, e := range entries {
+		switch strings.ToLower(filepath.Ext(e.Name())) {
+		case ".yaml", ".yml", ".json":
+			if !e.IsDir() {
+				files = append(files, filepath.Join(dir, e.Name()))
+			}
+		}
+	}
+	sort.Strings(files)
+
+	loaded := 0
+	for _, path := range files {
+		if err := r.loadToolFile(path); err != nil {
+			r.ReportProblem(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), path, err)
+			continue
+		}
+		loaded++
+	}
+	return loaded
+}
+
+// loadToolFile parses and registers a single tool spec file.
+func (r *Registry) loadToolFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	spec, err := ParseToolSpec(data, filepath.Ext(path))
+	if err != nil {
+		return err
+	}
+	def, err := spec.ToDef(path)
+	if err != nil {
+		return err
+	}
+	return r.TryRegister(def)
+}
@@ -1,6 +1,9 @@
 package defs
 
-import "nser/internal/tool"
+import (
+	"nser/internal/parse"
+	"nser/internal/tool"
+)
 
 func init() {
 	r := tool.DefaultRegistry
@@ -18,6 +21,18 @@ func init() {
 			"windows": "pip install sqlmap",
 		},
 		VersionFlag: "--version",
+		ProxyFlag:   "--proxy=",
+		ArgTemplate: []string{"-u", "{{url}}", "{{args}}"},
+		Options: []tool.Option{
+			{Name: "level", Flag: "--level=", Type: tool.OptionEnum, Choices: []string{"1", "2", "3", "4", "5"}, Help: "Level of tests to perform"},
+			{Name: "risk", Flag: "--risk=", Type: tool.OptionEnum, Choices: []string{"1", "2", "3"}, Help: "Risk of tests to perform"},
+			{Name: "dbs", Flag: "--dbs", Type: tool.OptionBool, Help: "Enumerate databases"},
+			{Name: "tables", Flag: "--tables", Type: tool.OptionBool, Help: "Enumerate tables"},
+			{Name: "dump", Flag: "--dump", Type: tool.OptionBool, Help: "Dump table entries"},
+			{Name: "passwords", Flag: "--passwords", Type: tool.OptionBool, Help: "Enumerate DBMS users' password hashes"},
+		},
+		// Credentials from --dump and --passwords go to the credential store.
+		Parser: &tool.Parser{Parse: parse.SqlmapOutput},
 	})
 
 	r.Register(tool.ToolDef{
@@ -33,5 +48,18 @@ func init() {
 			"windows": "download from https://github.com/vanhauser-thc/thc-hydra",
 		},
 		VersionFlag: "-h", // hydra prints version in help header
+		// Target must carry the service: ssh://10.0.0.1, ftp://host:2121.
+		ArgTemplate: []string{"{{args}}", "{{scheme}}://{{host}}:{{port}}"},
+		Options: []tool.Option{
+			{Name: "login", Flag: "-l", Type: tool.OptionString, Conflicts: []string{"login-list"}, Help: "Single login name"},
+			{Name: "login-list", Flag: "-L", Type: tool.OptionWordlist, Help: "File of login names"},
+			{Name: "password", Flag: "-p", Type: tool.OptionPassword, Conflicts: []string{"password-list"}, Help: "Single password"},
+			{Name: "password-list", Flag: "-P", Type: tool.OptionWordlist, Help: "File of passwords"},
+			{Name: "tasks", Flag: "-t", Type: tool.OptionInt, Help: "Parallel connections per target"},
+			{Name: "combo", Flag: "-C", Type: tool.OptionCredentials, Conflicts: []string{"login", "login-list", "password", "password-list"},
+				Help: "Try stored credentials: all, validated, or a service name"},
+			{Name: "stop-first", Flag: "-f", Type: tool.OptionBool, Help: "Stop after the first valid pair"},
+		},
+		Parser: &tool.Parser{Parse: parse.HydraOutput},
 	})
 }
@@ -18,6 +18,13 @@ func init() {
 			"windows": "go install -v github.com/projectdiscovery/subfinder/v2/cmd/subfinder@latest",
 		},
 		VersionFlag: "-version",
+		MinVersion:  "2.0.0",
+		Env:         map[string]string{"PDCP_API_KEY": "{{secret:pdcp_api_key}}"}, // chaos source
+		ArgTemplate: []string{"-d", "{{host}}", "{{args}}"},
+		Options: []tool.Option{
+			{Name: "all", Flag: "-all", Type: tool.OptionBool, Help: "Use all sources (slower)"},
+			{Name: "recursive", Flag: "-recursive", Type: tool.OptionBool, Help: "Only use sources that support recursive enumeration"},
+		},
 	})
 
 	r.Register(tool.ToolDef{
@@ -33,6 +40,7 @@ func init() {
 			"windows": "go install -v github.com/owasp-amass/amass/v4/...@master",
 		},
 		VersionFlag: "-version",
+		ArgTemplate: []string{"-d", "{{host}}", "{{args}}"},
 	})
 
 	r.Register(tool.ToolDef{
@@ -47,7 +55,9 @@ func init() {
 			"darwin":  "pip install theHarvester",
 			"windows": "pip install theHarvester",
 		},
-		VersionFlag: "--help", // theHarvester prints version in help output
+		VersionFlag:  "--help",                           // theHarvester prints version in help output
+		VersionRegex: `theHarvester\s+v?(\d+\.\d+\.\d+)`, // skip other numbers in the banner
+		ArgTemplate:  []string{"-d", "{{host}}", "{{args}}"},
 	})
 
 	r.Register(tool.ToolDef{
@@ -1,6 +1,9 @@
 package defs
 
-import "nser/internal/tool"
+import (
+	"nser/internal/parse"
+	"nser/internal/tool"
+)
 
 func init() {
 	r := tool.DefaultRegistry
@@ -11,28 +14,49 @@ func init() {
 		Binary:      "nmap",
 		DefaultArgs: nil,
 		NeedsRoot:   true, // SYN scans, OS detection require root
-		Description: "Network discovery and security auditing with port scanning",
+		// setcap cap_net_raw,cap_net_admin,cap_net_bind_service+eip $(which nmap)
+		Capabilities:   []string{"cap_net_raw", "cap_net_admin", "cap_net_bind_service"},
+		CapabilityArgs: []string{"--privileged"},
+		Description:    "Network discovery and security auditing with port scanning",
 		InstallHint: map[string]string{
 			"linux":   "apt install nmap",
 			"darwin":  "brew install nmap",
 			"windows": "choco install nmap",
 		},
 		VersionFlag: "--version",
+		Options: []tool.Option{
+			{Name: "syn", Flag: "-sS", Type: tool.OptionBool, NeedsRoot: true, Conflicts: []string{"connect"}, Help: "TCP SYN scan"},
+			{Name: "connect", Flag: "-sT", Type: tool.OptionBool, Help: "TCP connect scan (no root needed)"},
+			{Name: "version", Flag: "-sV", Type: tool.OptionBool, Help: "Probe open ports for service/version info"},
+			{Name: "os", Flag: "-O", Type: tool.OptionBool, NeedsRoot: true, Help: "Enable OS detection"},
+			{Name: "ports", Flag: "-p", Type: tool.OptionPortList, Conflicts: []string{"top-ports"}, Help: "Ports to scan, e.g. 22,80,8000-8100 or - for all"},
+			{Name: "top-ports", Flag: "--top-ports", Type: tool.OptionInt, Help: "Scan the N most common ports"},
+			{Name: "timing", Flag: "-T", Type: tool.OptionEnum, Choices: []string{"0", "1", "2", "3", "4", "5"}, Help: "Timing template (higher is faster)"},
+		},
+		// XML goes to a side file; the user still sees the normal output.
+		Parser: &tool.Parser{Args: []string{"-oX", "{{file}}"}, Parse: parse.NmapXML},
 	})
 
 	r.Register(tool.ToolDef{
-		Name:        "masscan",
-		Category:    tool.CategoryScanning,
-		Binary:      "masscan",
-		DefaultArgs: nil,
-		NeedsRoot:   true,
-		Description: "Fastest Internet port scanner, supports async SYN scanning",
+		Name:         "masscan",
+		Category:     tool.CategoryScanning,
+		Binary:       "masscan",
+		DefaultArgs:  nil,
+		NeedsRoot:    true,
+		Capabilities: []string{"cap_net_raw", "cap_net_admin"},
+		Description:  "Fastest Internet port scanner, supports async SYN scanning",
 		InstallHint: map[string]string{
 			"linux":   "apt install masscan",
 			"darwin":  "brew install masscan",
 			"windows": "download from https://github.com/robertdavidgraham/masscan",
 		},
 		VersionFlag: "--version",
+		Options: []tool.Option{
+			{Name: "ports", Flag: "-p", Type: tool.OptionPortList, Default: "1-1000", Help: "Ports to scan"},
+			{Name: "rate", Flag: "--rate", Type: tool.OptionInt, Default: "1000", Help: "Packets per second"},
+			{Name: "banners", Flag: "--banners", Type: tool.OptionBool, Help: "Grab service banners"},
+		},
+		Parser: &tool.Parser{Args: []string{"-oJ", "{{file}}"}, Parse: parse.MasscanJSON},
 	})
 
 	r.Register(tool.ToolDef{
@@ -48,6 +72,20 @@ func init() {
 			"windows": "go install -v github.com/projectdiscovery/nuclei/v3/cmd/nuclei@latest",
 		},
 		VersionFlag: "-version",
+		// Nuclei prints "Nuclei Engine Version: v3.x"; the JSONL output format
+		// parsers rely on is specific to v3.
+		VersionRegex: `Engine Version: v?(\d+\.\d+\.\d+)`,
+		MinVersion:   "3.0.0",
+		MaxVersion:   "4.0.0",
+		ProxyFlag:    "-proxy",
+		Env:          map[string]string{"PDCP_API_KEY": "{{secret:pdcp_api_key}}"}, // ProjectDiscovery Cloud
+		ArgTemplate:  []string{"-u", "{{target}}", "{{args}}"},
+		Options: []tool.Option{
+			{Name: "severity", Flag: "-severity", Type: tool.OptionString, Help: "Comma-separated severities: info,low,medium,high,critical"},
+			{Name: "templates", Flag: "-t", Type: tool.OptionPath, Help: "Template file or directory"},
+			{Name: "tags", Flag: "-tags", Type: tool.OptionString, Help: "Execute templates with these tags"},
+			{Name: "automatic", Flag: "-as", Type: tool.OptionBool, Help: "Automatic web scan"},
+		},
 	})
 
 	r.Register(tool.ToolDef{
@@ -62,7 +100,15 @@ func init() {
 			"darwin":  "brew install gobuster",
 			"windows": "go install github.com/OJ/gobuster/v3@latest",
 		},
-		VersionFlag: "version",
+		VersionFlag:     "version",
+		MinVersion:      "3.0.0", // "dir" subcommand syntax
+		ArgTemplate:     []string{"dir", "-u", "{{url}}", "-w", "{{wordlist}}", "{{args}}"},
+		DefaultWordlist: "/usr/share/wordlists/dirb/common.txt",
+		Options: []tool.Option{
+			{Name: "wordlist", Type: tool.OptionWordlist, Help: "Wordlist path (defaults to dirb common.txt)"},
+			{Name: "threads", Flag: "-t", Type: tool.OptionInt, Help: "Concurrent threads"},
+			{Name: "extensions", Flag: "-x", Type: tool.OptionString, Help: "File extensions to search for, e.g. php,html"},
+		},
 	})
 
 	r.Register(tool.ToolDef{
@@ -77,7 +123,17 @@ func init() {
 			"darwin":  "brew install ffuf",
 			"windows": "go install github.com/ffuf/ffuf/v2@latest",
 		},
-		VersionFlag: "-V",
+		VersionFlag:     "-V",
+		ProxyFlag:       "-x",
+		ArgTemplate:     []string{"-u", "{{url}}/FUZZ", "-w", "{{wordlist}}", "{{args}}"},
+		DefaultWordlist: "/usr/share/wordlists/dirb/common.txt",
+		Options: []tool.Option{
+			{Name: "wordlist", Type: tool.OptionWordlist, Help: "Wordlist path (defaults to dirb common.txt)"},
+			{Name: "match-codes", Flag: "-mc", Type: tool.OptionString, Help: "Match HTTP status codes, e.g. 200,301"},
+			{Name: "filter-codes", Flag: "-fc", Type: tool.OptionString, Help: "Filter HTTP status codes"},
+			{Name: "filter-size", Flag: "-fs", Type: tool.OptionString, Help: "Filter by response size"},
+			{Name: "threads", Flag: "-t", Type: tool.OptionInt, Help: "Concurrent threads"},
+		},
 	})
 
 	r.Register(tool.ToolDef{
@@ -93,5 +149,6 @@ func init() {
 			"windows": "download from https://github.com/sullo/nikto",
 		},
 		VersionFlag: "-Version",
+		ArgTemplate: []string{"-h", "{{target}}", "{{args}}"},
 	})
 }
@@ -0,0 +1,173 @@
+package tool
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// ElevationPolicy says how the runner gets root for tools that need it when
+// the app itself isn't running as root.
+type ElevationPolicy string
+
+const (
+	ElevateNone   ElevationPolicy = "none"   // run unprivileged; the tool reports its own error
+	ElevateSudo   ElevationPolicy = "sudo"   // sudo -n: needs a NOPASSWD rule, never prompts
+	ElevatePkexec ElevationPolicy = "pkexec" // polkit: shows a graphical password prompt
+)
+
+// ParseElevationPolicy validates a stored or submitted policy. Empty means none.
+func ParseElevationPolicy(s string) (ElevationPolicy, error) {
+	switch p := ElevationPolicy(s); p {
+	case "":
+		return ElevateNone, nil
+	case ElevateNone, ElevateSudo, ElevatePkexec:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown elevation policy %q", s)
+	}
+}
+
+// How a privileged run got its privileges, recorded in tool_runs.elevation.
+// Empty means the run didn't need (or didn't get) privileges.
+const (
+	ElevationRoot         = "root"         // the app itself runs as root
+	ElevationCapabilities = "capabilities" // the binary has the needed file capabilities
+	ElevationSudo         = "sudo"
+	ElevationPkexec       = "pkexec"
+)
+
+// requiresRoot reports whether a run needs privileges: when any selected
+// option is marked NeedsRoot, or — for tools without per-option marks — when
+// the tool itself is. RawArgs aren't inspected.
+func requiresRoot(def ToolDef, req RunRequest) (bool, error) {
+	values, err := resolveOptionValues(def.Options, req.Options)
+	if err != nil {
+		return false, err
+	}
+	marked := false
+	for _, o := range def.Options {
+		if !o.NeedsRoot {
+			continue
+		}
+		marked = true
+		if _, ok := values[o.Name]; ok {
+			return true, nil
+		}
+	}
+	return def.NeedsRoot && !marked, nil
+}
+
+// elevationFor decides how a privileged run of def (found at binPath) gets
+// its privileges: the process is root with the tool's capabilities, the
+// binary carries them as file capabilities or inherits them as ambient
+// capabilities, or the policy's wrapper. Returns "" when none applies.
+func elevationFor(def ToolDef, binPath string, policy ElevationPolicy) string {
+	need := rootCaps(def)
+	priv := CheckPrivileges()
+	// Root in a container can lack capabilities; nil means the sets are
+	// unknown (non-Linux) and root is trusted.
+	if priv.Elevated && (priv.Capabilities == nil || hasCaps(priv.Capabilities, need)) {
+		return ElevationRoot
+	}
+	if caps, _ := FileCapabilities(binPath); hasCaps(caps, need) {
+		return ElevationCapabilities
+	}
+	if hasCaps(priv.AmbientCaps, need) {
+		return ElevationCapabilities
+	}
+	return policyElevation(policy)
+}
+
+// policyElevation returns the wrapper elevation for policy, or "" for none.
+func policyElevation(policy ElevationPolicy) string {
+	switch policy {
+	case ElevateSudo:
+		return ElevationSudo
+	case ElevatePkexec:
+		return ElevationPkexec
+	}
+	return ""
+}
+
+// needsPrivileges reports whether any of def's work requires root.
+func needsPrivileges(def ToolDef) bool {
+	if def.NeedsRoot {
+		return true
+	}
+	for _, o := range def.Options {
+		if o.NeedsRoot {
+			return true
+		}
+	}
+	return false
+}
+
+// sudoAllowed reports whether sudo -n may run binPath without a password.
+func sudoAllowed(ctx context.Context, binPath string) bool {
+	sudo, err := exec.LookPath("sudo")
+	if err != nil {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(ctx, versionTimeout)
+	defer cancel()
+	return exec.CommandContext(ctx, sudo, "-n", "-l", "--", binPath).Run() == nil
+}
+
+// wrapElevated returns the executable and argv that run binPath with args
+// under the given elevation, carrying env ("K=V" entries meant for the
+// tool) across the wrapper.
+func wrapElevated(elevation, binPath string, args, env []string) (string, []string, error) {
+	wrapper, argv, err := elevationArgv(elevation, binPath, args, env)
+	if err != nil || wrapper == "" {
+		return binPath, args, err
+	}
+	path, err := exec.LookPath(wrapper)
+	if err != nil {
+		return "", nil, fmt.Errorf("elevation via %s: %w", wrapper, err)
+	}
+	return path, argv, nil
+}
+
+// elevationArgv returns the wrapper to run and its argv, or "" when the
+// run isn't wrapped. sudo and pkexec both reset the environment, so the
+// proxy, tool and secret variables would silently be lost: sudo is told to
+// keep them by name with --preserve-env (the sudoers rule needs SETENV or
+// a matching env_keep, else sudo refuses the run), and pkexec, which can't
+// keep any and would expose values passed in argv to the process list,
+// refuses runs that need them.
+func elevationArgv(elevation, binPath string, args, env []string) (string, []string, error) {
+	names := envNames(env)
+	switch elevation {
+	case ElevationSudo:
+		argv := []string{"-n"}
+		if len(names) > 0 {
+			argv = append(argv, "--preserve-env="+strings.Join(names, ","))
+		}
+		return "sudo", append(append(argv, "--", binPath), args...), nil
+	case ElevationPkexec:
+		if len(names) > 0 {
+			return "", nil, fmt.Errorf("pkexec can't pass %s to the tool; use the sudo elevation policy or a proxychains network profile",
+				strings.Join(names, ", "))
+		}
+		return "pkexec", append([]string{binPath}, args...), nil
+	}
+	return "", nil, nil
+}
+
+// envNames returns the sorted, distinct variable names in env.
+func envNames(env []string) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, kv := range env {
+		name, _, _ := strings.Cut(kv, "=")
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,232 @@
+package tool
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// SecretStore supplies the values behind {{secret:name}} references and
+// seals the password values of stored runs under its key. Implemented by
+// secrets.Store.
+type SecretStore interface {
+	Lookup(ctx context.Context, name string) (string, bool, error)
+	Values(ctx context.Context) ([]string, error)
+	Seal(label string, plaintext []byte) ([]byte, error)
+	Open(label string, data []byte) ([]byte, error)
+}
+
+var (
+	envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	secretRef      = regexp.MustCompile(`\{\{secret:([a-z0-9_.-]+)\}\}`)
+)
+
+// minMaskLen keeps very short secrets from masking unrelated output.
+const minMaskLen = 4
+
+// EnvVar is a user-configured environment variable for tool runs.
+// Value may reference secrets: "{{secret:shodan}}".
+type EnvVar struct {
+	ID          int64  `json:"id"`
+	WorkspaceID int64  `json:"workspaceId"` // 0 = every workspace
+	ToolName    string `json:"toolName"`    // "" = every tool
+	Name        string `json:"name"`
+	Value       string `json:"value"`
+}
+
+// validateEnvVar checks a variable name and its value's placeholders.
+func validateEnvVar(name, value string) error {
+	if !envNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid environment variable name %q", name)
+	}
+	if strings.Contains(secretRef.ReplaceAllString(value, ""), "{{") {
+		return fmt.Errorf("env %s: only {{secret:name}} placeholders are allowed", name)
+	}
+	return nil
+}
+
+// validateEnv checks a ToolDef.Env map.
+func validateEnv(env map[string]string) error {
+	for name, value := range env {
+		if err := validateEnvVar(name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expandEnvValue replaces secret references in value. ok is false when a
+// referenced secret isn't set (or there is no store), so the variable is
+// left out rather than exported half-filled.
+func expandEnvValue(ctx context.Context, store SecretStore, value string) (string, bool, error) {
+	var expandErr error
+	ok := true
+	expanded := secretRef.ReplaceAllStringFunc(value, func(ref string) string {
+		if store == nil {
+			ok = false
+			return ""
+		}
+		name := secretRef.FindStringSubmatch(ref)[1]
+		secret, found, err := store.Lookup(ctx, name)
+		if err != nil && expandErr == nil {
+			expandErr = err
+		}
+		if !found {
+			ok = false
+		}
+		return secret
+	})
+	if expandErr != nil {
+		return "", false, expandErr
+	}
+	return expanded, ok, nil
+}
+
+// runEnv resolves the environment for a run of def in a workspace: the
+// tool's Env templates, then user variables from least to most specific
+// (all tools everywhere, this tool everywhere, all tools in the workspace,
+// this tool in the workspace). Later entries override earlier ones.
+func runEnv(ctx context.Context, db *sql.DB, store SecretStore, def ToolDef, workspaceID int64) ([]string, error) {
+	values := make(map[string]string)
+	names := make([]string, 0, len(def.Env))
+	for name := range def.Env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		values[name] = def.Env[name]
+	}
+
+	rows, err := db.QueryContext(ctx,
+		`SELECT name, value FROM run_env
+		 WHERE (workspace_id IS NULL OR workspace_id = ?) AND (tool_name = '' OR tool_name = ?)
+		 ORDER BY workspace_id IS NOT NULL, tool_name != '', id`,
+		workspaceID, def.Name,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("load run env: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name, value string
+		if err := rows.Scan(&name, &value); err != nil {
+			return nil, fmt.Errorf("scan run env: %w", err)
+		}
+		if _, seen := values[name]; !seen {
+			names = append(names, name)
+		}
+		values[name] = value
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var env []string
+	for _, name := range names {
+		value, ok, err := expandEnvValue(ctx, store, values[name])
+		if err != nil {
+			return nil, fmt.Errorf("env %s: %w", name, err)
+		}
+		if ok {
+			env = append(env, name+"="+value)
+		}
+	}
+	return env, nil
+}
+
+// secretMasker returns a func replacing every known secret value with
+// "********", or nil when there is nothing to mask.
+func secretMasker(ctx context.Context, store SecretStore) (func(string) string, error) {
+	if store == nil {
+		return nil, nil
+	}
+	values, err := store.Values(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Longest first so a secret containing another is masked whole.
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	var pairs []string
+	for _, v := range values {
+		if len(v) >= minMaskLen {
+			pairs = append(pairs, v, "********")
+		}
+	}
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+	return strings.NewReplacer(pairs...).Replace, nil
+}
+
+// ListEnvVars returns the variables that apply to a workspace: its own and
+// the global ones (workspaceID 0 returns only global ones).
+func ListEnvVars(ctx context.Context, db *sql.DB, workspaceID int64) ([]EnvVar, error) {
+	rows, err := db.QueryContext(ctx,
+		`SELECT id, COALESCE(workspace_id, 0), tool_name, name, value FROM run_env
+		 WHERE workspace_id IS NULL OR workspace_id = ?
+		 ORDER BY workspace_id IS NOT NULL, tool_name, name`,
+		workspaceID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list env vars: %w", err)
+	}
+	defer rows.Close()
+
+	var result []EnvVar
+	for rows.Next() {
+		var v EnvVar
+		if err := rows.Scan(&v.ID, &v.WorkspaceID, &v.ToolName, &v.Name, &v.Value); err != nil {
+			return nil, fmt.Errorf("scan env var: %w", err)
+		}
+		result = append(result, v)
+	}
+	return result, rows.Err()
+}
+
+// SaveEnvVar validates and stores a variable. ID 0 inserts (replacing a
+// variable with the same scope and name), otherwise the row is updated.
+func SaveEnvVar(ctx context.Context, db *sql.DB, v EnvVar) (EnvVar, error) {
+	if err := validateEnvVar(v.Name, v.Value); err != nil {
+		return EnvVar{}, err
+	}
+	workspace := sql.NullInt64{Int64: v.WorkspaceID, Valid: v.WorkspaceID != 0}
+
+	if v.ID != 0 {
+		_, err := db.ExecContext(ctx,
+			`UPDATE run_env SET workspace_id = ?, tool_name = ?, name = ?, value = ? WHERE id = ?`,
+			workspace, v.ToolName, v.Name, v.Value, v.ID,
+		)
+		if err != nil {
+			return EnvVar{}, fmt.Errorf("update env var: %w", err)
+		}
+		return v, nil
+	}
+
+	// UNIQUE doesn't cover NULL workspace IDs, so replace explicitly.
+	if _, err := db.ExecContext(ctx,
+		`DELETE FROM run_env WHERE workspace_id IS ? AND tool_name = ? AND name = ?`,
+		workspace, v.ToolName, v.Name,
+	); err != nil {
+		return EnvVar{}, fmt.Errorf("replace env var: %w", err)
+	}
+	res, err := db.ExecContext(ctx,
+		`INSERT INTO run_env (workspace_id, tool_name, name, value) VALUES (?, ?, ?, ?)`,
+		workspace, v.ToolName, v.Name, v.Value,
+	)
+	if err != nil {
+		return EnvVar{}, fmt.Errorf("insert env var: %w", err)
+	}
+	v.ID, _ = res.LastInsertId()
+	return v, nil
+}
+
+// DeleteEnvVar removes a variable.
+func DeleteEnvVar(ctx context.Context, db *sql.DB, id int64) error {
+	if _, err := db.ExecContext(ctx, `DELETE FROM run_env WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("delete env var: %w", err)
+	}
+	return nil
+}
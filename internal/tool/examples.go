@@ -0,0 +1,158 @@
+package tool
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"strings"
+)
+
+// exampleLiterals maps the placeholder values used in documentation examples
+// to template placeholders. They only replace a whole value (see
+// templateArg), never a substring, so "10.0.0.100" and "sub.example.com"
+// are left alone.
+var exampleLiterals = map[string]string{
+	"https://example.com": "{{url}}",
+	"http://example.com":  "{{url}}",
+	"10.0.0.0/24":         "{{target}}",
+	"example.com":         "{{host}}",
+	"10.0.0.1":            "{{host}}",
+}
+
+// exampleWordlist is replaced by {{wordlist}} when it is a whole argument.
+const exampleWordlist = "wordlist.txt"
+
+// SplitCommandLine splits a shell-style command into argv, honouring single
+// quotes, double quotes and backslash escapes. It does not expand variables
+// or globs.
+func SplitCommandLine(s string) ([]string, error) {
+	var (
+		args    []string
+		cur     strings.Builder
+		inArg   bool
+		quote   rune
+		escaped bool
+	)
+	for _, c := range s {
+		switch {
+		case escaped:
+			cur.WriteRune(c)
+			escaped = false
+		case c == '\\' && quote != '\'':
+			escaped = true
+			inArg = true
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else {
+				cur.WriteRune(c)
+			}
+		case c == '\'' || c == '"':
+			quote = c
+			inArg = true
+		case c == ' ' || c == '\t' || c == '\n':
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		default:
+			cur.WriteRune(c)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote in command", quote)
+	}
+	if escaped {
+		return nil, fmt.Errorf("trailing backslash in command")
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+	return args, nil
+}
+
+// ExampleTemplate turns a documented example command ("nmap -sV 10.0.0.1")
+// into an argument template for def: the binary must match def.Binary and
+// example targets become placeholders. {{args}} is appended so the user can
+// still add flags.
+//
+// Like any request template, the result replaces def.DefaultArgs, so the
+// example runs as written: one that needs a default such as sqlmap's
+// --batch must include it.
+func ExampleTemplate(def ToolDef, command string) ([]string, error) {
+	argv, err := SplitCommandLine(command)
+	if err != nil {
+		return nil, err
+	}
+	if len(argv) == 0 {
+		return nil, fmt.Errorf("example command is empty")
+	}
+	if filepath.Base(argv[0]) != def.Binary {
+		return nil, fmt.Errorf("example runs %q but tool %q uses %q", argv[0], def.Name, def.Binary)
+	}
+
+	tmpl := make([]string, 0, len(argv))
+	for _, arg := range argv[1:] {
+		tmpl = append(tmpl, templateArg(arg))
+	}
+	tmpl = append(tmpl, argsPlaceholder)
+
+	if err := ValidateArgTemplate(tmpl); err != nil {
+		return nil, fmt.Errorf("example command: %w", err)
+	}
+	return tmpl, nil
+}
+
+// templateArg replaces the example value in one argument: the whole
+// argument, or the value of a joined "--flag=value".
+func templateArg(arg string) string {
+	if arg == exampleWordlist {
+		return "{{wordlist}}"
+	}
+	if i := strings.Index(arg, "="); i > 0 && strings.HasPrefix(arg, "-") {
+		return arg[:i+1] + templateValue(arg[i+1:])
+	}
+	return templateValue(arg)
+}
+
+// templateValue replaces an example value that is exactly a literal, a URL
+// whose host is one ("https://example.com/FUZZ", "ssh://10.0.0.1:22"), or a
+// host:port. Anything else is returned unchanged.
+func templateValue(v string) string {
+	if p, ok := exampleLiterals[v]; ok {
+		return p
+	}
+	if scheme, rest, ok := strings.Cut(v, "://"); ok {
+		authority, path := rest, ""
+		if i := strings.IndexAny(rest, "/?#"); i >= 0 {
+			authority, path = rest[:i], rest[i:]
+		}
+		if p, ok := exampleLiterals[scheme+"://"+authority]; ok {
+			return p + path
+		}
+		if host := templateHostPort(authority); host != authority {
+			return scheme + "://" + host + path
+		}
+		return v
+	}
+	return templateHostPort(v)
+}
+
+// templateHostPort replaces the host of "host" or "host:port" when it is an
+// example host.
+func templateHostPort(v string) string {
+	host, port, err := net.SplitHostPort(v)
+	if err != nil {
+		host, port = v, ""
+	}
+	p, ok := exampleLiterals[host]
+	if !ok || p != "{{host}}" {
+		return v
+	}
+	if port != "" {
+		return p + ":" + port
+	}
+	return p
+}
@@ -1,84 +1,285 @@
 package tool
 
 import (
+	"context"
+	"fmt"
 	"os/exec"
 	"runtime"
+	"sort"
 	"strings"
+	"sync"
+	"time"
+)
+
+// Health statuses reported in ToolHealth.Status.
+const (
+	HealthOK          = "ok"          // installed, version within the supported range
+	HealthMissing     = "missing"     // binary not found in PATH
+	HealthUnknown     = "unknown"     // installed, but the version couldn't be determined or the check timed out
+	HealthOutdated    = "outdated"    // version below MinVersion
+	HealthUnsupported = "unsupported" // version at or above MaxVersion
+	HealthError       = "error"       // user-defined tool failed to load
 )
 
 // ToolHealth reports the availability status of a single tool.
 type ToolHealth struct {
-	Name        string `json:"name"`
-	Category    string `json:"category"`
-	Installed   bool   `json:"installed"`
-	Version     string `json:"version"`
-	Path        string `json:"path"`
-	NeedsRoot   bool   `json:"needsRoot"`
+	Name           string   `json:"name"`
+	Category       string   `json:"category"`
+	Installed      bool     `json:"installed"`
+	Status         string   `json:"status"`
+	Version        string   `json:"version"`
+	VersionOutput  string   `json:"versionOutput"`
+	SupportedRange string   `json:"supportedRange"`
+	Path           string   `json:"path"`
+	NeedsRoot      bool     `json:"needsRoot"`
+	FileCaps       []string `json:"fileCaps"`  // Linux file capabilities on the binary
+	Elevation      string   `json:"elevation"` // how privileged runs get root (see elevation.go)
+
+	// PrivilegeStatus says whether the tool's root-only features work in the
+	// current context; empty for tools that never need root.
+	PrivilegeStatus string `json:"privilegeStatus"`
+	PrivilegeNote   string `json:"privilegeNote"`
+
 	InstallHint string `json:"installHint"`
+	Source      string `json:"source"`
+	Error       string `json:"error"`
 }
 
+// Privilege statuses reported in ToolHealth.PrivilegeStatus.
+const (
+	PrivilegeOK          = "ok"          // root or capabilities available directly
+	PrivilegeElevate     = "elevate"     // runs will be wrapped with sudo or pkexec
+	PrivilegeUnavailable = "unavailable" // root-only features will fail
+)
+
 // PrivilegeInfo reports the current privilege status of the running process.
 type PrivilegeInfo struct {
 	Elevated bool   `json:"elevated"`
 	Username string `json:"username"`
 	OS       string `json:"os"`
+
+	// Capabilities is the process's effective capability set and
+	// AmbientCaps the subset tools started by nser inherit (Linux only).
+	Capabilities []string `json:"capabilities"`
+	AmbientCaps  []string `json:"ambientCaps"`
+
+	// ToolCaps lists the file capabilities of each installed tool's binary
+	// (filled by Registry.PrivilegeStatus).
+	ToolCaps map[string][]string `json:"toolCaps"`
 }
 
-// CheckAll returns the health status of every registered tool.
+// Health checks run version commands concurrently, each bounded by
+// versionTimeout so one hanging binary can't stall the dashboard.
+const healthConcurrency = 8
+
+var versionTimeout = 5 * time.Second // var so tests can shorten it
+
+// CheckAll returns the health status of every registered tool, sorted by name.
 func (r *Registry) CheckAll() []ToolHealth {
-	defs := r.List()
-	results := make([]ToolHealth, 0, len(defs))
-
-	for _, def := range defs {
-		h := ToolHealth{
-			Name:      def.Name,
-			Category:  string(def.Category),
-			NeedsRoot: def.NeedsRoot,
-		}
+	results := r.Check(context.Background(), r.List())
 
-		// Install hint for current OS.
-		h.InstallHint = def.InstallHint[runtime.GOOS]
+	// User-defined tools that failed to load show up as broken entries.
+	for _, p := range r.Problems() {
+		results = append(results, problemHealth(p))
+	}
 
-		// Check if binary is in PATH.
-		path, err := exec.LookPath(def.Binary)
-		if err != nil {
-			h.Installed = false
-			results = append(results, h)
-			continue
-		}
+	return results
+}
 
-		h.Installed = true
-		h.Path = path
+// problemHealth turns a load problem into a dashboard entry.
+func problemHealth(p LoadProblem) ToolHealth {
+	return ToolHealth{
+		Name:     p.Name,
+		Category: "custom",
+		Status:   HealthError,
+		Source:   p.Source,
+		Error:    p.Error,
+	}
+}
 
-		// Try to get version.
-		if def.VersionFlag != "" {
-			h.Version = getVersion(path, def.VersionFlag)
-		}
+// sortHealth orders health results by tool name.
+func sortHealth(results []ToolHealth) {
+	sort.Slice(results, func(i, j int) bool { return results[i].Name < results[j].Name })
+}
 
-		results = append(results, h)
+// Check returns the health of the given tools, checked concurrently and
+// sorted by name.
+func (r *Registry) Check(ctx context.Context, defs []ToolDef) []ToolHealth {
+	results := make([]ToolHealth, len(defs))
+	sem := make(chan struct{}, healthConcurrency)
+	var wg sync.WaitGroup
+
+	for i, def := range defs {
+		wg.Add(1)
+		go func(i int, def ToolDef) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i] = CheckTool(ctx, def)
+		}(i, def)
 	}
+	wg.Wait()
 
+	sortHealth(results)
 	return results
 }
 
-// getVersion runs "binary <versionFlag>" and returns the first non-empty
-// line of output. Returns "" on any error.
-func getVersion(binaryPath, versionFlag string) string {
-	out, err := exec.Command(binaryPath, versionFlag).CombinedOutput()
+// CheckTool returns the health status of a single tool.
+func CheckTool(ctx context.Context, def ToolDef) ToolHealth {
+	h := ToolHealth{
+		Name:           def.Name,
+		Category:       string(def.Category),
+		NeedsRoot:      def.NeedsRoot,
+		Source:         def.Source,
+		SupportedRange: supportedRange(def),
+	}
+
+	// Install hint for current OS.
+	h.InstallHint = def.InstallHint[runtime.GOOS]
+
+	// Check if binary is in PATH.
+	path, err := exec.LookPath(def.Binary)
 	if err != nil {
-		// Some tools return non-zero exit code with --version.
-		// We still try to extract version from output.
-		if len(out) == 0 {
-			return ""
+		h.Installed = false
+		h.Status = HealthMissing
+		return h
+	}
+
+	h.Installed = true
+	h.Path = path
+	checkPrivilege(ctx, def, &h, ElevateNone)
+
+	// Try to get version.
+	if def.VersionFlag != "" {
+		out, err := getVersionOutput(ctx, path, def.VersionFlag)
+		if err != nil {
+			h.Error = err.Error()
 		}
+		h.Version = extractVersion(def, out)
+		h.VersionOutput = versionLine(out, h.Version)
 	}
+	h.Status = versionStatus(def, h.Version)
+	if h.Error != "" && h.Version == "" {
+		// A timed-out check says nothing about the version, range or not.
+		h.Status = HealthUnknown
+	}
+
+	return h
+}
 
-	// Return the first non-empty line — usually contains the version.
-	for _, line := range strings.Split(string(out), "\n") {
+// checkPrivilege fills in the binary's file capabilities and whether the
+// tool's root-only features will work under policy. It reads the binary on
+// every call since setcap doesn't change its mtime.
+func checkPrivilege(ctx context.Context, def ToolDef, h *ToolHealth, policy ElevationPolicy) {
+	h.FileCaps, _ = FileCapabilities(h.Path)
+	h.Elevation, h.PrivilegeStatus, h.PrivilegeNote = "", "", ""
+	if !needsPrivileges(def) {
+		return
+	}
+
+	h.Elevation = elevationFor(def, h.Path, policy)
+	switch h.Elevation {
+	case ElevationRoot, ElevationCapabilities:
+		h.PrivilegeStatus = PrivilegeOK
+	case ElevationSudo:
+		if sudoAllowed(ctx, h.Path) {
+			h.PrivilegeStatus = PrivilegeElevate
+		} else {
+			h.PrivilegeStatus = PrivilegeUnavailable
+			h.PrivilegeNote = "sudo -n needs a NOPASSWD rule for " + h.Path
+		}
+	case ElevationPkexec:
+		h.PrivilegeStatus = PrivilegeElevate
+		h.PrivilegeNote = "pkexec will ask for a password on each privileged run"
+	default:
+		h.PrivilegeStatus = PrivilegeUnavailable
+		h.PrivilegeNote = fmt.Sprintf("needs root, %s on the binary, or an elevation policy",
+			strings.Join(rootCaps(def), ","))
+	}
+}
+
+// PrivilegeStatus returns CheckPrivileges plus the file capabilities of
+// every installed tool.
+func (r *Registry) PrivilegeStatus() PrivilegeInfo {
+	info := CheckPrivileges()
+	info.ToolCaps = make(map[string][]string)
+	for _, def := range r.List() {
+		path, err := exec.LookPath(def.Binary)
+		if err != nil {
+			continue
+		}
+		if caps, _ := FileCapabilities(path); len(caps) > 0 {
+			info.ToolCaps[def.Name] = caps
+		}
+	}
+	return info
+}
+
+// getVersionOutput runs "binary <versionFlag>" and returns its combined
+// output. Only a timeout is reported as an error — tools that exit non-zero
+// on --version still have their output used.
+func getVersionOutput(ctx context.Context, binaryPath, versionFlag string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, versionTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, binaryPath, versionFlag)
+	cmd.WaitDelay = time.Second // don't wait on pipes held open by grandchildren
+	out, _ := cmd.CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		return strings.TrimSpace(string(out)), fmt.Errorf("version check timed out after %s", versionTimeout)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// versionLine returns the output line the version was found on, or the
+// first non-empty line — shown in the dashboard instead of a whole help page.
+func versionLine(output, version string) string {
+	first := ""
+	for _, line := range strings.Split(output, "\n") {
 		line = strings.TrimSpace(line)
-		if line != "" {
+		if line == "" {
+			continue
+		}
+		if version != "" && strings.Contains(line, version) {
 			return line
 		}
+		if first == "" {
+			first = line
+		}
+	}
+	return first
+}
+
+// versionStatus classifies a parsed version against def's supported range.
+func versionStatus(def ToolDef, version string) string {
+	if def.MinVersion == "" && def.MaxVersion == "" {
+		return HealthOK // nothing to check against
+	}
+	v, err := ParseVersion(version)
+	if err != nil {
+		return HealthUnknown
+	}
+	if def.MinVersion != "" {
+		if minV, err := ParseVersion(def.MinVersion); err == nil && v.Compare(minV) < 0 {
+			return HealthOutdated
+		}
+	}
+	if def.MaxVersion != "" {
+		if maxV, err := ParseVersion(def.MaxVersion); err == nil && v.Compare(maxV) >= 0 {
+			return HealthUnsupported
+		}
+	}
+	return HealthOK
+}
+
+// supportedRange formats def's version bounds for display: ">=3.0.0 <4.0.0".
+func supportedRange(def ToolDef) string {
+	var parts []string
+	if def.MinVersion != "" {
+		parts = append(parts, ">="+def.MinVersion)
+	}
+	if def.MaxVersion != "" {
+		parts = append(parts, "<"+def.MaxVersion)
 	}
-	return ""
+	return strings.Join(parts, " ")
 }
@@ -0,0 +1,196 @@
+package tool
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+// HealthCache stores tool health results in the tool_health table so the
+// dashboard doesn't rerun every version command on each load. A cached entry
+// is reused while it is younger than the TTL and the binary found in PATH is
+// still the same file (path and modification time) — installing, upgrading
+// or removing a tool invalidates its entry immediately.
+//
+// Privilege fields are recomputed on every Get, since they depend on the
+// current elevation policy and on capabilities that don't touch the mtime.
+type HealthCache struct {
+	registry *Registry
+	db       *sql.DB
+	ttl      time.Duration
+	policy   func() ElevationPolicy
+}
+
+// NewHealthCache creates a cache for registry's tools backed by db. policy
+// reports the runner's current elevation policy; nil means none.
+func NewHealthCache(registry *Registry, db *sql.DB, ttl time.Duration, policy func() ElevationPolicy) *HealthCache {
+	if policy == nil {
+		policy = func() ElevationPolicy { return ElevateNone }
+	}
+	return &HealthCache{registry: registry, db: db, ttl: ttl, policy: policy}
+}
+
+// cachedHealth is one tool_health row.
+type cachedHealth struct {
+	health    ToolHealth
+	path      string
+	mtime     int64
+	checkedAt time.Time
+}
+
+// binaryStamp returns the resolved path and modification time of a binary,
+// or empty values when it isn't in PATH.
+func binaryStamp(binary string) (string, int64) {
+	path, err := exec.LookPath(binary)
+	if err != nil {
+		return "", 0
+	}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return path, 0
+	}
+	return path, fi.ModTime().UnixNano()
+}
+
+// Get returns the health of every registered tool, rechecking only stale or
+// invalidated entries. Load problems are appended as in CheckAll.
+func (c *HealthCache) Get(ctx context.Context) ([]ToolHealth, error) {
+	cached, err := c.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defs := c.registry.List()
+	var results []ToolHealth
+	var stale []ToolDef
+	for _, def := range defs {
+		entry, ok := cached[def.Name]
+		path, mtime := binaryStamp(def.Binary)
+		if !ok || time.Since(entry.checkedAt) > c.ttl || entry.path != path || entry.mtime != mtime {
+			stale = append(stale, def)
+			continue
+		}
+		// Range and metadata come from the current definition, which may
+		// have changed since the check (e.g. an edited custom tool).
+		h := entry.health
+		h.Category = string(def.Category)
+		h.NeedsRoot = def.NeedsRoot
+		h.Source = def.Source
+		h.SupportedRange = supportedRange(def)
+		if h.Installed {
+			h.Status = versionStatus(def, h.Version)
+		}
+		results = append(results, h)
+	}
+
+	fresh, err := c.check(ctx, stale)
+	if err != nil {
+		return nil, err
+	}
+	results = append(results, fresh...)
+
+	policy := c.policy()
+	byName := make(map[string]ToolDef, len(defs))
+	for _, def := range defs {
+		byName[def.Name] = def
+	}
+	for i := range results {
+		if results[i].Installed {
+			checkPrivilege(ctx, byName[results[i].Name], &results[i], policy)
+		}
+	}
+	return c.finish(results), nil
+}
+
+// Recheck bypasses the cache for the named tools (all tools when names is
+// empty) and returns the full, updated health list.
+func (c *HealthCache) Recheck(ctx context.Context, names []string) ([]ToolHealth, error) {
+	var defs []ToolDef
+	if len(names) == 0 {
+		defs = c.registry.List()
+	} else {
+		for _, name := range names {
+			def, err := c.registry.Get(name)
+			if err != nil {
+				return nil, err
+			}
+			defs = append(defs, def)
+		}
+	}
+	if _, err := c.check(ctx, defs); err != nil {
+		return nil, err
+	}
+	return c.Get(ctx)
+}
+
+// check runs health checks for defs and stores the results.
+func (c *HealthCache) check(ctx context.Context, defs []ToolDef) ([]ToolHealth, error) {
+	if len(defs) == 0 {
+		return nil, nil
+	}
+	results := c.registry.Check(ctx, defs)
+
+	binaries := make(map[string]string, len(defs))
+	for _, def := range defs {
+		binaries[def.Name] = def.Binary
+	}
+	for _, h := range results {
+		path, mtime := binaryStamp(binaries[h.Name])
+		healthJSON, err := json.Marshal(h)
+		if err != nil {
+			return nil, fmt.Errorf("encode health for %q: %w", h.Name, err)
+		}
+		_, err = c.db.ExecContext(ctx,
+			`INSERT INTO tool_health (tool_name, path, mtime, health_json, checked_at) VALUES (?, ?, ?, ?, ?)
+			 ON CONFLICT(tool_name) DO UPDATE SET
+			     path = excluded.path, mtime = excluded.mtime,
+			     health_json = excluded.health_json, checked_at = excluded.checked_at`,
+			h.Name, path, mtime, string(healthJSON), time.Now(),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("cache health for %q: %w", h.Name, err)
+		}
+	}
+	return results, nil
+}
+
+// load reads every cached entry keyed by tool name.
+func (c *HealthCache) load(ctx context.Context) (map[string]cachedHealth, error) {
+	rows, err := c.db.QueryContext(ctx,
+		`SELECT tool_name, COALESCE(path,''), COALESCE(mtime,0), health_json, checked_at FROM tool_health`)
+	if err != nil {
+		return nil, fmt.Errorf("load health cache: %w", err)
+	}
+	defer rows.Close()
+
+	cached := make(map[string]cachedHealth)
+	for rows.Next() {
+		var name, healthJSON string
+		var e cachedHealth
+		if err := rows.Scan(&name, &e.path, &e.mtime, &healthJSON, &e.checkedAt); err != nil {
+			return nil, fmt.Errorf("scan health cache: %w", err)
+		}
+		if err := json.Unmarshal([]byte(healthJSON), &e.health); err != nil {
+			continue // unreadable entry: treat as stale
+		}
+		cached[name] = e
+	}
+	return cached, rows.Err()
+}
+
+// finish sorts results and appends load problems, matching CheckAll.
+func (c *HealthCache) finish(results []ToolHealth) []ToolHealth {
+	sortHealth(results)
+	for _, p := range c.registry.Problems() {
+		results = append(results, problemHealth(p))
+	}
+	return results
+}
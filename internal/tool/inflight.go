@@ -0,0 +1,60 @@
+package tool
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrClosed is returned when starting work on a closed Runner or Installer.
+var ErrClosed = errors.New("database is being switched; try again")
+
+// inFlight counts running processes that still write to the database, so
+// the database isn't closed underneath them.
+type inFlight struct {
+	mu     sync.Mutex
+	n      int
+	closed bool
+}
+
+// begin registers one process; every successful begin needs an end.
+func (f *inFlight) begin() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return ErrClosed
+	}
+	f.n++
+	return nil
+}
+
+func (f *inFlight) end() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.n--
+}
+
+// count returns the number of processes in flight.
+func (f *inFlight) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.n
+}
+
+// close refuses new work, unless some is still in flight.
+func (f *inFlight) close(what string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.n > 0 {
+		return fmt.Errorf("%d %s still running", f.n, what)
+	}
+	f.closed = true
+	return nil
+}
+
+// reopen undoes close, for when closing a sibling failed.
+func (f *inFlight) reopen() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = false
+}
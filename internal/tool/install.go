@@ -0,0 +1,318 @@
+package tool
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// installTimeout bounds a single install; `go install` of a large tool can
+// take several minutes on a cold module cache.
+const installTimeout = 20 * time.Minute
+
+// Package managers understood by PlanInstall.
+const (
+	ManagerApt = "apt"
+	ManagerGo  = "go"
+	ManagerPip = "pip"
+)
+
+// InstallPlan is the exact command InstallTool will run for a tool, derived
+// from its Linux InstallHint. The frontend shows CommandLine before the user
+// confirms.
+type InstallPlan struct {
+	ToolName    string   `json:"toolName"`
+	Hint        string   `json:"hint"`
+	Manager     string   `json:"manager"`
+	Argv        []string `json:"argv"`
+	CommandLine string   `json:"commandLine"`
+	NeedsRoot   bool     `json:"needsRoot"`
+	BinDir      string   `json:"binDir"` // where the binary should land ("" = system PATH)
+}
+
+// InstallStartResult is returned when an install begins. Progress arrives as
+//
+//	"tool:install:output:<installID>" — payload: string (one line of output)
+//	"tool:install:done:<installID>"   — payload: InstallResult
+type InstallStartResult struct {
+	InstallID int64       `json:"installId"`
+	Plan      InstallPlan `json:"plan"`
+}
+
+// InstallResult is the final summary of an install.
+type InstallResult struct {
+	InstallID   int64  `json:"installId"`
+	ToolName    string `json:"toolName"`
+	CommandLine string `json:"commandLine"`
+	Status      string `json:"status"`
+	ExitCode    int    `json:"exitCode"`
+	Output      string `json:"output"`
+	Duration    string `json:"duration"`
+	Path        string `json:"path"`        // resolved binary after install, "" if not found
+	AddedToPath string `json:"addedToPath"` // directory added to the nser search path
+	Error       string `json:"error"`
+}
+
+// PlanInstall turns def's Linux install hint into a command line:
+//
+//	apt install X   → apt-get install -y X (needs root)
+//	go install P    → go install P, landing in $GOBIN or $GOPATH/bin
+//	pip install X   → pip install --user X unless running as root
+//
+// Other hints ("download from ...") can't be automated and return an error.
+func PlanInstall(def ToolDef) (InstallPlan, error) {
+	hint := def.InstallHint["linux"]
+	plan := InstallPlan{ToolName: def.Name, Hint: hint}
+	if hint == "" {
+		return plan, fmt.Errorf("tool %q has no linux install hint", def.Name)
+	}
+	argv, err := SplitCommandLine(hint)
+	if err != nil {
+		return plan, fmt.Errorf("install hint: %w", err)
+	}
+	if len(argv) < 3 || argv[1] != "install" {
+		return plan, fmt.Errorf("install hint %q is not an automatable install command", hint)
+	}
+
+	switch argv[0] {
+	case "apt", "apt-get":
+		plan.Manager = ManagerApt
+		plan.NeedsRoot = true
+		// apt's CLI isn't meant for scripts; apt-get -y never prompts.
+		plan.Argv = append([]string{"apt-get", "install", "-y"}, argv[2:]...)
+
+	case "go":
+		plan.Manager = ManagerGo
+		plan.Argv = argv
+		plan.BinDir = goBinDir()
+
+	case "pip", "pip3":
+		plan.Manager = ManagerPip
+		plan.Argv = argv
+		if _, err := exec.LookPath(argv[0]); err != nil {
+			// Debian-family systems often ship pip3 or only the module.
+			plan.Argv = append([]string{"python3", "-m", "pip"}, argv[1:]...)
+		}
+		if !CheckPrivileges().Elevated && !slices.Contains(argv, "--user") {
+			i := slices.Index(plan.Argv, "install") + 1
+			plan.Argv = slices.Insert(plan.Argv, i, "--user")
+			plan.BinDir = pipUserBinDir()
+		}
+
+	default:
+		return plan, fmt.Errorf("install hint %q uses unsupported package manager %q", hint, argv[0])
+	}
+
+	plan.CommandLine = buildCommandLine(plan.Argv[0], plan.Argv[1:])
+	return plan, nil
+}
+
+// goBinDir returns where `go install` puts binaries: $GOBIN, else the first
+// $GOPATH entry's bin, else ~/go/bin.
+func goBinDir() string {
+	if dir := os.Getenv("GOBIN"); dir != "" {
+		return dir
+	}
+	if gopath := filepath.SplitList(os.Getenv("GOPATH")); len(gopath) > 0 && gopath[0] != "" {
+		return filepath.Join(gopath[0], "bin")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, "go", "bin")
+}
+
+// pipUserBinDir returns where `pip install --user` puts scripts:
+// $PYTHONUSERBASE/bin, else ~/.local/bin.
+func pipUserBinDir() string {
+	if base := os.Getenv("PYTHONUSERBASE"); base != "" {
+		return filepath.Join(base, "bin")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".local", "bin")
+}
+
+// Installer runs install plans as tracked streaming runs (tool_installs) and
+// adds the install directory to the nser search path when needed.
+type Installer struct {
+	registry   *Registry
+	db         *sql.DB
+	searchPath string
+	policy     func() ElevationPolicy
+	installs   inFlight
+}
+
+// NewInstaller creates an installer for registry's tools backed by db.
+// searchPath is the search path file install directories are added to.
+// policy reports the runner's current elevation policy, used for plans that
+// need root; nil means none.
+func NewInstaller(registry *Registry, db *sql.DB, searchPath string, policy func() ElevationPolicy) *Installer {
+	if policy == nil {
+		policy = func() ElevationPolicy { return ElevateNone }
+	}
+	return &Installer{registry: registry, db: db, searchPath: searchPath, policy: policy}
+}
+
+// Plan returns the install plan for a registered tool.
+func (in *Installer) Plan(name string) (InstallPlan, error) {
+	def, err := in.registry.Get(name)
+	if err != nil {
+		return InstallPlan{}, err
+	}
+	return PlanInstall(def)
+}
+
+// Close refuses new installs so the database can be closed. It fails while
+// installs are still running.
+func (in *Installer) Close() error {
+	return in.installs.close("tool installs")
+}
+
+// Install starts installing a tool in a goroutine and returns immediately.
+// Root-only plans run through the elevation policy's sudo or pkexec, like
+// privileged tool runs. With no policy they are refused unless the process
+// is already elevated; the error carries the command so the user can run it
+// in a terminal.
+//
+// The calling context (ctx) must be the Wails app context so EventsEmit works.
+func (in *Installer) Install(ctx context.Context, name string) (*InstallStartResult, error) {
+	def, err := in.registry.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	plan, err := PlanInstall(def)
+	if err != nil {
+		return nil, err
+	}
+	var elevation string
+	if plan.NeedsRoot && !CheckPrivileges().Elevated {
+		if elevation = policyElevation(in.policy()); elevation == "" {
+			return nil, fmt.Errorf("installing %s needs root: set an elevation policy or run \"sudo %s\" in a terminal", name, plan.CommandLine)
+		}
+	}
+	path, argv, env, err := installCommand(plan, elevation)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := in.installs.begin(); err != nil {
+		return nil, err
+	}
+	res, err := in.db.ExecContext(ctx,
+		`INSERT INTO tool_installs (tool_name, manager, command_line, status, started_at)
+		 VALUES (?, ?, ?, 'running', ?)`,
+		name, plan.Manager, plan.CommandLine, time.Now(),
+	)
+	if err != nil {
+		in.installs.end()
+		return nil, fmt.Errorf("insert tool_install: %w", err)
+	}
+	installID, err := res.LastInsertId()
+	if err != nil {
+		in.installs.end()
+		return nil, err
+	}
+
+	go func() {
+		defer in.installs.end()
+		startedAt := time.Now()
+
+		execCtx, cancel := context.WithTimeout(ctx, installTimeout)
+		defer cancel()
+
+		cmd := exec.CommandContext(execCtx, path, argv...)
+		if len(env) > 0 {
+			cmd.Env = append(os.Environ(), env...)
+		}
+		killGroupOnCancel(cmd)
+		output, _, status, exitCode := streamCommand(cmd, func(line string) {
+			runtime.EventsEmit(ctx, fmt.Sprintf("tool:install:output:%d", installID), line)
+		})
+
+		result := InstallResult{
+			InstallID:   installID,
+			ToolName:    name,
+			CommandLine: plan.CommandLine,
+			Status:      status,
+			ExitCode:    exitCode,
+			Output:      output,
+			Duration:    time.Since(startedAt).Round(time.Millisecond).String(),
+		}
+		if status == "completed" {
+			in.locateBinary(context.Background(), def, plan, &result)
+		}
+
+		// Best-effort DB update — use background context in case app ctx is done.
+		in.db.ExecContext(context.Background(), //nolint:errcheck
+			`UPDATE tool_installs SET status = ?, exit_code = ?, output = ?, path = ?, added_to_path = ?, completed_at = ?
+			 WHERE id = ?`,
+			result.Status, result.ExitCode, []byte(result.Output), result.Path, result.AddedToPath, time.Now(), installID,
+		)
+		runtime.EventsEmit(ctx, fmt.Sprintf("tool:install:done:%d", installID), result)
+	}()
+
+	return &InstallStartResult{InstallID: installID, Plan: plan}, nil
+}
+
+// installCommand returns the executable, argv and extra environment that
+// run plan, wrapped for elevation ("" when it runs as is). sudo and pkexec
+// reset the environment, so an elevated apt gets its DEBIAN_FRONTEND
+// through env(1) instead.
+func installCommand(plan InstallPlan, elevation string) (string, []string, []string, error) {
+	binPath, err := exec.LookPath(plan.Argv[0])
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("%s not found in PATH: %w", plan.Argv[0], err)
+	}
+	args := plan.Argv[1:]
+	var env []string
+	if plan.Manager == ManagerApt {
+		env = []string{"DEBIAN_FRONTEND=noninteractive"}
+	}
+	if elevation != "" && len(env) > 0 {
+		envPath, err := exec.LookPath("env")
+		if err != nil {
+			return "", nil, nil, fmt.Errorf("env not found in PATH: %w", err)
+		}
+		args = append(append(env, binPath), args...)
+		binPath, env = envPath, nil
+	}
+	path, argv, err := wrapElevated(elevation, binPath, args, env)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	return path, argv, env, nil
+}
+
+// locateBinary finds def's binary after a successful install. If it landed
+// in plan.BinDir and that directory isn't in $PATH, the directory is added
+// to the nser search path.
+func (in *Installer) locateBinary(ctx context.Context, def ToolDef, plan InstallPlan, result *InstallResult) {
+	if path, err := exec.LookPath(def.Binary); err == nil {
+		result.Path = path
+		return
+	}
+	if plan.BinDir != "" && !inPath(plan.BinDir) {
+		candidate := filepath.Join(plan.BinDir, def.Binary)
+		if fi, err := os.Stat(candidate); err == nil && !fi.IsDir() && fi.Mode()&0o111 != 0 {
+			if err := AddSearchDir(in.searchPath, plan.BinDir); err != nil {
+				result.Error = err.Error()
+				return
+			}
+			result.Path = candidate
+			result.AddedToPath = plan.BinDir
+			return
+		}
+	}
+	result.Error = fmt.Sprintf("install finished but %q was not found in PATH", def.Binary)
+}
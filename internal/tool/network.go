@@ -0,0 +1,217 @@
+package tool
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// NetworkProfile routes a workspace's tool runs through proxies or a pivot.
+// The zero value means direct connections.
+type NetworkProfile struct {
+	HTTPProxy  string `json:"httpProxy"`  // HTTP_PROXY
+	HTTPSProxy string `json:"httpsProxy"` // HTTPS_PROXY
+	SOCKSProxy string `json:"socksProxy"` // ALL_PROXY, e.g. socks5://127.0.0.1:1080
+	NoProxy    string `json:"noProxy"`    // NO_PROXY
+
+	// ToolProxy is passed to tools with a ProxyFlag. Defaults to the first
+	// of HTTPSProxy, HTTPProxy and SOCKSProxy.
+	ToolProxy string `json:"toolProxy"`
+
+	// Proxychains wraps every run with proxychains4, for tools that ignore
+	// proxy env vars. Env vars and proxy flags are then left out so traffic
+	// isn't proxied twice. ProxychainsConf selects a config file; when empty
+	// one is generated from SOCKSProxy.
+	Proxychains     bool   `json:"proxychains"`
+	ProxychainsConf string `json:"proxychainsConf"`
+}
+
+// IsZero reports whether the profile leaves connections direct.
+func (p NetworkProfile) IsZero() bool {
+	return p == NetworkProfile{}
+}
+
+// Redacted returns a copy with proxy passwords replaced by "xxxxx", for
+// storing on runs and showing in command lines.
+func (p NetworkProfile) Redacted() NetworkProfile {
+	for _, v := range []*string{&p.HTTPProxy, &p.HTTPSProxy, &p.SOCKSProxy, &p.ToolProxy} {
+		if u, err := url.Parse(*v); err == nil && u.User != nil {
+			*v = u.Redacted()
+		}
+	}
+	return p
+}
+
+// proxySchemes are the URL schemes accepted for proxy settings.
+var proxySchemes = map[string]bool{"http": true, "https": true, "socks4": true, "socks5": true, "socks5h": true}
+
+// Validate checks the proxy URLs and proxychains settings.
+func (p NetworkProfile) Validate() error {
+	for _, f := range []struct{ name, value string }{
+		{"http proxy", p.HTTPProxy},
+		{"https proxy", p.HTTPSProxy},
+		{"socks proxy", p.SOCKSProxy},
+		{"tool proxy", p.ToolProxy},
+	} {
+		if f.value == "" {
+			continue
+		}
+		u, err := url.Parse(f.value)
+		if err != nil || u.Host == "" || !proxySchemes[u.Scheme] {
+			return fmt.Errorf("%s %q must be a URL like socks5://host:port", f.name, f.value)
+		}
+	}
+	if p.SOCKSProxy != "" {
+		if u, _ := url.Parse(p.SOCKSProxy); u.Scheme == "http" || u.Scheme == "https" {
+			return fmt.Errorf("socks proxy %q must use a socks scheme", p.SOCKSProxy)
+		}
+	}
+	if p.Proxychains && p.ProxychainsConf == "" && p.SOCKSProxy == "" {
+		return fmt.Errorf("proxychains needs a config file or a socks proxy")
+	}
+	return nil
+}
+
+// toolProxy returns the URL passed via ProxyFlag.
+func (p NetworkProfile) toolProxy() string {
+	for _, v := range []string{p.ToolProxy, p.HTTPSProxy, p.HTTPProxy, p.SOCKSProxy} {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// env returns the proxy environment variables, in both cases since tools
+// disagree on which they read.
+func (p NetworkProfile) env() []string {
+	var env []string
+	for _, v := range []struct{ name, value string }{
+		{"HTTP_PROXY", p.HTTPProxy},
+		{"HTTPS_PROXY", p.HTTPSProxy},
+		{"ALL_PROXY", p.SOCKSProxy},
+		{"NO_PROXY", p.NoProxy},
+	} {
+		if v.value == "" {
+			continue
+		}
+		env = append(env, v.name+"="+v.value, strings.ToLower(v.name)+"="+v.value)
+	}
+	return env
+}
+
+// proxyArgs returns def's proxy flag and value, or nil.
+func (p NetworkProfile) proxyArgs(def ToolDef) []string {
+	proxy := p.toolProxy()
+	if def.ProxyFlag == "" || proxy == "" {
+		return nil
+	}
+	if strings.HasSuffix(def.ProxyFlag, "=") {
+		return []string{def.ProxyFlag + proxy}
+	}
+	return []string{def.ProxyFlag, proxy}
+}
+
+// proxychainsWrap returns the proxychains executable and argv that run
+// path with args, and a cleanup func removing any generated config.
+func (p NetworkProfile) proxychainsWrap(path string, args []string) (string, []string, func(), error) {
+	wrapper, err := exec.LookPath("proxychains4")
+	if err != nil {
+		if wrapper, err = exec.LookPath("proxychains"); err != nil {
+			return "", nil, nil, fmt.Errorf("proxychains not found in PATH: %w", err)
+		}
+	}
+
+	conf, cleanup := p.ProxychainsConf, func() {}
+	if conf == "" {
+		if conf, err = writeProxychainsConf(p.SOCKSProxy); err != nil {
+			return "", nil, nil, err
+		}
+		cleanup = func() { os.Remove(conf) }
+	}
+	argv := append([]string{"-q", "-f", conf, path}, args...)
+	return wrapper, argv, cleanup, nil
+}
+
+// writeProxychainsConf writes a temporary single-hop proxychains config
+// for a socks proxy URL and returns its path.
+func writeProxychainsConf(socks string) (string, error) {
+	u, err := url.Parse(socks)
+	if err != nil {
+		return "", fmt.Errorf("socks proxy: %w", err)
+	}
+	kind := "socks5"
+	if u.Scheme == "socks4" {
+		kind = "socks4"
+	}
+	line := fmt.Sprintf("%s %s %s", kind, u.Hostname(), u.Port())
+	if pass, ok := u.User.Password(); ok {
+		line += " " + u.User.Username() + " " + pass
+	}
+
+	f, err := os.CreateTemp("", "nser-proxychains-*.conf")
+	if err != nil {
+		return "", fmt.Errorf("create proxychains config: %w", err)
+	}
+	defer f.Close()
+	if _, err := fmt.Fprintf(f, "strict_chain\nproxy_dns\nquiet_mode\n\n[ProxyList]\n%s\n", line); err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("write proxychains config: %w", err)
+	}
+	return f.Name(), nil
+}
+
+// LoadNetworkProfile returns a workspace's network profile; workspaces
+// without one get the zero (direct) profile.
+func LoadNetworkProfile(ctx context.Context, db *sql.DB, workspaceID int64) (NetworkProfile, error) {
+	var profileJSON string
+	err := db.QueryRowContext(ctx,
+		`SELECT profile_json FROM network_profiles WHERE workspace_id = ?`, workspaceID,
+	).Scan(&profileJSON)
+	if errors.Is(err, sql.ErrNoRows) {
+		return NetworkProfile{}, nil
+	}
+	if err != nil {
+		return NetworkProfile{}, fmt.Errorf("load network profile: %w", err)
+	}
+	var p NetworkProfile
+	if err := json.Unmarshal([]byte(profileJSON), &p); err != nil {
+		return NetworkProfile{}, fmt.Errorf("decode network profile: %w", err)
+	}
+	return p, nil
+}
+
+// SaveNetworkProfile validates and stores a workspace's network profile
+// within tx. Saving the zero profile removes it.
+func SaveNetworkProfile(ctx context.Context, tx *sql.Tx, workspaceID int64, p NetworkProfile) error {
+	if p.IsZero() {
+		_, err := tx.ExecContext(ctx, `DELETE FROM network_profiles WHERE workspace_id = ?`, workspaceID)
+		if err != nil {
+			return fmt.Errorf("clear network profile: %w", err)
+		}
+		return nil
+	}
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	profileJSON, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("encode network profile: %w", err)
+	}
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO network_profiles (workspace_id, profile_json, updated_at) VALUES (?, ?, ?)
+		 ON CONFLICT(workspace_id) DO UPDATE SET profile_json = excluded.profile_json, updated_at = excluded.updated_at`,
+		workspaceID, string(profileJSON), time.Now(),
+	)
+	if err != nil {
+		return fmt.Errorf("save network profile: %w", err)
+	}
+	return nil
+}
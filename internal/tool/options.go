@@ -0,0 +1,259 @@
+package tool
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// OptionType tells the frontend which form control to render and the backend
+// how to validate the submitted value.
+type OptionType string
+
+const (
+	OptionBool     OptionType = "bool"
+	OptionInt      OptionType = "int"
+	OptionString   OptionType = "string"
+	OptionEnum     OptionType = "enum"
+	OptionPath     OptionType = "path"
+	OptionPortList OptionType = "port-list"
+	OptionWordlist OptionType = "wordlist"
+
+	// OptionPassword is a string whose value is a secret, such as hydra's
+	// -p. It is redacted from the stored run, its command line and the audit
+	// log, and kept sealed under the secrets key for reruns (see redact.go).
+	OptionPassword OptionType = "password"
+
+	// OptionCredentials picks stored workspace credentials: "all",
+	// "validated" or a service name ("ssh"). The runner writes the matching
+	// passwords to a temp file as login:password lines (hydra's -C format)
+	// and passes its path.
+	OptionCredentials OptionType = "credentials"
+)
+
+// Option describes one structured input a tool accepts. Values arrive from
+// the frontend as strings keyed by Name and are turned into argv by
+// BuildOptionArgs.
+type Option struct {
+	// Name is the key used in the values map: "ports", "rate".
+	Name string
+
+	// Flag is emitted before the value: "-p", "--rate". A flag ending in "="
+	// is joined to its value ("--risk=3"). Bool options emit only the flag.
+	// A wordlist option with no Flag fills the {{wordlist}} placeholder.
+	Flag string
+
+	// Type selects the validation rule and form control.
+	Type OptionType
+
+	// Default is used when no value is submitted. Empty means "omit".
+	Default string
+
+	// Help is a one-line description shown next to the form control.
+	Help string
+
+	// Choices lists the allowed values for OptionEnum.
+	Choices []string
+
+	// Conflicts names options that cannot be set together with this one.
+	Conflicts []string
+
+	// NeedsRoot is true when setting this option requires elevated privileges
+	// (e.g. nmap -sS), even if the tool itself doesn't.
+	NeedsRoot bool
+}
+
+// validOptionTypes is used to validate ToolDef.Options at registration.
+var validOptionTypes = map[OptionType]bool{
+	OptionBool: true, OptionInt: true, OptionString: true, OptionEnum: true,
+	OptionPath: true, OptionPortList: true, OptionWordlist: true, OptionCredentials: true,
+	OptionPassword: true,
+}
+
+// validateOptions checks an option schema for duplicates, unknown types,
+// invalid defaults and dangling conflict references.
+func validateOptions(opts []Option) error {
+	names := make(map[string]bool, len(opts))
+	for _, o := range opts {
+		if o.Name == "" {
+			return fmt.Errorf("option has no name")
+		}
+		if names[o.Name] {
+			return fmt.Errorf("duplicate option %q", o.Name)
+		}
+		names[o.Name] = true
+		if !validOptionTypes[o.Type] {
+			return fmt.Errorf("option %q: unknown type %q", o.Name, o.Type)
+		}
+		if o.Flag == "" && o.Type != OptionWordlist {
+			return fmt.Errorf("option %q has no flag", o.Name)
+		}
+		if o.Type == OptionEnum && len(o.Choices) == 0 {
+			return fmt.Errorf("option %q: enum has no choices", o.Name)
+		}
+		if err := validateOptionDefault(o); err != nil {
+			return err
+		}
+	}
+	for _, o := range opts {
+		for _, c := range o.Conflicts {
+			if !names[c] {
+				return fmt.Errorf("option %q conflicts with unknown option %q", o.Name, c)
+			}
+		}
+	}
+	return nil
+}
+
+// validateOptionDefault checks o.Default against o's type and choices, so a
+// bad default fails at registration instead of on every run that omits the
+// option. Wordlist defaults aren't checked for existence: the file may only
+// be installed later. Passwords can't have defaults, since they would sit in
+// the tool definition in the clear.
+func validateOptionDefault(o Option) error {
+	switch {
+	case o.Default == "":
+		return nil
+	case o.Type == OptionPassword:
+		return fmt.Errorf("option %q: password options can't have a default", o.Name)
+	case o.Type == OptionWordlist:
+		if strings.ContainsRune(o.Default, 0) {
+			return fmt.Errorf("option %q: invalid default wordlist path", o.Name)
+		}
+		return nil
+	}
+	if err := validateOptionValue(o, o.Default); err != nil {
+		return fmt.Errorf("default: %w", err)
+	}
+	return nil
+}
+
+// validateOptionValue checks a single submitted value against its option type.
+func validateOptionValue(o Option, v string) error {
+	switch o.Type {
+	case OptionBool:
+		if _, err := strconv.ParseBool(v); err != nil {
+			return fmt.Errorf("option %q: %q is not a boolean", o.Name, v)
+		}
+	case OptionInt:
+		if _, err := strconv.Atoi(v); err != nil {
+			return fmt.Errorf("option %q: %q is not an integer", o.Name, v)
+		}
+	case OptionEnum:
+		for _, c := range o.Choices {
+			if v == c {
+				return nil
+			}
+		}
+		return fmt.Errorf("option %q: %q is not one of %s", o.Name, v, strings.Join(o.Choices, ", "))
+	case OptionPath:
+		if strings.ContainsRune(v, 0) {
+			return fmt.Errorf("option %q: invalid path", o.Name)
+		}
+	case OptionWordlist:
+		if fi, err := os.Stat(v); err != nil || fi.IsDir() {
+			return fmt.Errorf("option %q: wordlist %q not found", o.Name, v)
+		}
+	case OptionPortList:
+		return validatePortList(o.Name, v)
+	case OptionCredentials:
+		if strings.ContainsAny(v, "\x00\n") {
+			return fmt.Errorf("option %q: invalid credential set %q", o.Name, v)
+		}
+	}
+	return nil
+}
+
+// validatePortList accepts "-" (all ports) or comma-separated ports and
+// ranges: "22,80,8000-8100".
+func validatePortList(name, v string) error {
+	if v == "-" {
+		return nil
+	}
+	for _, part := range strings.Split(v, ",") {
+		lo, hi, isRange := strings.Cut(strings.TrimSpace(part), "-")
+		if !isRange {
+			hi = lo
+		}
+		a, errA := strconv.Atoi(lo)
+		b, errB := strconv.Atoi(hi)
+		if errA != nil || errB != nil || a < 0 || b > 65535 || a > b {
+			return fmt.Errorf("option %q: invalid port or range %q", name, part)
+		}
+	}
+	return nil
+}
+
+// resolveOptionValues validates values against the schema and fills in
+// defaults. The result only contains options that will be emitted.
+func resolveOptionValues(opts []Option, values map[string]string) (map[string]string, error) {
+	byName := make(map[string]Option, len(opts))
+	for _, o := range opts {
+		byName[o.Name] = o
+	}
+	for name := range values {
+		if _, ok := byName[name]; !ok {
+			return nil, fmt.Errorf("unknown option %q", name)
+		}
+	}
+
+	resolved := make(map[string]string)
+	for _, o := range opts {
+		v, ok := values[o.Name]
+		if !ok {
+			v = o.Default
+		}
+		if v == "" {
+			continue
+		}
+		if err := validateOptionValue(o, v); err != nil {
+			return nil, err
+		}
+		if o.Type == OptionBool {
+			if b, _ := strconv.ParseBool(v); !b {
+				continue
+			}
+		}
+		resolved[o.Name] = v
+	}
+
+	for _, o := range opts {
+		if _, set := resolved[o.Name]; !set {
+			continue
+		}
+		for _, c := range o.Conflicts {
+			if _, other := resolved[c]; other {
+				return nil, fmt.Errorf("options %q and %q cannot be used together", o.Name, c)
+			}
+		}
+	}
+	return resolved, nil
+}
+
+// BuildOptionArgs validates values against def.Options and returns the argv
+// fragment they produce, in schema order. The wordlist return value is set
+// when a flagless wordlist option should fill {{wordlist}}.
+func BuildOptionArgs(def ToolDef, values map[string]string) (args []string, wordlist string, err error) {
+	resolved, err := resolveOptionValues(def.Options, values)
+	if err != nil {
+		return nil, "", fmt.Errorf("tool %q: %w", def.Name, err)
+	}
+	for _, o := range def.Options {
+		v, ok := resolved[o.Name]
+		if !ok {
+			continue
+		}
+		switch {
+		case o.Type == OptionWordlist && o.Flag == "":
+			wordlist = v
+		case o.Type == OptionBool:
+			args = append(args, o.Flag)
+		case strings.HasSuffix(o.Flag, "="):
+			args = append(args, o.Flag+v)
+		default:
+			args = append(args, o.Flag, v)
+		}
+	}
+	return args, wordlist, nil
+}
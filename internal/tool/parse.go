@@ -0,0 +1,224 @@
+package tool
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"nser/internal/parse"
+)
+
+// parseFilePlaceholder in Parser.Args is replaced with a per-run temp file.
+const parseFilePlaceholder = "{{file}}"
+
+// Parser turns a tool's output into structured results.
+type Parser struct {
+	// Args are appended to the argv so the tool also writes machine-readable
+	// output to a file, e.g. ["-oX", "{{file}}"] for nmap; the normal output
+	// still streams to the user. When empty, Parse reads the run's stdout.
+	Args []string
+
+	// Parse reads the file written via Args, or stdout.
+	Parse parse.Func
+}
+
+// parseArgs creates the temp file for def's Parser.Args and returns the
+// args to append and the file path ("" when the tool parses stdout). A
+// preview creates no file and leaves the placeholder in the args.
+func parseArgs(def ToolDef, preview bool) ([]string, string, error) {
+	if def.Parser == nil || len(def.Parser.Args) == 0 {
+		return nil, "", nil
+	}
+	if preview {
+		return def.Parser.Args, "", nil
+	}
+	f, err := os.CreateTemp("", "nser-"+def.Name+"-*.out")
+	if err != nil {
+		return nil, "", fmt.Errorf("create parse file: %w", err)
+	}
+	f.Close()
+	args := make([]string, len(def.Parser.Args))
+	for i, a := range def.Parser.Args {
+		args[i] = strings.ReplaceAll(a, parseFilePlaceholder, f.Name())
+	}
+	return args, f.Name(), nil
+}
+
+// parseRun runs def's parser over a finished run, stores the result as the
+// run's parsed_json and merges it into the workspace inventory. Parse
+// errors are returned for display; the run itself still counts as done.
+func (r *Runner) parseRun(ctx context.Context, p preparedRun, workspaceID, runID int64, stdout string) error {
+	if p.def.Parser == nil {
+		return nil
+	}
+	data := []byte(stdout)
+	if p.parseFile != "" {
+		var err error
+		if data, err = os.ReadFile(p.parseFile); err != nil {
+			return fmt.Errorf("read parse file: %w", err)
+		}
+		if len(data) == 0 {
+			return nil // the tool failed before writing anything
+		}
+	}
+	result, err := p.def.Parser.Parse(data)
+	if err != nil {
+		return err
+	}
+
+	parsedJSON, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("encode parse result: %w", err)
+	}
+	sealed, err := r.seal(parsedJSON)
+	if err != nil {
+		return fmt.Errorf("seal parse result: %w", err)
+	}
+	if _, err := r.db.ExecContext(ctx,
+		`UPDATE tool_runs SET parsed_json = ? WHERE id = ?`, sealed, runID,
+	); err != nil {
+		return fmt.Errorf("store parse result: %w", err)
+	}
+	return ingest(ctx, r.db, workspaceID, runID, result, r.seal)
+}
+
+// ingest merges parsed hosts into assets and ports, and credentials into
+// the credential store, in one transaction. Fields a run didn't report
+// (e.g. a masscan banner after an nmap -sV scan) keep their earlier values;
+// run_id points at the latest run to see the port. Credential secrets are
+// stored through seal.
+func ingest(ctx context.Context, db *sql.DB, workspaceID, runID int64, result *parse.Result, seal func([]byte) ([]byte, error)) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin ingest: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	now := time.Now()
+	for _, h := range result.Hosts {
+		if net.ParseIP(h.Address) == nil {
+			continue
+		}
+		assetID, err := upsertAsset(ctx, tx, workspaceID, "ip", h.Address)
+		if err != nil {
+			return err
+		}
+		for _, name := range h.Hostnames {
+			if _, err := upsertAsset(ctx, tx, workspaceID, "domain", name); err != nil {
+				return err
+			}
+		}
+		for _, s := range h.Services {
+			if _, err := tx.ExecContext(ctx,
+				`INSERT INTO ports (asset_id, port, protocol, service, state, product, version, extra_info, cpe, banner, tls, run_id, updated_at)
+				 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+				 ON CONFLICT(asset_id, port, protocol) DO UPDATE SET
+				   state      = excluded.state,
+				   service    = COALESCE(NULLIF(excluded.service, ''), service),
+				   product    = COALESCE(NULLIF(excluded.product, ''), product),
+				   version    = COALESCE(NULLIF(excluded.version, ''), version),
+				   extra_info = COALESCE(NULLIF(excluded.extra_info, ''), extra_info),
+				   cpe        = COALESCE(NULLIF(excluded.cpe, ''), cpe),
+				   banner     = COALESCE(NULLIF(excluded.banner, ''), banner),
+				   tls        = MAX(excluded.tls, tls),
+				   run_id     = excluded.run_id,
+				   updated_at = excluded.updated_at`,
+				assetID, s.Port, s.Protocol, s.Name, s.State, s.Product, s.Version, s.ExtraInfo, s.CPE, s.Banner, s.TLS, runID, now,
+			); err != nil {
+				return fmt.Errorf("store port %s:%d: %w", h.Address, s.Port, err)
+			}
+		}
+	}
+	if len(result.Credentials) > 0 {
+		if err := ingestCredentials(ctx, tx, workspaceID, runID, result.Credentials, seal); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ingestCredentials upserts credentials keyed on where they apply, who
+// they are for and their type; a later run's secret replaces an earlier
+// one. Credentials without a host belong to the run's target. Validation
+// is sticky: once a tool has logged in with a credential it stays
+// validated.
+func ingestCredentials(ctx context.Context, tx *sql.Tx, workspaceID, runID int64, creds []parse.Credential, seal func([]byte) ([]byte, error)) error {
+	var target string
+	if err := tx.QueryRowContext(ctx, `SELECT target FROM tool_runs WHERE id = ?`, runID).Scan(&target); err != nil {
+		return fmt.Errorf("load run target: %w", err)
+	}
+	now := time.Now()
+	for _, c := range creds {
+		host := c.Host
+		if host == "" {
+			host = targetHost(target)
+		}
+		var assetID sql.NullInt64
+		if host != "" {
+			kind := "domain"
+			if net.ParseIP(host) != nil {
+				kind = "ip"
+			}
+			id, err := upsertAsset(ctx, tx, workspaceID, kind, host)
+			if err != nil {
+				return err
+			}
+			assetID = sql.NullInt64{Int64: id, Valid: true}
+		}
+		secret, err := seal([]byte(c.Secret))
+		if err != nil {
+			return fmt.Errorf("seal credential: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO credentials (workspace_id, asset_id, host, port, service, realm, username, secret, type, validated, run_id, created_at, updated_at)
+			 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			 ON CONFLICT(workspace_id, host, port, service, realm, username, type) DO UPDATE SET
+			   asset_id   = excluded.asset_id,
+			   secret     = excluded.secret,
+			   validated  = MAX(excluded.validated, validated),
+			   run_id     = excluded.run_id,
+			   updated_at = excluded.updated_at`,
+			workspaceID, assetID, host, c.Port, c.Service, c.Realm, c.Username, secret, c.Type, c.Validated, runID, now, now,
+		); err != nil {
+			return fmt.Errorf("store credential for %s: %w", c.Username, err)
+		}
+	}
+	return nil
+}
+
+// targetHost returns the host of a run target: a URL, host:port or a bare
+// host or address.
+func targetHost(target string) string {
+	if u, err := url.Parse(target); err == nil && u.Host != "" {
+		return u.Hostname()
+	}
+	if host, _, err := net.SplitHostPort(target); err == nil {
+		return host
+	}
+	return target
+}
+
+// upsertAsset returns the ID of a workspace asset, creating it if needed.
+func upsertAsset(ctx context.Context, tx *sql.Tx, workspaceID int64, kind, value string) (int64, error) {
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO assets (workspace_id, type, value) VALUES (?, ?, ?)
+		 ON CONFLICT(workspace_id, type, value) DO NOTHING`,
+		workspaceID, kind, value,
+	); err != nil {
+		return 0, fmt.Errorf("store asset %s: %w", value, err)
+	}
+	var id int64
+	err := tx.QueryRowContext(ctx,
+		`SELECT id FROM assets WHERE workspace_id = ? AND type = ? AND value = ?`, workspaceID, kind, value,
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("load asset %s: %w", value, err)
+	}
+	return id, nil
+}
@@ -0,0 +1,70 @@
+package tool
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// pathDebounce coalesces the burst of events a package install produces.
+const pathDebounce = time.Second
+
+// pathExtended is signalled by ExtendPath so WatchPath also watches the
+// search directories added after it started.
+var pathExtended = make(chan struct{}, 1)
+
+// WatchPath watches every directory in $PATH, the nser search path included,
+// and calls onChange (debounced) when a file is created, removed, renamed or
+// has its mode changed — so a newly installed tool shows up without
+// restarting. Blocks until ctx is done.
+func WatchPath(ctx context.Context, onChange func()) error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	watched := make(map[string]bool)
+	watchDirs := func() {
+		for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+			if watched[dir] {
+				continue
+			}
+			if fi, err := os.Stat(dir); err == nil && fi.IsDir() && w.Add(dir) == nil {
+				watched[dir] = true // unreadable dirs are skipped
+			}
+		}
+	}
+	watchDirs()
+
+	var timer *time.Timer
+	for {
+		select {
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			return nil
+		case <-pathExtended:
+			watchDirs()
+		case ev, ok := <-w.Events:
+			if !ok {
+				return nil
+			}
+			if ev.Op&(fsnotify.Create|fsnotify.Remove|fsnotify.Rename|fsnotify.Chmod) == 0 {
+				continue
+			}
+			if timer != nil {
+				timer.Stop()
+			}
+			timer = time.AfterFunc(pathDebounce, onChange)
+		case _, ok := <-w.Errors:
+			if !ok {
+				return nil
+			}
+		}
+	}
+}
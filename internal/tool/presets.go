@@ -0,0 +1,243 @@
+package tool
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Preset is a named set of options and args for one tool ("nmap full TCP").
+// WorkspaceID 0 means the preset is available in every workspace.
+type Preset struct {
+	ID          int64             `json:"id"`
+	WorkspaceID int64             `json:"workspaceId"`
+	ToolName    string            `json:"toolName"`
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Options     map[string]string `json:"options"`
+	RawArgs     []string          `json:"rawArgs"`
+	CreatedAt   string            `json:"createdAt"`
+	UpdatedAt   string            `json:"updatedAt"`
+}
+
+// Request returns the run of p against target in a workspace.
+func (p Preset) Request(workspaceID int64, target string) RunRequest {
+	return RunRequest{
+		WorkspaceID: workspaceID,
+		ToolName:    p.ToolName,
+		Target:      target,
+		Options:     p.Options,
+		RawArgs:     p.RawArgs,
+	}
+}
+
+const presetColumns = `id, COALESCE(workspace_id, 0), tool_name, name, COALESCE(description,''),
+	COALESCE(options_json,''), COALESCE(args_json,''), created_at, updated_at`
+
+// scanPreset scans a row selected with presetColumns, opening the sealed
+// options.
+func (r *Runner) scanPreset(row interface{ Scan(...any) error }) (Preset, error) {
+	var p Preset
+	var options []byte
+	var argsJSON string
+	if err := row.Scan(&p.ID, &p.WorkspaceID, &p.ToolName, &p.Name, &p.Description,
+		&options, &argsJSON, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		return p, err
+	}
+	optionsJSON, err := r.open(options)
+	if err != nil {
+		return p, err
+	}
+	p.RawArgs, p.Options, err = DecodeRunArgs("", argsJSON, string(optionsJSON))
+	return p, err
+}
+
+// GetPreset returns a preset by ID.
+func (r *Runner) GetPreset(ctx context.Context, id int64) (Preset, error) {
+	p, err := r.scanPreset(r.db.QueryRowContext(ctx, `SELECT `+presetColumns+` FROM run_presets WHERE id = ?`, id))
+	if err != nil {
+		return Preset{}, fmt.Errorf("get preset: %w", err)
+	}
+	return p, nil
+}
+
+// ListPresets returns the presets usable in a workspace: its own plus the
+// global ones. An empty toolName returns presets for every tool.
+func (r *Runner) ListPresets(ctx context.Context, workspaceID int64, toolName string) ([]Preset, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+presetColumns+` FROM run_presets
+		 WHERE (workspace_id IS NULL OR workspace_id = ?) AND (? = '' OR tool_name = ?)
+		 ORDER BY tool_name, name`,
+		workspaceID, toolName, toolName,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list presets: %w", err)
+	}
+	defer rows.Close()
+
+	var result []Preset
+	for rows.Next() {
+		p, err := r.scanPreset(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan preset: %w", err)
+		}
+		result = append(result, p)
+	}
+	return result, rows.Err()
+}
+
+// SavePreset validates p against its tool and stores it. ID 0 inserts a new
+// preset, otherwise the existing one is updated.
+func (r *Runner) SavePreset(ctx context.Context, p Preset) (Preset, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Preset{}, fmt.Errorf("save preset: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if p.ID, err = r.savePreset(ctx, tx, p); err != nil {
+		return Preset{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return Preset{}, fmt.Errorf("save preset: %w", err)
+	}
+	return r.GetPreset(ctx, p.ID)
+}
+
+// savePreset validates and stores p in tx, returning its ID. Presets are
+// shared, exported and cloned, so password values are refused rather than
+// stored: they are entered when the preset is run.
+func (r *Runner) savePreset(ctx context.Context, tx *sql.Tx, p Preset) (int64, error) {
+	def, err := r.registry.Get(p.ToolName)
+	if err != nil {
+		return 0, err
+	}
+	if strings.TrimSpace(p.Name) == "" {
+		return 0, fmt.Errorf("preset name is required")
+	}
+	if _, _, err := BuildOptionArgs(def, p.Options); err != nil {
+		return 0, err
+	}
+	if _, s := redactRequest(def, p.Request(0, "")); !s.empty() {
+		return 0, fmt.Errorf("preset %q: passwords can't be saved in a preset, enter them when running it", p.Name)
+	}
+
+	optionsJSON, err := json.Marshal(p.Options)
+	if err != nil {
+		return 0, fmt.Errorf("encode preset options: %w", err)
+	}
+	options, err := r.seal(optionsJSON)
+	if err != nil {
+		return 0, fmt.Errorf("seal preset options: %w", err)
+	}
+	argsJSON, err := json.Marshal(p.RawArgs)
+	if err != nil {
+		return 0, fmt.Errorf("encode preset args: %w", err)
+	}
+	workspace := sql.NullInt64{Int64: p.WorkspaceID, Valid: p.WorkspaceID != 0}
+
+	if p.ID == 0 {
+		res, err := tx.ExecContext(ctx,
+			`INSERT INTO run_presets (workspace_id, tool_name, name, description, options_json, args_json)
+			 VALUES (?, ?, ?, ?, ?, ?)`,
+			workspace, p.ToolName, p.Name, p.Description, options, string(argsJSON),
+		)
+		if err != nil {
+			return 0, fmt.Errorf("create preset: %w", err)
+		}
+		return res.LastInsertId()
+	}
+	res, err := tx.ExecContext(ctx,
+		`UPDATE run_presets SET workspace_id = ?, tool_name = ?, name = ?, description = ?,
+		        options_json = ?, args_json = ?, updated_at = CURRENT_TIMESTAMP
+		 WHERE id = ?`,
+		workspace, p.ToolName, p.Name, p.Description, options, string(argsJSON), p.ID,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("update preset: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return 0, fmt.Errorf("preset %d not found", p.ID)
+	}
+	return p.ID, nil
+}
+
+// WithoutPasswords returns p with any password values left from before
+// presets refused them removed: password options dropped, and password
+// flags dropped from RawArgs with their values. Used for exports.
+func (p Preset) WithoutPasswords(registry *Registry) Preset {
+	def, err := registry.Get(p.ToolName)
+	if err != nil {
+		return p
+	}
+	options := make(map[string]string, len(p.Options))
+	for k, v := range p.Options {
+		options[k] = v
+	}
+	for _, o := range def.Options {
+		if o.Type == OptionPassword {
+			delete(options, o.Name)
+		}
+	}
+	p.Options = options
+
+	redacted := redactArgv(def, p.RawArgs)
+	var args []string
+	for i, arg := range redacted {
+		switch {
+		case arg != p.RawArgs[i]:
+			// "--pass=secret", or the value after "-p": drop the flag too.
+			if !strings.Contains(arg, "=") && len(args) > 0 {
+				args = args[:len(args)-1]
+			}
+		default:
+			args = append(args, arg)
+		}
+	}
+	p.RawArgs = args
+	return p
+}
+
+// DeletePreset removes a preset.
+func DeletePreset(ctx context.Context, db *sql.DB, id int64) error {
+	if _, err := db.ExecContext(ctx, `DELETE FROM run_presets WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("delete preset: %w", err)
+	}
+	return nil
+}
+
+// ImportPresets stores exported presets, all or none. Presets keep their
+// global/workspace scope: workspace-scoped ones are attached to workspaceID.
+// An existing preset with the same scope, tool and name is replaced.
+// Returns the number of presets imported.
+func (r *Runner) ImportPresets(ctx context.Context, workspaceID int64, presets []Preset) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("import presets: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	for _, p := range presets {
+		if p.WorkspaceID != 0 {
+			p.WorkspaceID = workspaceID
+		}
+		workspace := sql.NullInt64{Int64: p.WorkspaceID, Valid: p.WorkspaceID != 0}
+		p.ID = 0
+		err := tx.QueryRowContext(ctx,
+			`SELECT id FROM run_presets WHERE workspace_id IS ? AND tool_name = ? AND name = ?`,
+			workspace, p.ToolName, p.Name,
+		).Scan(&p.ID)
+		if err != nil && err != sql.ErrNoRows {
+			return 0, fmt.Errorf("look up preset %q: %w", p.Name, err)
+		}
+		if _, err := r.savePreset(ctx, tx, p); err != nil {
+			return 0, fmt.Errorf("import preset %q: %w", p.Name, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("import presets: %w", err)
+	}
+	return len(presets), nil
+}
@@ -8,7 +8,7 @@ import (
 )
 
 // CheckPrivileges reports whether the current process is running with
-// elevated privileges (root on Unix).
+// elevated privileges (root on Unix) and, on Linux, its capability sets.
 func CheckPrivileges() PrivilegeInfo {
 	info := PrivilegeInfo{OS: "unix"}
 
@@ -17,6 +17,7 @@ func CheckPrivileges() PrivilegeInfo {
 	}
 
 	info.Elevated = os.Getuid() == 0
+	info.Capabilities, info.AmbientCaps, _ = processCapabilities()
 
 	return info
 }
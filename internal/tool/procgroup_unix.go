@@ -0,0 +1,29 @@
+//go:build !windows
+
+package tool
+
+import (
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// killDelay is how long a cancelled or timed-out run gets to exit after
+// SIGTERM before its process group is killed.
+const killDelay = 5 * time.Second
+
+// killGroupOnCancel starts cmd in its own process group and, when its
+// context ends, signals the whole group rather than just cmd: SIGTERM, then
+// SIGKILL after killDelay. sudo relays the SIGTERM to the root child, which
+// nser isn't allowed to signal itself; pkexec replaces itself with the
+// root process, so for pkexec runs only the tool's own handling of SIGTERM
+// applies.
+func killGroupOnCancel(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		pgid := cmd.Process.Pid
+		time.AfterFunc(killDelay, func() { syscall.Kill(-pgid, syscall.SIGKILL) }) //nolint:errcheck
+		return syscall.Kill(-pgid, syscall.SIGTERM)
+	}
+	cmd.WaitDelay = killDelay + time.Second
+}
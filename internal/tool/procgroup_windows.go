@@ -0,0 +1,9 @@
+//go:build windows
+
+package tool
+
+import "os/exec"
+
+// killGroupOnCancel leaves cmd as is: on Windows the context's kill ends
+// the process, and runs are never wrapped by sudo or pkexec.
+func killGroupOnCancel(cmd *exec.Cmd) {}
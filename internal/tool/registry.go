@@ -2,6 +2,7 @@ package tool
 
 import (
 	"fmt"
+	"slices"
 	"sync"
 )
 
@@ -33,6 +34,15 @@ type ToolDef struct {
 	// NeedsRoot is true if the tool requires elevated privileges (e.g. nmap SYN scan).
 	NeedsRoot bool
 
+	// Capabilities are the Linux file capabilities that let the tool do its
+	// root-only work without root (default: cap_net_raw). When the binary
+	// has them (setcap ...+eip) the runner doesn't elevate.
+	Capabilities []string
+
+	// CapabilityArgs are prepended when the tool runs on file capabilities
+	// instead of root — nmap needs "--privileged" to trust them.
+	CapabilityArgs []string
+
 	// InstallHint maps OS identifiers to install commands shown in the health dashboard.
 	// Keys: "linux", "darwin", "windows".
 	InstallHint map[string]string
@@ -40,15 +50,104 @@ type ToolDef struct {
 	// VersionFlag is the CLI flag to retrieve the tool version: "--version", "-V", etc.
 	VersionFlag string
 
+	// VersionRegex extracts the version from the VersionFlag output; its
+	// first capture group is the version. Defaults to the first dotted number.
+	VersionRegex string
+
+	// VersionFunc, when set, replaces VersionRegex for tools whose output
+	// needs custom parsing. Not available to user-defined tools.
+	VersionFunc func(output string) string `json:"-"`
+
+	// MinVersion and MaxVersion bound the supported versions: MinVersion is
+	// inclusive, MaxVersion exclusive ("3.0.0" up to "4.0.0" for nuclei v3).
+	// Either may be empty. CheckAll reports versions outside the range.
+	MinVersion string
+	MaxVersion string
+
 	// Description is a one-line summary shown in the tool picker.
 	Description string
+
+	// ArgTemplate declares where inputs go in the argv, after DefaultArgs.
+	// Supports {{target}}, {{host}}, {{port}}, {{scheme}}, {{url}},
+	// {{wordlist}}, {{outdir}} and {{args}} (see template.go).
+	// Example: ["-u", "{{url}}/FUZZ", "-w", "{{wordlist}}", "{{args}}"] for ffuf.
+	// When empty, the target is appended after the user's args.
+	ArgTemplate []string
+
+	// DefaultWordlist is substituted for {{wordlist}} when the run doesn't
+	// supply one.
+	DefaultWordlist string
+
+	// Env sets environment variables for every run, typically API keys
+	// read from the secrets store: {"PDCP_API_KEY": "{{secret:pdcp_api_key}}"}.
+	// Variables whose secret isn't set are left out.
+	Env map[string]string
+
+	// ProxyFlag passes the workspace proxy to tools that take it as a flag:
+	// "-x" for ffuf, "--proxy=" for sqlmap. A flag ending in "=" is joined
+	// to its value, as with Option.Flag.
+	ProxyFlag string
+
+	// Options declares the structured inputs the frontend renders as a form.
+	// Their argv is inserted where user args go; raw args follow them.
+	Options []Option
+
+	// Parser extracts hosts and services from the run's output into the
+	// workspace inventory (see parse.go). Not available to user-defined tools.
+	Parser *Parser `json:"-"`
+
+	// Source is empty for built-in tools. User-defined tools carry the file
+	// path they were loaded from, or "db" when saved from the UI.
+	Source string
+}
+
+// Validate checks a definition for errors that would make it unrunnable.
+func (d ToolDef) Validate() error {
+	if d.Name == "" {
+		return fmt.Errorf("tool has no name")
+	}
+	if d.Binary == "" {
+		return fmt.Errorf("tool %q has no binary", d.Name)
+	}
+	switch d.Category {
+	case CategoryRecon, CategoryScanning, CategoryExploit:
+	default:
+		return fmt.Errorf("tool %q: unknown category %q", d.Name, d.Category)
+	}
+	if err := ValidateArgTemplate(d.ArgTemplate); err != nil {
+		return fmt.Errorf("tool %q: arg template: %w", d.Name, err)
+	}
+	if err := validateOptions(d.Options); err != nil {
+		return fmt.Errorf("tool %q: options: %w", d.Name, err)
+	}
+	if err := validateVersionRules(d); err != nil {
+		return fmt.Errorf("tool %q: %w", d.Name, err)
+	}
+	if err := validateEnv(d.Env); err != nil {
+		return fmt.Errorf("tool %q: %w", d.Name, err)
+	}
+	for _, c := range d.Capabilities {
+		if !slices.Contains(capNames[:], c) {
+			return fmt.Errorf("tool %q: unknown capability %q", d.Name, c)
+		}
+	}
+	return nil
 }
 
 // Registry holds all known tool definitions. Tools register themselves via
 // Register() — typically called from init() functions in the defs/ package.
 type Registry struct {
-	mu    sync.RWMutex
-	tools map[string]ToolDef
+	mu       sync.RWMutex
+	tools    map[string]ToolDef
+	problems []LoadProblem
+}
+
+// LoadProblem records a user-defined tool that could not be registered.
+// Problems are surfaced by CheckAll instead of stopping the app.
+type LoadProblem struct {
+	Name   string `json:"name"`
+	Source string `json:"source"`
+	Error  string `json:"error"`
 }
 
 // NewRegistry creates an empty tool registry.
@@ -57,15 +156,101 @@ func NewRegistry() *Registry {
 }
 
 // Register adds a tool definition to the registry. Panics on duplicate names
-// (this is a programming error, caught at startup).
+// or invalid definitions (these are programming errors, caught at startup).
 func (r *Registry) Register(def ToolDef) {
+	if err := r.TryRegister(def); err != nil {
+		panic(err.Error())
+	}
+}
+
+// TryRegister adds a tool definition, returning an error on duplicate names
+// or invalid definitions. Used for user-defined tools.
+func (r *Registry) TryRegister(def ToolDef) error {
+	if err := def.Validate(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, exists := r.tools[def.Name]; exists {
+		return fmt.Errorf("tool %q already registered%s", def.Name, describeSource(existing.Source))
+	}
+	r.tools[def.Name] = def
+	return nil
+}
+
+// Replace registers def, overwriting an existing user-defined tool of the
+// same name. Built-in tools cannot be replaced.
+func (r *Registry) Replace(def ToolDef) error {
+	if err := def.Validate(); err != nil {
+		return err
+	}
+
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if _, exists := r.tools[def.Name]; exists {
-		panic(fmt.Sprintf("tool %q already registered", def.Name))
+	if existing, exists := r.tools[def.Name]; exists && existing.Source == "" {
+		return fmt.Errorf("tool %q is built-in and cannot be replaced", def.Name)
 	}
 	r.tools[def.Name] = def
+	return nil
+}
+
+// Unregister removes a user-defined tool. Built-in tools cannot be removed.
+func (r *Registry) Unregister(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	def, ok := r.tools[name]
+	if !ok {
+		return fmt.Errorf("unknown tool: %q", name)
+	}
+	if def.Source == "" {
+		return fmt.Errorf("tool %q is built-in and cannot be removed", name)
+	}
+	delete(r.tools, name)
+	return nil
+}
+
+// ReportProblem records a user-defined tool that failed to load.
+func (r *Registry) ReportProblem(name, source string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.problems = append(r.problems, LoadProblem{Name: name, Source: source, Error: err.Error()})
+}
+
+// ClearProblems drops the load problems recorded for source, or only those
+// for the named tools when names are given.
+func (r *Registry) ClearProblems(source string, names ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	kept := r.problems[:0]
+	for _, p := range r.problems {
+		if p.Source == source && (len(names) == 0 || slices.Contains(names, p.Name)) {
+			continue
+		}
+		kept = append(kept, p)
+	}
+	r.problems = kept
+}
+
+// Problems returns the load problems recorded so far.
+func (r *Registry) Problems() []LoadProblem {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]LoadProblem(nil), r.problems...)
+}
+
+// describeSource formats a ToolDef.Source for error messages.
+func describeSource(source string) string {
+	if source == "" {
+		return " (built-in)"
+	}
+	return " (from " + source + ")"
 }
 
 // Get returns a tool definition by name, or an error if not found.
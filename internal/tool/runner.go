@@ -5,9 +5,13 @@ import (
 	"bytes"
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
 	"os/exec"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/wailsapp/wails/v2/pkg/runtime"
@@ -23,6 +27,9 @@ type RunResult struct {
 	Output      string `json:"output"`
 	Duration    string `json:"duration"`
 	ExitCode    int    `json:"exitCode"`
+	Elevation   string `json:"elevation"`
+	ParseError  string `json:"parseError"` // set when the output couldn't be parsed
+	Warning     string `json:"warning"`    // e.g. started outside the test window
 }
 
 // StreamStartResult is returned immediately when a streaming run begins.
@@ -33,17 +40,189 @@ type RunResult struct {
 type StreamStartResult struct {
 	RunID       int64  `json:"runId"`
 	CommandLine string `json:"commandLine"`
+	Elevation   string `json:"elevation"`
+	Warning     string `json:"warning"` // e.g. started outside the test window
+}
+
+// RunRequest describes a single tool invocation.
+type RunRequest struct {
+	WorkspaceID int64  `json:"workspaceId"`
+	ToolName    string `json:"toolName"`
+	Target      string `json:"target"`
+
+	// Options holds structured values validated against ToolDef.Options.
+	Options map[string]string `json:"options"`
+
+	// RawArgs are passed through unvalidated after the option args — the
+	// escape hatch for flags the schema doesn't cover.
+	RawArgs []string `json:"rawArgs"`
+
+	// ArgTemplate, when set, replaces the tool's DefaultArgs and ArgTemplate
+	// for this run — used to launch documented examples (see ExampleTemplate).
+	ArgTemplate []string `json:"argTemplate"`
 }
 
 // Runner executes tools as subprocesses and stores results in the database.
 type Runner struct {
 	registry *Registry
 	db       *sql.DB
+
+	mu      sync.RWMutex
+	policy  ElevationPolicy
+	secrets SecretStore
+	sealer  Sealer
+	auditor Auditor
+	active  map[int64]int // runs in progress per workspace
+
+	runs inFlight
+}
+
+// Sealer encrypts sensitive columns before they are stored. Implemented by
+// vault.Vault.
+type Sealer interface {
+	Seal(plaintext []byte) ([]byte, error)
+	Open(data []byte) ([]byte, error)
+	Ready() error // non-nil while values can't be sealed (vault locked)
 }
 
 // NewRunner creates a runner backed by the given registry and database.
+// Privileged tools run unelevated until SetElevationPolicy is called.
 func NewRunner(registry *Registry, db *sql.DB) *Runner {
-	return &Runner{registry: registry, db: db}
+	return &Runner{registry: registry, db: db, policy: ElevateNone}
+}
+
+// SetElevationPolicy sets how runs that need root are elevated.
+func (r *Runner) SetElevationPolicy(p ElevationPolicy) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.policy = p
+}
+
+// SetSecrets sets the store used for {{secret:name}} env references and
+// output masking. Without one, secret-backed variables are left out.
+func (r *Runner) SetSecrets(s SecretStore) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.secrets = s
+}
+
+// secretStore returns the current secret store, or nil.
+func (r *Runner) secretStore() SecretStore {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.secrets
+}
+
+// SetSealer sets how run output is encrypted at rest. Without one it is
+// stored as is.
+func (r *Runner) SetSealer(s Sealer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sealer = s
+}
+
+// seal encrypts data with the current sealer, if any.
+func (r *Runner) seal(data []byte) ([]byte, error) {
+	r.mu.RLock()
+	s := r.sealer
+	r.mu.RUnlock()
+	if s == nil {
+		return data, nil
+	}
+	return s.Seal(data)
+}
+
+// open decrypts a value stored with seal.
+func (r *Runner) open(data []byte) ([]byte, error) {
+	r.mu.RLock()
+	s := r.sealer
+	r.mu.RUnlock()
+	if s == nil {
+		return data, nil
+	}
+	return s.Open(data)
+}
+
+// runSecretsLabel is the label run secrets are sealed under.
+const runSecretsLabel = "tool_runs.secrets_json"
+
+// sealSecrets encrypts a run's password values under the secrets key, which
+// is always there, and then with the vault like any sensitive column.
+func (r *Runner) sealSecrets(data []byte) ([]byte, error) {
+	store := r.secretStore()
+	if store == nil {
+		return nil, fmt.Errorf("secrets store is unavailable")
+	}
+	sealed, err := store.Seal(runSecretsLabel, data)
+	if err != nil {
+		return nil, err
+	}
+	return r.seal(sealed)
+}
+
+// openSecrets decrypts a value stored with sealSecrets.
+func (r *Runner) openSecrets(data []byte) ([]byte, error) {
+	data, err := r.open(data)
+	if err != nil {
+		return nil, err
+	}
+	store := r.secretStore()
+	if store == nil {
+		return nil, fmt.Errorf("secrets store is unavailable")
+	}
+	return store.Open(runSecretsLabel, data)
+}
+
+// Active returns the number of runs in progress.
+func (r *Runner) Active() int {
+	return r.runs.count()
+}
+
+// ActiveIn returns the number of runs in progress in a workspace.
+func (r *Runner) ActiveIn(workspaceID int64) int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.active[workspaceID]
+}
+
+// begin registers a run in a workspace as in flight. Call end once it is
+// over.
+func (r *Runner) begin(workspaceID int64) (end func(), err error) {
+	if err := r.runs.begin(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	if r.active == nil {
+		r.active = make(map[int64]int)
+	}
+	r.active[workspaceID]++
+	r.mu.Unlock()
+	return func() {
+		r.mu.Lock()
+		if r.active[workspaceID]--; r.active[workspaceID] == 0 {
+			delete(r.active, workspaceID)
+		}
+		r.mu.Unlock()
+		r.runs.end()
+	}, nil
+}
+
+// Close refuses new runs so the database can be closed. It fails while runs
+// are in progress.
+func (r *Runner) Close() error {
+	return r.runs.close("tool runs")
+}
+
+// Reopen accepts runs again after a Close.
+func (r *Runner) Reopen() {
+	r.runs.reopen()
+}
+
+// ElevationPolicy returns the current elevation policy.
+func (r *Runner) ElevationPolicy() ElevationPolicy {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.policy
 }
 
 // buildCommandLine constructs a human-readable CLI string for the history view.
@@ -61,12 +240,46 @@ func buildCommandLine(binary string, args []string) string {
 }
 
 // insertRun inserts a new tool_runs record with status=running and returns its ID.
-func (r *Runner) insertRun(ctx context.Context, workspaceID int64, toolName, target, commandLine string, userArgs []string) (int64, error) {
-	argsStr := strings.Join(userArgs, " ")
+// RawArgs are stored as a JSON array (args_json) so the run can be replayed
+// with its quoting intact; args keeps a space-joined copy for display.
+// Password values are stored redacted, and sealed in secrets_json under the
+// secrets key.
+func (r *Runner) insertRun(ctx context.Context, req RunRequest, p preparedRun) (int64, error) {
+	req, secrets := redactRequest(p.def, req)
+	var secretsJSON []byte
+	if !secrets.empty() {
+		b, err := json.Marshal(secrets)
+		if err != nil {
+			return 0, fmt.Errorf("encode run secrets: %w", err)
+		}
+		if secretsJSON, err = r.sealSecrets(b); err != nil {
+			return 0, fmt.Errorf("seal run secrets: %w", err)
+		}
+	}
+	argsStr := strings.Join(req.RawArgs, " ")
+	argsJSON, err := json.Marshal(req.RawArgs)
+	if err != nil {
+		return 0, fmt.Errorf("encode args: %w", err)
+	}
+	var optionsJSON, templateJSON sql.NullString
+	if len(req.Options) > 0 {
+		b, err := json.Marshal(req.Options)
+		if err != nil {
+			return 0, fmt.Errorf("encode options: %w", err)
+		}
+		optionsJSON = sql.NullString{String: string(b), Valid: true}
+	}
+	if len(req.ArgTemplate) > 0 {
+		b, err := json.Marshal(req.ArgTemplate)
+		if err != nil {
+			return 0, fmt.Errorf("encode arg template: %w", err)
+		}
+		templateJSON = sql.NullString{String: string(b), Valid: true}
+	}
 	res, err := r.db.ExecContext(ctx,
-		`INSERT INTO tool_runs (workspace_id, tool_name, target, args, command_line, status, started_at)
-		 VALUES (?, ?, ?, ?, ?, 'running', ?)`,
-		workspaceID, toolName, target, argsStr, commandLine, time.Now(),
+		`INSERT INTO tool_runs (workspace_id, tool_name, target, args, args_json, options_json, template_json, command_line, elevation, network_json, secrets_json, status, started_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 'running', ?)`,
+		req.WorkspaceID, req.ToolName, req.Target, argsStr, string(argsJSON), optionsJSON, templateJSON, p.commandLine, p.elevation, p.networkJSON, secretsJSON, time.Now(),
 	)
 	if err != nil {
 		return 0, fmt.Errorf("insert tool_run: %w", err)
@@ -74,54 +287,358 @@ func (r *Runner) insertRun(ctx context.Context, workspaceID int64, toolName, tar
 	return res.LastInsertId()
 }
 
+// StoredRequest reads back the request of a stored run, with the password
+// values stored sealed apart from it, so the run can be replayed. Runs in
+// the trash are refused.
+func (r *Runner) StoredRequest(ctx context.Context, runID int64) (RunRequest, error) {
+	var req RunRequest
+	var args, argsJSON, optionsJSON, templateJSON string
+	var secretsJSON []byte
+	var trashed bool
+	err := r.db.QueryRowContext(ctx,
+		`SELECT workspace_id, tool_name, target, COALESCE(args,''), COALESCE(args_json,''),
+		        COALESCE(options_json,''), COALESCE(template_json,''), secrets_json, deleted_at IS NOT NULL
+		 FROM tool_runs WHERE id = ?`, runID,
+	).Scan(&req.WorkspaceID, &req.ToolName, &req.Target, &args, &argsJSON, &optionsJSON, &templateJSON, &secretsJSON, &trashed)
+	if err != nil {
+		return RunRequest{}, fmt.Errorf("get run %d: %w", runID, err)
+	}
+	if trashed {
+		return RunRequest{}, fmt.Errorf("run %d is in the trash", runID)
+	}
+	if req.RawArgs, req.Options, err = DecodeRunArgs(args, argsJSON, optionsJSON); err != nil {
+		return RunRequest{}, fmt.Errorf("decode run %d: %w", runID, err)
+	}
+	if templateJSON != "" {
+		if err := json.Unmarshal([]byte(templateJSON), &req.ArgTemplate); err != nil {
+			return RunRequest{}, fmt.Errorf("decode run %d template: %w", runID, err)
+		}
+	}
+	if len(secretsJSON) > 0 {
+		if secretsJSON, err = r.openSecrets(secretsJSON); err != nil {
+			return RunRequest{}, fmt.Errorf("open run %d secrets: %w", runID, err)
+		}
+		if err := RestoreSecrets(&req, secretsJSON); err != nil {
+			return RunRequest{}, fmt.Errorf("decode run %d: %w", runID, err)
+		}
+	}
+	return req, nil
+}
+
+// DecodeRunArgs decodes the stored raw args and options of a run or preset.
+// Runs recorded before args_json existed fall back to splitting the display
+// string.
+func DecodeRunArgs(args, argsJSON, optionsJSON string) ([]string, map[string]string, error) {
+	var rawArgs []string
+	var options map[string]string
+	if argsJSON != "" {
+		if err := json.Unmarshal([]byte(argsJSON), &rawArgs); err != nil {
+			return nil, nil, fmt.Errorf("args: %w", err)
+		}
+	} else {
+		rawArgs = strings.Fields(args)
+	}
+	if optionsJSON != "" {
+		if err := json.Unmarshal([]byte(optionsJSON), &options); err != nil {
+			return nil, nil, fmt.Errorf("options: %w", err)
+		}
+	}
+	return rawArgs, options, nil
+}
+
 // finalizeRun updates the tool_runs record after a run completes.
 func (r *Runner) finalizeRun(ctx context.Context, runID int64, output, status string, exitCode int) error {
-	_, err := r.db.ExecContext(ctx,
+	sealed, err := r.seal([]byte(output))
+	if err != nil {
+		return fmt.Errorf("seal run output: %w", err)
+	}
+	_, err = r.db.ExecContext(ctx,
 		`UPDATE tool_runs SET raw_output = ?, status = ?, exit_code = ?, completed_at = ? WHERE id = ?`,
-		[]byte(output), status, exitCode, time.Now(), runID,
+		sealed, status, exitCode, time.Now(), runID,
 	)
 	return err
 }
 
-// prepareExec performs common setup: lookup binary, build full args list, commandLine.
-func (r *Runner) prepareExec(toolName string, target string, userArgs []string) (string, []string, string, error) {
-	def, err := r.registry.Get(toolName)
+// preparedRun is a run ready to exec.
+type preparedRun struct {
+	path        string              // executable to start: the tool, or a wrapper
+	args        []string            // argv for path
+	env         []string            // extra environment, appended to os.Environ(); may hold secrets
+	mask        func(string) string // hides secret values in output
+	commandLine string              // the tool's command line, without any wrapper or passwords
+	elevation   string              // how the run is elevated ("" = not elevated)
+	networkJSON sql.NullString      // workspace network profile in effect, passwords redacted
+	def         ToolDef             // the tool being run
+	parseFile   string              // where the tool writes output for its Parser, if anywhere
+	cleanup     func()              // removes temporary files once the run ends
+	warning     string              // shown with the result, e.g. outside the test window
+}
+
+// command builds the exec.Cmd for a prepared run.
+func (p preparedRun) command(ctx context.Context) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, p.path, p.args...)
+	if len(p.env) > 0 {
+		cmd.Env = append(os.Environ(), p.env...)
+	}
+	killGroupOnCancel(cmd)
+	return cmd
+}
+
+// prepareExec performs common setup: lookup binary, build full args list,
+// commandLine, apply the workspace network profile, and wrap the command
+// when the run needs root.
+func (r *Runner) prepareExec(ctx context.Context, req RunRequest) (preparedRun, error) {
+	// Refuse up front rather than losing the output when it can't be stored.
+	r.mu.RLock()
+	sealer := r.sealer
+	r.mu.RUnlock()
+	if sealer != nil {
+		if err := sealer.Ready(); err != nil {
+			return preparedRun{}, err
+		}
+	}
+	return r.prepare(ctx, req, false)
+}
+
+// PreviewCommand validates a request and returns the command line it would
+// run, without executing anything: the same args as prepareExec builds,
+// parser output, capability and proxy flags included. Nothing is written,
+// so temp paths show as their placeholders and credentials options as the
+// set name.
+func (r *Runner) PreviewCommand(ctx context.Context, req RunRequest) (string, error) {
+	p, err := r.prepare(ctx, req, true)
 	if err != nil {
-		return "", nil, "", err
+		return "", err
+	}
+	return p.commandLine, nil
+}
+
+// prepare builds a run for prepareExec or, with preview, only its command
+// line, without creating files or recording anything.
+func (r *Runner) prepare(ctx context.Context, req RunRequest, preview bool) (preparedRun, error) {
+	def, err := r.registry.Get(req.ToolName)
+	if err != nil {
+		return preparedRun{}, err
+	}
+	var trashed bool
+	if err := r.db.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM workspaces WHERE id = ? AND deleted_at IS NOT NULL)`, req.WorkspaceID,
+	).Scan(&trashed); err != nil {
+		return preparedRun{}, fmt.Errorf("load workspace: %w", err)
+	}
+	if trashed {
+		return preparedRun{}, fmt.Errorf("workspace %d is in the trash", req.WorkspaceID)
+	}
+	window, err := LoadTestWindow(ctx, r.db, req.WorkspaceID)
+	if err != nil {
+		return preparedRun{}, err
+	}
+	warning := window.Check(time.Now())
+	if warning != "" && window.Policy == WindowBlock && !preview {
+		r.recordRefused(ctx, req, warning)
+		return preparedRun{}, fmt.Errorf("run refused: %s", warning)
 	}
 	binPath, err := exec.LookPath(def.Binary)
 	if err != nil {
-		return "", nil, "", fmt.Errorf("tool %q not found in PATH: %w", def.Binary, err)
+		if !preview {
+			return preparedRun{}, fmt.Errorf("tool %q not found in PATH: %w", def.Binary, err)
+		}
+		binPath = def.Binary
+	}
+	removeTemp := func() {}
+	outDir := previewOutDir
+	if !preview {
+		options, removeCombos, err := r.comboFiles(ctx, def, req)
+		if err != nil {
+			return preparedRun{}, err
+		}
+		req.Options, removeTemp = options, removeCombos
+		outDir = ""
+		if usesPlaceholder(argTemplate(def, req), "outdir") {
+			if outDir, err = os.MkdirTemp("", "nser-"+def.Name+"-"); err != nil {
+				removeCombos()
+				return preparedRun{}, fmt.Errorf("create output dir: %w", err)
+			}
+			removeTemp = func() { os.RemoveAll(outDir); removeCombos() }
+		}
+	}
+	args, err := buildArgs(def, req, outDir)
+	if err != nil {
+		removeTemp()
+		return preparedRun{}, err
+	}
+
+	p := preparedRun{def: def, cleanup: removeTemp, mask: func(s string) string { return s }, warning: warning}
+	extra, parseFile, err := parseArgs(def, preview)
+	if err != nil {
+		p.cleanup()
+		return preparedRun{}, err
+	}
+	args = append(args, extra...)
+	if parseFile != "" {
+		p.parseFile = parseFile
+		p.cleanup = func() { os.Remove(parseFile); removeTemp() }
+	}
+	needsRoot, err := requiresRoot(def, req)
+	if err != nil {
+		p.cleanup()
+		return preparedRun{}, err
+	}
+	if needsRoot {
+		p.elevation = elevationFor(def, binPath, r.ElevationPolicy())
+	}
+	if p.elevation == ElevationCapabilities {
+		args = append(append([]string(nil), def.CapabilityArgs...), args...)
+	}
+
+	network, err := LoadNetworkProfile(ctx, r.db, req.WorkspaceID)
+	if err != nil {
+		p.cleanup()
+		return preparedRun{}, err
+	}
+	// The command line shows the proxy flag and password options without
+	// their passwords.
+	shownArgs := args
+	if !network.Proxychains {
+		shownArgs = append(network.Redacted().proxyArgs(def), args...)
+		args = append(network.proxyArgs(def), args...)
+		p.env = network.env()
+	}
+	p.commandLine = buildCommandLine(def.Binary, redactArgv(def, shownArgs))
+	if preview {
+		return p, nil
+	}
+
+	// Secrets travel only in the environment — never in args or the
+	// command line — and are masked if the tool echoes them.
+	store := r.secretStore()
+	toolEnv, err := runEnv(ctx, r.db, store, def, req.WorkspaceID)
+	if err != nil {
+		p.cleanup()
+		return preparedRun{}, err
+	}
+	p.env = append(p.env, toolEnv...)
+	mask, err := secretMasker(ctx, store)
+	if err != nil {
+		p.cleanup()
+		return preparedRun{}, err
 	}
-	args := make([]string, 0, len(def.DefaultArgs)+len(userArgs)+1)
+	if mask != nil {
+		p.mask = mask
+	}
+
+	path := binPath
+	if network.Proxychains {
+		var unwrap func()
+		if path, args, unwrap, err = network.proxychainsWrap(binPath, args); err != nil {
+			p.cleanup()
+			return preparedRun{}, err
+		}
+		removeParseFile := p.cleanup
+		p.cleanup = func() { unwrap(); removeParseFile() }
+	}
+	if !network.IsZero() {
+		b, err := json.Marshal(network.Redacted())
+		if err != nil {
+			p.cleanup()
+			return preparedRun{}, fmt.Errorf("encode network profile: %w", err)
+		}
+		p.networkJSON = sql.NullString{String: string(b), Valid: true}
+	}
+
+	p.path, p.args, err = wrapElevated(p.elevation, path, args, p.env)
+	if err != nil {
+		p.cleanup()
+		return preparedRun{}, err
+	}
+	return p, nil
+}
+
+// previewOutDir stands in for the per-run {{outdir}} in previews, which
+// don't create one.
+const previewOutDir = "{{outdir}}"
+
+// argTemplate returns the template a run uses: the request's own, or the
+// tool's.
+func argTemplate(def ToolDef, req RunRequest) []string {
+	if len(req.ArgTemplate) > 0 {
+		return req.ArgTemplate
+	}
+	return def.ArgTemplate
+}
+
+// buildArgs assembles the argv (without the binary) for a run: DefaultArgs
+// followed by the expanded ArgTemplate, or by the user args + target when the
+// tool declares no template. User args are the option args then RawArgs.
+// A request-level ArgTemplate replaces both DefaultArgs and the tool's template.
+// outDir is the run's scratch directory for {{outdir}}; the caller creates
+// and removes it.
+func buildArgs(def ToolDef, req RunRequest, outDir string) ([]string, error) {
+	optArgs, wordlist, err := BuildOptionArgs(def, req.Options)
+	if err != nil {
+		return nil, err
+	}
+	userArgs := append(optArgs, req.RawArgs...)
+
+	if len(req.ArgTemplate) > 0 {
+		if err := ValidateArgTemplate(req.ArgTemplate); err != nil {
+			return nil, fmt.Errorf("tool %q: %w", def.Name, err)
+		}
+		def.DefaultArgs = nil
+		def.ArgTemplate = req.ArgTemplate
+	}
+
+	args := make([]string, 0, len(def.DefaultArgs)+len(def.ArgTemplate)+len(userArgs)+1)
 	args = append(args, def.DefaultArgs...)
-	args = append(args, userArgs...)
-	args = append(args, target)
 
-	cmdLine := buildCommandLine(def.Binary, args)
-	return binPath, args, cmdLine, nil
+	if len(def.ArgTemplate) == 0 {
+		args = append(args, userArgs...)
+		return append(args, req.Target), nil
+	}
+
+	vars := TemplateVars{Target: req.Target, Wordlist: def.DefaultWordlist, OutDir: outDir}
+	if wordlist != "" {
+		vars.Wordlist = wordlist
+	}
+
+	expanded, err := expandTemplate(def.ArgTemplate, vars, userArgs)
+	if err != nil {
+		return nil, fmt.Errorf("tool %q: %w", def.Name, err)
+	}
+	return append(args, expanded...), nil
 }
 
 // ─── Blocking Run ────────────────────────────────────────────────────────────
 
 // Run executes a tool and blocks until it finishes, then stores and returns the result.
-func (r *Runner) Run(ctx context.Context, toolName string, workspaceID int64, target string, userArgs []string) (*RunResult, error) {
-	binPath, args, cmdLine, err := r.prepareExec(toolName, target, userArgs)
+func (r *Runner) Run(ctx context.Context, req RunRequest) (*RunResult, error) {
+	end, err := r.begin(req.WorkspaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer end()
+
+	p, err := r.prepareExec(ctx, req)
 	if err != nil {
 		return nil, err
 	}
+	defer p.cleanup()
 
 	startedAt := time.Now()
 
-	runID, err := r.insertRun(ctx, workspaceID, toolName, target, cmdLine, userArgs)
+	runID, err := r.insertRun(ctx, req, p)
 	if err != nil {
 		return nil, err
 	}
+	if err := r.recordStart(ctx, req, runID, p); err != nil {
+		r.finalizeRun(ctx, runID, "", "failed", -1) //nolint:errcheck
+		return nil, err
+	}
 
 	execCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
 	defer cancel()
 
-	cmd := exec.CommandContext(execCtx, binPath, args...)
+	cmd := p.command(execCtx)
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
@@ -132,6 +649,7 @@ func (r *Runner) Run(ctx context.Context, toolName string, workspaceID int64, ta
 	if stderr.Len() > 0 {
 		combined += "\n--- STDERR ---\n" + stderr.String()
 	}
+	combined = p.mask(combined)
 
 	status := "completed"
 	exitCode := 0
@@ -147,18 +665,26 @@ func (r *Runner) Run(ctx context.Context, toolName string, workspaceID int64, ta
 	if err := r.finalizeRun(ctx, runID, combined, status, exitCode); err != nil {
 		return nil, fmt.Errorf("update tool_run: %w", err)
 	}
+	warning := joinWarnings(p.warning, r.recordStop(ctx, req.WorkspaceID, runID, status, exitCode))
+	var parseError string
+	if err := r.parseRun(ctx, p, req.WorkspaceID, runID, p.mask(stdout.String())); err != nil {
+		parseError = err.Error()
+	}
 
 	duration := time.Since(startedAt).Round(time.Millisecond)
 
 	return &RunResult{
 		RunID:       runID,
-		ToolName:    toolName,
-		Target:      target,
-		CommandLine: cmdLine,
+		ToolName:    req.ToolName,
+		Target:      req.Target,
+		CommandLine: p.commandLine,
 		Status:      status,
 		Output:      combined,
 		Duration:    duration.String(),
 		ExitCode:    exitCode,
+		Elevation:   p.elevation,
+		ParseError:  parseError,
+		Warning:     warning,
 	}, nil
 }
 
@@ -171,98 +697,133 @@ func (r *Runner) Run(ctx context.Context, toolName string, workspaceID int64, ta
 //	"tool:done:<runID>"   — RunResult payload sent when the process exits
 //
 // The calling context (ctx) must be the Wails app context so EventsEmit works.
-func (r *Runner) RunStreaming(ctx context.Context, toolName string, workspaceID int64, target string, userArgs []string) (*StreamStartResult, error) {
-	binPath, args, cmdLine, err := r.prepareExec(toolName, target, userArgs)
+func (r *Runner) RunStreaming(ctx context.Context, req RunRequest) (*StreamStartResult, error) {
+	end, err := r.begin(req.WorkspaceID)
+	if err != nil {
+		return nil, err
+	}
+	p, err := r.prepareExec(ctx, req)
 	if err != nil {
+		end()
 		return nil, err
 	}
 
-	runID, err := r.insertRun(ctx, workspaceID, toolName, target, cmdLine, userArgs)
+	runID, err := r.insertRun(ctx, req, p)
+	if err == nil {
+		if err = r.recordStart(ctx, req, runID, p); err != nil {
+			r.finalizeRun(ctx, runID, "", "failed", -1) //nolint:errcheck
+		}
+	}
 	if err != nil {
+		p.cleanup()
+		end()
 		return nil, err
 	}
 
 	go func() {
+		defer end()
+		defer p.cleanup()
 		startedAt := time.Now()
 
 		execCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
 		defer cancel()
 
-		cmd := exec.CommandContext(execCtx, binPath, args...)
-
-		// Merge stdout + stderr into a single pipe for sequential output.
-		cmd.Stderr = cmd.Stdout
-
-		stdout, err := cmd.StdoutPipe()
-		if err != nil {
-			runtime.EventsEmit(ctx, fmt.Sprintf("tool:done:%d", runID), RunResult{
-				RunID:       runID,
-				ToolName:    toolName,
-				Target:      target,
-				CommandLine: cmdLine,
-				Status:      "failed",
-				Output:      fmt.Sprintf("pipe error: %v", err),
-				ExitCode:    -1,
-			})
-			return
-		}
-
-		if err := cmd.Start(); err != nil {
-			runtime.EventsEmit(ctx, fmt.Sprintf("tool:done:%d", runID), RunResult{
-				RunID:       runID,
-				ToolName:    toolName,
-				Target:      target,
-				CommandLine: cmdLine,
-				Status:      "failed",
-				Output:      fmt.Sprintf("start error: %v", err),
-				ExitCode:    -1,
-			})
-			return
-		}
-
-		var outputBuilder strings.Builder
-		scanner := bufio.NewScanner(stdout)
-		for scanner.Scan() {
-			line := scanner.Text()
-			outputBuilder.WriteString(line)
-			outputBuilder.WriteByte('\n')
-			runtime.EventsEmit(ctx, fmt.Sprintf("tool:output:%d", runID), line)
-		}
-
-		waitErr := cmd.Wait()
-
-		status := "completed"
-		exitCode := 0
-		if waitErr != nil {
-			status = "failed"
-			if exitErr, ok := waitErr.(*exec.ExitError); ok {
-				exitCode = exitErr.ExitCode()
-			} else {
-				exitCode = -1
-			}
-		}
+		cmd := p.command(execCtx)
+		combined, stdout, status, exitCode := streamCommand(cmd, func(line string) {
+			runtime.EventsEmit(ctx, fmt.Sprintf("tool:output:%d", runID), p.mask(line))
+		})
+		combined = p.mask(combined)
 
-		combined := outputBuilder.String()
 		duration := time.Since(startedAt).Round(time.Millisecond)
 
 		// Best-effort DB update — use background context in case app ctx is done.
 		r.finalizeRun(context.Background(), runID, combined, status, exitCode) //nolint:errcheck
+		warning := joinWarnings(p.warning, r.recordStop(context.Background(), req.WorkspaceID, runID, status, exitCode))
+		var parseError string
+		if err := r.parseRun(context.Background(), p, req.WorkspaceID, runID, p.mask(stdout)); err != nil {
+			parseError = err.Error()
+		}
 
 		result := RunResult{
 			RunID:       runID,
-			ToolName:    toolName,
-			Target:      target,
-			CommandLine: cmdLine,
+			ToolName:    req.ToolName,
+			Target:      req.Target,
+			CommandLine: p.commandLine,
 			Status:      status,
 			Output:      combined,
 			Duration:    duration.String(),
 			ExitCode:    exitCode,
+			Elevation:   p.elevation,
+			ParseError:  parseError,
+			Warning:     warning,
 		}
 		runtime.EventsEmit(ctx, fmt.Sprintf("tool:done:%d", runID), result)
 	}()
 
 	return &StreamStartResult{
 		RunID:       runID,
-		CommandLine: cmdLine,
+		CommandLine: p.commandLine,
+		Elevation:   p.elevation,
+		Warning:     p.warning,
 	}, nil
 }
+
+// streamCommand runs cmd, calling onLine for each line of stdout and stderr
+// as it arrives. Returns the output of both in arrival order, stdout alone
+// (what parsers read), the run status and the exit code.
+func streamCommand(cmd *exec.Cmd, onLine func(string)) (combined, stdout, status string, exitCode int) {
+	outPipe, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Sprintf("pipe error: %v", err), "", "failed", -1
+	}
+	errPipe, err := cmd.StderrPipe()
+	if err != nil {
+		return fmt.Sprintf("pipe error: %v", err), "", "failed", -1
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Sprintf("start error: %v", err), "", "failed", -1
+	}
+
+	type outputLine struct {
+		text   string
+		stdout bool
+	}
+	lines := make(chan outputLine)
+	var wg sync.WaitGroup
+	read := func(r io.Reader, isStdout bool) {
+		defer wg.Done()
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			lines <- outputLine{scanner.Text(), isStdout}
+		}
+	}
+	wg.Add(2)
+	go read(outPipe, true)
+	go read(errPipe, false)
+	go func() { wg.Wait(); close(lines) }()
+
+	var outputBuilder, stdoutBuilder strings.Builder
+	for line := range lines {
+		outputBuilder.WriteString(line.text)
+		outputBuilder.WriteByte('\n')
+		if line.stdout {
+			stdoutBuilder.WriteString(line.text)
+			stdoutBuilder.WriteByte('\n')
+		}
+		onLine(line.text)
+	}
+
+	waitErr := cmd.Wait()
+
+	status = "completed"
+	if waitErr != nil {
+		status = "failed"
+		if exitErr, ok := waitErr.(*exec.ExitError); ok {
+			exitCode = exitErr.ExitCode()
+		} else {
+			exitCode = -1
+		}
+	}
+	return outputBuilder.String(), stdoutBuilder.String(), status, exitCode
+}
@@ -0,0 +1,153 @@
+package tool
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// The nser search path is a list of extra directories (stored in a file in
+// the data directory, ~/.nser/search_path, like the tools it points at)
+// appended to the process's $PATH at startup. Because it
+// is merged into $PATH, exec.LookPath — and so the runner, health checks and
+// child processes — finds tools installed into ~/go/bin or ~/.local/bin even
+// when the user's shell profile doesn't list them.
+
+// ExtendPath appends dirs to $PATH, skipping ones already present, and
+// returns the directories that were added.
+func ExtendPath(dirs ...string) []string {
+	current := filepath.SplitList(os.Getenv("PATH"))
+	var added []string
+	for _, dir := range dirs {
+		dir = filepath.Clean(dir)
+		if slices.Contains(current, dir) {
+			continue
+		}
+		current = append(current, dir)
+		added = append(added, dir)
+	}
+	if len(added) > 0 {
+		os.Setenv("PATH", strings.Join(current, string(os.PathListSeparator)))
+		select {
+		case pathExtended <- struct{}{}:
+		default:
+		}
+	}
+	return added
+}
+
+// inPath reports whether dir is listed in $PATH.
+func inPath(dir string) bool {
+	return slices.Contains(filepath.SplitList(os.Getenv("PATH")), filepath.Clean(dir))
+}
+
+// LoadSearchPath appends every directory in the search path file to $PATH.
+func LoadSearchPath(file string) error {
+	dirs, err := SearchPath(file)
+	if err != nil {
+		return err
+	}
+	ExtendPath(dirs...)
+	return nil
+}
+
+// SearchPath returns the directories in the search path file, one per
+// line, in the order they were added. A missing file is an empty path.
+func SearchPath(file string) ([]string, error) {
+	data, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read search path: %w", err)
+	}
+	var dirs []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			dirs = append(dirs, line)
+		}
+	}
+	return dirs, nil
+}
+
+// saveSearchPath writes dirs to the search path file.
+func saveSearchPath(file string, dirs []string) error {
+	data := strings.Join(dirs, "\n")
+	if len(dirs) > 0 {
+		data += "\n"
+	}
+	if err := os.WriteFile(file, []byte(data), 0o600); err != nil {
+		return fmt.Errorf("write search path: %w", err)
+	}
+	return nil
+}
+
+// AddSearchDir adds dir to the search path file and appends it to $PATH.
+func AddSearchDir(file, dir string) error {
+	if !filepath.IsAbs(dir) {
+		return fmt.Errorf("search directory %q must be absolute", dir)
+	}
+	dir = filepath.Clean(dir)
+	dirs, err := SearchPath(file)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(dirs, dir) {
+		if err := saveSearchPath(file, append(dirs, dir)); err != nil {
+			return err
+		}
+	}
+	ExtendPath(dir)
+	return nil
+}
+
+// RemoveSearchDir deletes dir from the search path file. $PATH keeps the
+// directory until the next start, since it may have been there already.
+func RemoveSearchDir(file, dir string) error {
+	dirs, err := SearchPath(file)
+	if err != nil {
+		return err
+	}
+	dir = filepath.Clean(dir)
+	return saveSearchPath(file, slices.DeleteFunc(dirs, func(d string) bool { return d == dir }))
+}
+
+// ImportSearchPath moves the directories databases from before the search
+// path file kept in their search_paths table to the file, emptying the
+// table so a directory removed later doesn't come back.
+func ImportSearchPath(ctx context.Context, db *sql.DB, file string) error {
+	rows, err := db.QueryContext(ctx, `SELECT dir FROM search_paths ORDER BY added_at, dir`)
+	if err != nil {
+		return fmt.Errorf("query search path: %w", err)
+	}
+	var stored []string
+	for rows.Next() {
+		var dir string
+		if err := rows.Scan(&dir); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan search path: %w", err)
+		}
+		stored = append(stored, dir)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, dir := range stored {
+		if err := AddSearchDir(file, dir); err != nil {
+			return err
+		}
+	}
+	if len(stored) == 0 {
+		return nil
+	}
+	if _, err := db.ExecContext(ctx, `DELETE FROM search_paths`); err != nil {
+		return fmt.Errorf("clear search path table: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package tool
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Version is a parsed semantic version. Missing minor/patch parts are zero,
+// so "7.94" parses as 7.94.0.
+type Version struct {
+	Major, Minor, Patch int
+	Pre                 string // pre-release tag: "dev", "rc1"
+}
+
+// defaultVersionRegex finds the first dotted version number in tool output.
+var defaultVersionRegex = regexp.MustCompile(`v?(\d+\.\d+(?:\.\d+)?(?:-[0-9A-Za-z.]+)?)`)
+
+// ParseVersion parses "v3.1.0", "2.1.0-dev", "7.94" and similar.
+func ParseVersion(s string) (Version, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexByte(s, '+'); i >= 0 {
+		s = s[:i] // build metadata doesn't affect precedence
+	}
+	var v Version
+	s, v.Pre, _ = strings.Cut(s, "-")
+
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 || parts[0] == "" {
+		return Version{}, fmt.Errorf("invalid version %q", s)
+	}
+	nums := [3]int{}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return Version{}, fmt.Errorf("invalid version %q", s)
+		}
+		nums[i] = n
+	}
+	v.Major, v.Minor, v.Patch = nums[0], nums[1], nums[2]
+	return v, nil
+}
+
+// String formats the version as "1.2.3" or "1.2.3-pre".
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Pre != "" {
+		s += "-" + v.Pre
+	}
+	return s
+}
+
+// Compare returns -1, 0 or +1. A pre-release sorts before its release, and
+// pre-release tags compare field by field as in semver: numbers numerically
+// and below words. Digit runs inside a field count as numbers too, since
+// tools write "rc10" rather than "rc.10".
+func (v Version) Compare(o Version) int {
+	for _, d := range [3]int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		if d < 0 {
+			return -1
+		}
+		if d > 0 {
+			return 1
+		}
+	}
+	switch {
+	case v.Pre == o.Pre:
+		return 0
+	case v.Pre == "":
+		return 1
+	case o.Pre == "":
+		return -1
+	default:
+		return comparePre(v.Pre, o.Pre)
+	}
+}
+
+// comparePre compares two non-empty pre-release tags. When every shared
+// field is equal, the tag with fewer fields sorts first ("alpha" < "alpha.1").
+func comparePre(a, b string) int {
+	af, bf := preFields(a), preFields(b)
+	for i := 0; i < len(af) && i < len(bf); i++ {
+		if c := comparePreField(af[i], bf[i]); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case len(af) < len(bf):
+		return -1
+	case len(af) > len(bf):
+		return 1
+	}
+	return 0
+}
+
+// preFields splits a pre-release tag on dots and between digit and
+// non-digit runs: "rc10.b2" → ["rc" "10" "b" "2"].
+func preFields(pre string) []string {
+	var fields []string
+	for _, ident := range strings.Split(pre, ".") {
+		start := 0
+		for i := 1; i <= len(ident); i++ {
+			if i == len(ident) || isDigit(ident[i]) != isDigit(ident[i-1]) {
+				fields = append(fields, ident[start:i])
+				start = i
+			}
+		}
+		if ident == "" {
+			fields = append(fields, "")
+		}
+	}
+	return fields
+}
+
+// comparePreField compares one field: numbers by value, numbers below
+// words, words in ASCII order.
+func comparePreField(a, b string) int {
+	aNum, bNum := isNumeric(a), isNumeric(b)
+	switch {
+	case aNum && bNum:
+		a, b = strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+		if len(a) != len(b) {
+			if len(a) < len(b) {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a, b)
+	case aNum:
+		return -1
+	case bNum:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+func isNumeric(s string) bool {
+	return s != "" && strings.IndexFunc(s, func(r rune) bool { return r < '0' || r > '9' }) < 0
+}
+
+// extractVersion pulls a version string out of a tool's version output using
+// def.VersionFunc, def.VersionRegex or the default regex, in that order.
+// Returns "" if nothing matches.
+func extractVersion(def ToolDef, output string) string {
+	if def.VersionFunc != nil {
+		return def.VersionFunc(output)
+	}
+	re := defaultVersionRegex
+	if def.VersionRegex != "" {
+		custom, err := regexp.Compile(def.VersionRegex)
+		if err != nil {
+			return ""
+		}
+		re = custom
+	}
+	m := re.FindStringSubmatch(output)
+	if len(m) < 2 {
+		return ""
+	}
+	return m[1]
+}
+
+// validateVersionRules checks VersionRegex and the supported range.
+func validateVersionRules(d ToolDef) error {
+	if d.VersionRegex != "" {
+		re, err := regexp.Compile(d.VersionRegex)
+		if err != nil {
+			return fmt.Errorf("version regex: %w", err)
+		}
+		if re.NumSubexp() < 1 {
+			return fmt.Errorf("version regex needs a capture group")
+		}
+	}
+	var minV, maxV Version
+	var err error
+	if d.MinVersion != "" {
+		if minV, err = ParseVersion(d.MinVersion); err != nil {
+			return fmt.Errorf("min version: %w", err)
+		}
+	}
+	if d.MaxVersion != "" {
+		if maxV, err = ParseVersion(d.MaxVersion); err != nil {
+			return fmt.Errorf("max version: %w", err)
+		}
+		if d.MinVersion != "" && minV.Compare(maxV) >= 0 {
+			return fmt.Errorf("min version %s is not below max version %s", minV, maxV)
+		}
+	}
+	return nil
+}
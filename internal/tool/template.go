@@ -0,0 +1,183 @@
+package tool
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// Argument templates let a ToolDef declare exactly where its inputs go:
+//
+//	ArgTemplate: []string{"dir", "-u", "{{url}}", "-w", "{{wordlist}}", "{{args}}"}
+//
+// Placeholders may appear anywhere inside an element ("{{url}}/FUZZ"), except
+// {{args}} which must be a whole element — it is replaced by the user's
+// arguments, one argv element each.
+const argsPlaceholder = "{{args}}"
+
+// templatePlaceholders lists every placeholder understood by expandTemplate.
+var templatePlaceholders = map[string]bool{
+	"target":   true, // target exactly as the user typed it
+	"host":     true, // hostname or IP extracted from the target
+	"port":     true, // explicit port, or the scheme's well-known port
+	"scheme":   true, // URL scheme of the target ("https", "ssh", ...)
+	"url":      true, // target as a URL, "http://" added when no scheme given
+	"wordlist": true, // wordlist path (defaults to ToolDef.DefaultWordlist)
+	"outdir":   true, // per-run scratch directory for tool output files
+	"args":     true, // user-supplied arguments (whole element only)
+}
+
+// defaultPorts maps URL schemes to their well-known ports for {{port}}.
+var defaultPorts = map[string]string{
+	"ftp":      "21",
+	"ssh":      "22",
+	"telnet":   "23",
+	"smtp":     "25",
+	"http":     "80",
+	"pop3":     "110",
+	"imap":     "143",
+	"https":    "443",
+	"smb":      "445",
+	"mssql":    "1433",
+	"mysql":    "3306",
+	"rdp":      "3389",
+	"postgres": "5432",
+	"vnc":      "5900",
+}
+
+// TemplateVars holds the values substituted into an argument template.
+type TemplateVars struct {
+	Target   string
+	Wordlist string
+	OutDir   string
+}
+
+// placeholders returns the names referenced by a single template element.
+func placeholders(elem string) ([]string, error) {
+	var names []string
+	rest := elem
+	for {
+		start := strings.Index(rest, "{{")
+		if start < 0 {
+			if strings.Contains(rest, "}}") {
+				return nil, fmt.Errorf("unmatched \"}}\" in %q", elem)
+			}
+			return names, nil
+		}
+		end := strings.Index(rest[start:], "}}")
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated placeholder in %q", elem)
+		}
+		names = append(names, rest[start+2:start+end])
+		rest = rest[start+end+2:]
+	}
+}
+
+// ValidateArgTemplate checks that every placeholder is known and that
+// {{args}} appears exactly once, as a whole element. An empty template is
+// valid and means "DefaultArgs + userArgs + target".
+func ValidateArgTemplate(tmpl []string) error {
+	if len(tmpl) == 0 {
+		return nil
+	}
+	argsCount := 0
+	for _, elem := range tmpl {
+		names, err := placeholders(elem)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			if !templatePlaceholders[name] {
+				return fmt.Errorf("unknown placeholder {{%s}} in %q", name, elem)
+			}
+			if name == "args" {
+				if elem != argsPlaceholder {
+					return fmt.Errorf("{{args}} must be a whole argument, got %q", elem)
+				}
+				argsCount++
+			}
+		}
+	}
+	if argsCount != 1 {
+		return fmt.Errorf("template must contain {{args}} exactly once, found %d", argsCount)
+	}
+	return nil
+}
+
+// usesPlaceholder reports whether any element of tmpl references name.
+func usesPlaceholder(tmpl []string, name string) bool {
+	for _, elem := range tmpl {
+		names, _ := placeholders(elem)
+		for _, n := range names {
+			if n == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// resolveTarget splits a target into scheme, host and port. Accepts URLs
+// ("https://example.com:8443/x"), host:port pairs and bare hosts or IPs.
+func resolveTarget(target string) (scheme, host, port string) {
+	if strings.Contains(target, "://") {
+		if u, err := url.Parse(target); err == nil {
+			scheme = strings.ToLower(u.Scheme)
+			host = u.Hostname()
+			port = u.Port()
+			if port == "" {
+				port = defaultPorts[scheme]
+			}
+			return scheme, host, port
+		}
+	}
+	if h, p, err := net.SplitHostPort(target); err == nil {
+		return "", h, p
+	}
+	return "", target, ""
+}
+
+// expandTemplate substitutes vars into tmpl and splices userArgs in place of
+// {{args}}. It fails if a referenced value cannot be derived from the inputs.
+func expandTemplate(tmpl []string, vars TemplateVars, userArgs []string) ([]string, error) {
+	scheme, host, port := resolveTarget(vars.Target)
+	targetURL := strings.TrimRight(vars.Target, "/")
+	if scheme == "" {
+		targetURL = "http://" + targetURL
+	}
+
+	values := map[string]string{
+		"target":   vars.Target,
+		"host":     host,
+		"port":     port,
+		"scheme":   scheme,
+		"url":      targetURL,
+		"wordlist": vars.Wordlist,
+		"outdir":   vars.OutDir,
+	}
+
+	out := make([]string, 0, len(tmpl)+len(userArgs))
+	for _, elem := range tmpl {
+		if elem == argsPlaceholder {
+			out = append(out, userArgs...)
+			continue
+		}
+		names, err := placeholders(elem)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			v, ok := values[name]
+			if !ok {
+				return nil, fmt.Errorf("unknown placeholder {{%s}}", name)
+			}
+			if v == "" {
+				return nil, fmt.Errorf("cannot resolve {{%s}} for target %q", name, vars.Target)
+			}
+			elem = strings.ReplaceAll(elem, "{{"+name+"}}", v)
+		}
+		out = append(out, elem)
+	}
+	return out, nil
+}
@@ -0,0 +1,88 @@
+package tool
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Window policies: what the runner does with a run started outside the
+// workspace's authorised test window.
+const (
+	WindowOff   = "off"   // no check
+	WindowWarn  = "warn"  // run, with RunResult.Warning set
+	WindowBlock = "block" // refuse the run
+)
+
+// ValidWindowPolicy reports whether p is a known window policy.
+func ValidWindowPolicy(p string) bool {
+	return p == WindowOff || p == WindowWarn || p == WindowBlock
+}
+
+// TestWindow is the period a workspace's client authorised testing in.
+// A zero Start or End leaves that side open.
+type TestWindow struct {
+	Start, End time.Time
+	Policy     string
+}
+
+// windowLayouts are accepted by ParseWindowTime, most precise first.
+var windowLayouts = []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02 15:04", time.DateOnly}
+
+// ParseWindowTime parses a test window bound: RFC 3339, a local
+// "2006-01-02T15:04" or a date. A date as the end bound covers the whole
+// day. "" is the zero time (open).
+func ParseWindowTime(s string, end bool) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range windowLayouts {
+		t, err := time.ParseInLocation(layout, s, time.Local)
+		if err != nil {
+			continue
+		}
+		if layout == time.DateOnly && end {
+			t = t.AddDate(0, 0, 1).Add(-time.Second)
+		}
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q: use YYYY-MM-DD or YYYY-MM-DDTHH:MM", s)
+}
+
+// LoadTestWindow returns a workspace's test window.
+func LoadTestWindow(ctx context.Context, db *sql.DB, workspaceID int64) (TestWindow, error) {
+	var start, end, policy string
+	err := db.QueryRowContext(ctx,
+		`SELECT COALESCE(window_start, ''), COALESCE(window_end, ''), COALESCE(window_policy, '')
+		 FROM workspaces WHERE id = ?`, workspaceID,
+	).Scan(&start, &end, &policy)
+	if err == sql.ErrNoRows {
+		return TestWindow{Policy: WindowOff}, nil
+	}
+	if err != nil {
+		return TestWindow{}, fmt.Errorf("load test window: %w", err)
+	}
+	w := TestWindow{Policy: policy}
+	if w.Start, err = ParseWindowTime(start, false); err != nil {
+		return TestWindow{}, fmt.Errorf("test window start: %w", err)
+	}
+	if w.End, err = ParseWindowTime(end, true); err != nil {
+		return TestWindow{}, fmt.Errorf("test window end: %w", err)
+	}
+	return w, nil
+}
+
+// Check returns a message if now falls outside the window, or "".
+func (w TestWindow) Check(now time.Time) string {
+	if w.Policy == WindowOff {
+		return ""
+	}
+	switch {
+	case !w.Start.IsZero() && now.Before(w.Start):
+		return fmt.Sprintf("outside the authorised test window: testing starts %s", w.Start.Format("2006-01-02 15:04"))
+	case !w.End.IsZero() && now.After(w.End):
+		return fmt.Sprintf("outside the authorised test window: testing ended %s", w.End.Format("2006-01-02 15:04"))
+	}
+	return ""
+}
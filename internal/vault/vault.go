@@ -0,0 +1,421 @@
+// Package vault provides the optional passphrase-protected encryption of
+// sensitive database columns (raw tool output, parsed results, ...).
+//
+// A random data key encrypts the columns with AES-256-GCM. The data key is
+// stored wrapped under a key derived from the passphrase with Argon2id, so
+// changing the passphrase only rewraps the data key. While the vault is
+// locked, sealed values can't be read or written.
+package vault
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+
+	"nser/internal/db"
+)
+
+// ErrLocked is returned when sealing or opening while the vault is locked.
+var ErrLocked = errors.New("database is locked: unlock it with the passphrase first")
+
+// sealedPrefix marks an encrypted column value; values without it are
+// plaintext (written before encryption was enabled).
+var sealedPrefix = []byte("nser:enc:v1:")
+
+const keySize = 32
+
+// Argon2id parameters for new vaults (RFC 9106 second recommendation).
+const (
+	kdfTime    = 3
+	kdfMemory  = 64 * 1024 // KiB
+	kdfThreads = 4
+)
+
+// Column is a table column holding sensitive data, keyed by an integer ID.
+type Column struct {
+	Table, Key, Column string
+}
+
+// SensitiveColumns are encrypted when the vault is enabled. Append-only.
+var SensitiveColumns = []Column{
+	{"tool_runs", "id", "raw_output"},
+	{"tool_runs", "id", "parsed_json"},
+	{"credentials", "id", "secret"},
+	{"notes", "id", "body"},
+	{"evidence", "id", "data"},
+	{"tool_runs", "id", "secrets_json"},
+	{"run_presets", "id", "options_json"},
+}
+
+// params is the vault configuration stored in the settings table.
+type params struct {
+	KDF        string `json:"kdf"`
+	Salt       []byte `json:"salt"`
+	Time       uint32 `json:"time"`
+	Memory     uint32 `json:"memory"`
+	Threads    uint8  `json:"threads"`
+	WrappedKey []byte `json:"wrappedKey"`
+	Nonce      []byte `json:"nonce"`
+}
+
+// Status is reported to the frontend so it can prompt for the passphrase.
+type Status struct {
+	Enabled  bool `json:"enabled"`
+	Unlocked bool `json:"unlocked"`
+}
+
+// Vault seals and opens sensitive column values.
+type Vault struct {
+	db *sql.DB
+
+	mu     sync.RWMutex
+	params *params     // nil when encryption is disabled
+	aead   cipher.AEAD // nil while locked
+}
+
+// Open loads the vault configuration from db. An enabled vault starts locked.
+func Open(ctx context.Context, conn *sql.DB) (*Vault, error) {
+	v := &Vault{db: conn}
+	raw, err := db.GetSetting(ctx, conn, db.SettingEncryption)
+	if err != nil {
+		return nil, err
+	}
+	if raw != "" {
+		var p params
+		if err := json.Unmarshal([]byte(raw), &p); err != nil {
+			return nil, fmt.Errorf("decode encryption settings: %w", err)
+		}
+		v.params = &p
+	}
+	return v, nil
+}
+
+// Status reports whether encryption is enabled and unlocked.
+func (v *Vault) Status() Status {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return Status{Enabled: v.params != nil, Unlocked: v.params == nil || v.aead != nil}
+}
+
+// Ready returns ErrLocked while sealed values can't be written.
+func (v *Vault) Ready() error {
+	if !v.Status().Unlocked {
+		return ErrLocked
+	}
+	return nil
+}
+
+// Unlock derives the key from passphrase and unlocks the vault.
+func (v *Vault) Unlock(passphrase string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.params == nil {
+		return fmt.Errorf("encryption is not enabled")
+	}
+	dataKey, err := unwrapKey(v.params, passphrase)
+	if err != nil {
+		return err
+	}
+	v.aead, err = newAEAD(dataKey)
+	return err
+}
+
+// Lock forgets the key until the next Unlock.
+func (v *Vault) Lock() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.params != nil {
+		v.aead = nil
+	}
+}
+
+// Seal encrypts a column value. With encryption disabled it returns the
+// value unchanged. nil stays nil so NULL columns remain NULL.
+func (v *Vault) Seal(plaintext []byte) ([]byte, error) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.seal(plaintext)
+}
+
+func (v *Vault) seal(plaintext []byte) ([]byte, error) {
+	if v.params == nil || plaintext == nil {
+		return plaintext, nil
+	}
+	if v.aead == nil {
+		return nil, ErrLocked
+	}
+	nonce := make([]byte, v.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generate nonce: %w", err)
+	}
+	out := append(append([]byte{}, sealedPrefix...), nonce...)
+	return v.aead.Seal(out, nonce, plaintext, sealedPrefix), nil
+}
+
+// Open decrypts a column value. Plaintext values (no sealed prefix) are
+// returned as is, so rows from before encryption stay readable.
+func (v *Vault) Open(data []byte) ([]byte, error) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.open(data)
+}
+
+func (v *Vault) open(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, sealedPrefix) {
+		return data, nil
+	}
+	if v.aead == nil {
+		return nil, ErrLocked
+	}
+	body := data[len(sealedPrefix):]
+	if len(body) < v.aead.NonceSize() {
+		return nil, fmt.Errorf("sealed value is truncated")
+	}
+	nonce, ciphertext := body[:v.aead.NonceSize()], body[v.aead.NonceSize():]
+	plaintext, err := v.aead.Open(nil, nonce, ciphertext, sealedPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt value: %w", err)
+	}
+	return plaintext, nil
+}
+
+// Enable turns on encryption with a new random data key wrapped under
+// passphrase, encrypts every sensitive column and compacts the database so
+// no plaintext copies remain in free pages or the WAL.
+func (v *Vault) Enable(ctx context.Context, passphrase string) error {
+	if len(passphrase) < 8 {
+		return fmt.Errorf("passphrase must be at least 8 characters")
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.params != nil {
+		return fmt.Errorf("encryption is already enabled")
+	}
+
+	dataKey := make([]byte, keySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return fmt.Errorf("generate data key: %w", err)
+	}
+	p, err := wrapKey(dataKey, passphrase)
+	if err != nil {
+		return err
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return err
+	}
+
+	v.params, v.aead = p, aead
+	if err := v.rewrite(ctx, p); err != nil {
+		v.params, v.aead = nil, nil
+		return err
+	}
+	return compact(ctx, v.db)
+}
+
+// Disable decrypts every sensitive column and removes the vault settings.
+func (v *Vault) Disable(ctx context.Context, passphrase string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.params == nil {
+		return fmt.Errorf("encryption is not enabled")
+	}
+	dataKey, err := unwrapKey(v.params, passphrase)
+	if err != nil {
+		return err
+	}
+	if v.aead, err = newAEAD(dataKey); err != nil {
+		return err
+	}
+	if err := v.rewriteWith(ctx, nil, v.open); err != nil {
+		return err
+	}
+	v.params, v.aead = nil, nil
+	return nil
+}
+
+// ChangePassphrase rewraps the data key under a new passphrase.
+func (v *Vault) ChangePassphrase(ctx context.Context, oldPassphrase, newPassphrase string) error {
+	if len(newPassphrase) < 8 {
+		return fmt.Errorf("passphrase must be at least 8 characters")
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.params == nil {
+		return fmt.Errorf("encryption is not enabled")
+	}
+	dataKey, err := unwrapKey(v.params, oldPassphrase)
+	if err != nil {
+		return err
+	}
+	p, err := wrapKey(dataKey, newPassphrase)
+	if err != nil {
+		return err
+	}
+	if err := saveParams(ctx, v.db, p); err != nil {
+		return err
+	}
+	v.params = p
+	return nil
+}
+
+// rewrite encrypts every sensitive column and saves p, in one transaction.
+func (v *Vault) rewrite(ctx context.Context, p *params) error {
+	return v.rewriteWith(ctx, p, func(data []byte) ([]byte, error) {
+		plaintext, err := v.open(data)
+		if err != nil {
+			return nil, err
+		}
+		return v.seal(plaintext)
+	})
+}
+
+// rewriteWith transforms every non-NULL sensitive column value with fn and
+// stores p as the new settings (nil clears them), in one transaction.
+func (v *Vault) rewriteWith(ctx context.Context, p *params, fn func([]byte) ([]byte, error)) error {
+	tx, err := v.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	for _, c := range SensitiveColumns {
+		if err := rewriteColumn(ctx, tx, c, fn); err != nil {
+			return err
+		}
+	}
+	value := ""
+	if p != nil {
+		b, err := json.Marshal(p)
+		if err != nil {
+			return fmt.Errorf("encode encryption settings: %w", err)
+		}
+		value = string(b)
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO settings (key, value) VALUES (?, ?) ON CONFLICT(key) DO UPDATE SET value = excluded.value`,
+		db.SettingEncryption, value,
+	); err != nil {
+		return fmt.Errorf("save encryption settings: %w", err)
+	}
+	return tx.Commit()
+}
+
+// rewriteColumn applies fn to every non-NULL value of one column.
+func rewriteColumn(ctx context.Context, tx *sql.Tx, c Column, fn func([]byte) ([]byte, error)) error {
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(
+		`SELECT %s, %s FROM %s WHERE %s IS NOT NULL`, c.Key, c.Column, c.Table, c.Column))
+	if err != nil {
+		return fmt.Errorf("read %s.%s: %w", c.Table, c.Column, err)
+	}
+	type row struct {
+		id   int64
+		data []byte
+	}
+	var all []row
+	for rows.Next() {
+		var r row
+		if err := rows.Scan(&r.id, &r.data); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan %s.%s: %w", c.Table, c.Column, err)
+		}
+		all = append(all, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	update := fmt.Sprintf(`UPDATE %s SET %s = ? WHERE %s = ?`, c.Table, c.Column, c.Key)
+	for _, r := range all {
+		out, err := fn(r.data)
+		if err != nil {
+			return fmt.Errorf("%s.%s row %d: %w", c.Table, c.Column, r.id, err)
+		}
+		if _, err := tx.ExecContext(ctx, update, out, r.id); err != nil {
+			return fmt.Errorf("write %s.%s row %d: %w", c.Table, c.Column, r.id, err)
+		}
+	}
+	return nil
+}
+
+// compact rewrites the database file and truncates the WAL so plaintext
+// left in free pages or stale search index entries is gone.
+func compact(ctx context.Context, conn *sql.DB) error {
+	if err := db.OptimizeSearch(ctx, conn); err != nil {
+		return err
+	}
+	if _, err := conn.ExecContext(ctx, `VACUUM`); err != nil {
+		return fmt.Errorf("vacuum: %w", err)
+	}
+	if _, err := conn.ExecContext(ctx, `PRAGMA wal_checkpoint(TRUNCATE)`); err != nil {
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+	return nil
+}
+
+// wrapKey derives a key-encryption key from passphrase with fresh salt and
+// seals dataKey under it.
+func wrapKey(dataKey []byte, passphrase string) (*params, error) {
+	p := &params{KDF: "argon2id", Time: kdfTime, Memory: kdfMemory, Threads: kdfThreads}
+	p.Salt = make([]byte, 16)
+	if _, err := rand.Read(p.Salt); err != nil {
+		return nil, fmt.Errorf("generate salt: %w", err)
+	}
+	kek, err := newAEAD(deriveKey(p, passphrase))
+	if err != nil {
+		return nil, err
+	}
+	p.Nonce = make([]byte, kek.NonceSize())
+	if _, err := rand.Read(p.Nonce); err != nil {
+		return nil, fmt.Errorf("generate nonce: %w", err)
+	}
+	p.WrappedKey = kek.Seal(nil, p.Nonce, dataKey, []byte("nser-data-key"))
+	return p, nil
+}
+
+// unwrapKey recovers the data key; a wrong passphrase fails authentication.
+func unwrapKey(p *params, passphrase string) ([]byte, error) {
+	if p.KDF != "argon2id" {
+		return nil, fmt.Errorf("unsupported key derivation %q", p.KDF)
+	}
+	kek, err := newAEAD(deriveKey(p, passphrase))
+	if err != nil {
+		return nil, err
+	}
+	dataKey, err := kek.Open(nil, p.Nonce, p.WrappedKey, []byte("nser-data-key"))
+	if err != nil {
+		return nil, fmt.Errorf("wrong passphrase")
+	}
+	return dataKey, nil
+}
+
+func deriveKey(p *params, passphrase string) []byte {
+	return argon2.IDKey([]byte(passphrase), p.Salt, p.Time, p.Memory, p.Threads, keySize)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// saveParams stores the vault settings.
+func saveParams(ctx context.Context, conn *sql.DB, p *params) error {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("encode encryption settings: %w", err)
+	}
+	return db.SetSetting(ctx, conn, db.SettingEncryption, string(b))
+}
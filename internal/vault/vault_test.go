@@ -0,0 +1,105 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"nser/internal/db"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	conn, err := db.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	if _, err := conn.Exec(`
+		INSERT INTO workspaces (id, name) VALUES (1, 'acme');
+		INSERT INTO tool_runs (id, workspace_id, tool_name, target, raw_output) VALUES (1, 1, 'nmap', 'host', 'open 22/tcp ssh');`); err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+func rawOutput(t *testing.T, conn *sql.DB) []byte {
+	t.Helper()
+	var out []byte
+	if err := conn.QueryRow(`SELECT raw_output FROM tool_runs WHERE id = 1`).Scan(&out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestVaultLifecycle(t *testing.T) {
+	ctx := context.Background()
+	conn := openTestDB(t)
+	v, err := Open(ctx, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Disabled: values pass through.
+	if got, _ := v.Seal([]byte("x")); string(got) != "x" {
+		t.Errorf("Seal while disabled = %q", got)
+	}
+
+	if err := v.Enable(ctx, "short"); err == nil {
+		t.Error("Enable accepted a short passphrase")
+	}
+	if err := v.Enable(ctx, "correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	stored := rawOutput(t, conn)
+	if bytes.Contains(stored, []byte("22/tcp")) {
+		t.Fatal("existing output left in plaintext")
+	}
+	if hits, err := db.Search(ctx, conn, 0, "ssh"); err != nil || len(hits) != 0 {
+		t.Errorf("sealed output still searchable: %+v, %v", hits, err)
+	}
+	if got, err := v.Open(stored); err != nil || string(got) != "open 22/tcp ssh" {
+		t.Fatalf("Open = %q, %v", got, err)
+	}
+	if got, _ := v.Open([]byte("legacy")); string(got) != "legacy" {
+		t.Errorf("Open(plaintext) = %q", got)
+	}
+
+	// A fresh open starts locked.
+	v, err = Open(ctx, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := v.Status(); !s.Enabled || s.Unlocked {
+		t.Fatalf("Status after reopen = %+v", s)
+	}
+	if _, err := v.Open(stored); !errors.Is(err, ErrLocked) {
+		t.Errorf("Open while locked: %v", err)
+	}
+	if err := v.Unlock("wrong passphrase"); err == nil {
+		t.Error("Unlock accepted the wrong passphrase")
+	}
+
+	if err := v.ChangePassphrase(ctx, "correct horse", "battery staple"); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Unlock("battery staple"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := v.Open(stored); err != nil || string(got) != "open 22/tcp ssh" {
+		t.Fatalf("Open after passphrase change = %q, %v", got, err)
+	}
+
+	if err := v.Disable(ctx, "battery staple"); err != nil {
+		t.Fatal(err)
+	}
+	if got := rawOutput(t, conn); string(got) != "open 22/tcp ssh" {
+		t.Errorf("output after Disable = %q", got)
+	}
+	if s := v.Status(); s.Enabled || !s.Unlocked {
+		t.Errorf("Status after Disable = %+v", s)
+	}
+}
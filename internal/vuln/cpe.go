@@ -0,0 +1,129 @@
+package vuln
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// CPE is the part of a CPE name used for matching.
+type CPE struct {
+	Part    string // a (application), o (OS), h (hardware)
+	Vendor  string
+	Product string
+	Version string // "*" = any, "-" = not applicable
+	Update  string
+}
+
+// ParseCPE parses a CPE 2.3 formatted string (cpe:2.3:a:apache:http_server:2.4.49:*:...)
+// or a CPE 2.2 URI as nmap reports it (cpe:/a:openbsd:openssh:7.2p2).
+// Missing fields are "*". ok is false for anything else.
+func ParseCPE(s string) (CPE, bool) {
+	var fields []string
+	switch {
+	case strings.HasPrefix(s, "cpe:2.3:"):
+		fields = splitCPE(strings.TrimPrefix(s, "cpe:2.3:"))
+	case strings.HasPrefix(s, "cpe:/"):
+		fields = strings.Split(strings.TrimPrefix(s, "cpe:/"), ":")
+		for i, f := range fields {
+			fields[i] = strings.ReplaceAll(f, "%", "") // 2.2 percent-encoding is rare; drop it
+		}
+	default:
+		return CPE{}, false
+	}
+	if len(fields) < 3 || fields[1] == "" || fields[2] == "" {
+		return CPE{}, false
+	}
+	field := func(i int) string {
+		if i < len(fields) && fields[i] != "" {
+			return strings.ToLower(fields[i])
+		}
+		return "*"
+	}
+	return CPE{
+		Part:    field(0),
+		Vendor:  field(1),
+		Product: field(2),
+		Version: field(3),
+		Update:  field(4),
+	}, true
+}
+
+// splitCPE splits a CPE 2.3 string on unescaped colons and unescapes the
+// fields ("http\:server" → "http:server").
+func splitCPE(s string) []string {
+	var fields []string
+	var cur strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s):
+			i++
+			cur.WriteByte(s[i])
+		case s[i] == ':':
+			fields = append(fields, cur.String())
+			cur.Reset()
+		default:
+			cur.WriteByte(s[i])
+		}
+	}
+	return append(fields, cur.String())
+}
+
+// compareVersions orders product versions like "2.4.49", "7.2p2" or
+// "1.18.0": they are split into runs of digits and letters, digit runs
+// compare numerically and letter runs alphabetically. When one version is
+// a prefix of the other, the longer one is greater ("7.2p2" > "7.2").
+func compareVersions(a, b string) int {
+	as, bs := versionSegments(a), versionSegments(b)
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if c := compareSegment(as[i], bs[i]); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case len(as) < len(bs):
+		return -1
+	case len(as) > len(bs):
+		return 1
+	}
+	return 0
+}
+
+func versionSegments(v string) []string {
+	var segs []string
+	start := -1
+	kind := 0 // 1 digit, 2 letter
+	for i, r := range strings.ToLower(v) {
+		k := 0
+		switch {
+		case unicode.IsDigit(r):
+			k = 1
+		case unicode.IsLetter(r):
+			k = 2
+		}
+		if k != kind {
+			if kind != 0 {
+				segs = append(segs, v[start:i])
+			}
+			start, kind = i, k
+		}
+	}
+	if kind != 0 {
+		segs = append(segs, v[start:])
+	}
+	return segs
+}
+
+func compareSegment(a, b string) int {
+	an, aErr := strconv.Atoi(a)
+	bn, bErr := strconv.Atoi(b)
+	switch {
+	case aErr == nil && bErr == nil:
+		return an - bn
+	case aErr == nil:
+		return 1 // numbers sort after letters: 1.0.1 > 1.0.beta
+	case bErr == nil:
+		return -1
+	}
+	return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+}
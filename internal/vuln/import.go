@@ -0,0 +1,394 @@
+package vuln
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Feed kinds detected by Import.
+const (
+	FeedNVD2  = "nvd-2.0"  // NVD API 2.0 responses and JSON 2.0 feeds: {"vulnerabilities": [{"cve": ...}]}
+	FeedNVD11 = "nvd-1.1"  // legacy NVD JSON 1.1 feeds: {"CVE_Items": [...]}
+	FeedKEV   = "cisa-kev" // CISA KEV catalog: {"catalogVersion": ..., "vulnerabilities": [{"cveID": ...}]}
+)
+
+// ImportResult reports what one Import added.
+type ImportResult struct {
+	Kind    string `json:"kind"`
+	Records int    `json:"records"`
+}
+
+// cveRecord is a CVE normalised from either NVD format.
+type cveRecord struct {
+	id, description        string
+	score                  float64
+	severity, vector, date string
+	cpes                   []cpeMatch
+}
+
+// cpeMatch is one vulnerable CPE criterion with optional version bounds.
+type cpeMatch struct {
+	criteria                               string
+	startIncl, startExcl, endIncl, endExcl string
+}
+
+// Import reads an NVD feed (2.0 or 1.1) or the CISA KEV catalog, plain or
+// gzipped, detecting the format from its top-level keys. Records replace
+// earlier imports of the same CVE, so re-importing newer feeds is safe.
+// source names the file for the import log.
+func (s *Store) Import(ctx context.Context, r io.Reader, source string) (ImportResult, error) {
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return ImportResult{}, fmt.Errorf("gunzip feed: %w", err)
+		}
+		defer gz.Close()
+		br = bufio.NewReader(gz)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return ImportResult{}, fmt.Errorf("begin import: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	res, err := decodeFeed(ctx, json.NewDecoder(br), tx)
+	if err != nil {
+		return ImportResult{}, err
+	}
+	if res.Kind == "" {
+		return ImportResult{}, fmt.Errorf("%s is not an NVD feed or the CISA KEV catalog", source)
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO imports (source, kind, records, imported_at) VALUES (?, ?, ?, ?)`,
+		source, res.Kind, res.Records, time.Now(),
+	); err != nil {
+		return ImportResult{}, fmt.Errorf("log import: %w", err)
+	}
+	return res, tx.Commit()
+}
+
+// decodeFeed walks the top-level object, streaming the record array so
+// year feeds of several hundred MB aren't held in memory.
+func decodeFeed(ctx context.Context, dec *json.Decoder, tx *sql.Tx) (ImportResult, error) {
+	if err := expectDelim(dec, '{'); err != nil {
+		return ImportResult{}, err
+	}
+	var res ImportResult
+	isKEV := false
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return ImportResult{}, fmt.Errorf("read feed: %w", err)
+		}
+		key, _ := tok.(string)
+		switch key {
+		case "catalogVersion":
+			isKEV = true
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return ImportResult{}, fmt.Errorf("read feed: %w", err)
+			}
+		case "vulnerabilities", "CVE_Items":
+			kind := FeedNVD2
+			if key == "CVE_Items" {
+				kind = FeedNVD11
+			}
+			n, err := decodeRecords(ctx, dec, tx, kind)
+			if err != nil {
+				return ImportResult{}, err
+			}
+			if kind == FeedNVD2 && n.kev {
+				kind = FeedKEV
+			}
+			res.Kind, res.Records = kind, n.count
+		default:
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return ImportResult{}, fmt.Errorf("read feed: %w", err)
+			}
+		}
+	}
+	if isKEV && res.Kind == FeedNVD2 && res.Records == 0 {
+		res.Kind = FeedKEV
+	}
+	return res, nil
+}
+
+type decodeCount struct {
+	count int
+	kev   bool
+}
+
+// decodeRecords stores each element of the record array. KEV entries and
+// NVD 2.0 records share the "vulnerabilities" key; they are told apart by
+// the first element's fields.
+func decodeRecords(ctx context.Context, dec *json.Decoder, tx *sql.Tx, kind string) (decodeCount, error) {
+	if err := expectDelim(dec, '['); err != nil {
+		return decodeCount{}, err
+	}
+	var n decodeCount
+	for dec.More() {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return decodeCount{}, fmt.Errorf("read record %d: %w", n.count+1, err)
+		}
+		var err error
+		switch {
+		case kind == FeedNVD11:
+			err = storeNVD11(ctx, tx, raw)
+		default:
+			var probe struct {
+				CVEID string `json:"cveID"`
+			}
+			json.Unmarshal(raw, &probe) //nolint:errcheck
+			if probe.CVEID != "" {
+				n.kev = true
+				err = storeKEV(ctx, tx, raw)
+			} else {
+				err = storeNVD2(ctx, tx, raw)
+			}
+		}
+		if err != nil {
+			return decodeCount{}, fmt.Errorf("record %d: %w", n.count+1, err)
+		}
+		n.count++
+	}
+	if _, err := dec.Token(); err != nil { // closing ]
+		return decodeCount{}, fmt.Errorf("read feed: %w", err)
+	}
+	return n, nil
+}
+
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return fmt.Errorf("read feed: %w", err)
+	}
+	if d, ok := tok.(json.Delim); !ok || d != want {
+		return fmt.Errorf("read feed: expected %q, got %v", want, tok)
+	}
+	return nil
+}
+
+// nvd2Item is one element of an NVD 2.0 "vulnerabilities" array.
+type nvd2Item struct {
+	CVE struct {
+		ID           string `json:"id"`
+		Published    string `json:"published"`
+		Descriptions []struct {
+			Lang  string `json:"lang"`
+			Value string `json:"value"`
+		} `json:"descriptions"`
+		Metrics map[string][]struct {
+			Type     string `json:"type"`
+			CVSSData struct {
+				BaseScore    float64 `json:"baseScore"`
+				BaseSeverity string  `json:"baseSeverity"`
+				VectorString string  `json:"vectorString"`
+			} `json:"cvssData"`
+			BaseSeverity string `json:"baseSeverity"` // v2 keeps it outside cvssData
+		} `json:"metrics"`
+		Configurations []struct {
+			Nodes []struct {
+				CPEMatch []struct {
+					Vulnerable            bool   `json:"vulnerable"`
+					Criteria              string `json:"criteria"`
+					VersionStartIncluding string `json:"versionStartIncluding"`
+					VersionStartExcluding string `json:"versionStartExcluding"`
+					VersionEndIncluding   string `json:"versionEndIncluding"`
+					VersionEndExcluding   string `json:"versionEndExcluding"`
+				} `json:"cpeMatch"`
+			} `json:"nodes"`
+		} `json:"configurations"`
+	} `json:"cve"`
+}
+
+func storeNVD2(ctx context.Context, tx *sql.Tx, raw json.RawMessage) error {
+	var item nvd2Item
+	if err := json.Unmarshal(raw, &item); err != nil {
+		return err
+	}
+	c := item.CVE
+	rec := cveRecord{id: c.ID, date: c.Published}
+	for _, d := range c.Descriptions {
+		if d.Lang == "en" {
+			rec.description = d.Value
+			break
+		}
+	}
+	// Newest CVSS version first; prefer NVD's own (Primary) score.
+	for _, key := range []string{"cvssMetricV40", "cvssMetricV31", "cvssMetricV30", "cvssMetricV2"} {
+		metrics := c.Metrics[key]
+		for i, m := range metrics {
+			if m.Type == "Primary" || i == len(metrics)-1 {
+				rec.score, rec.vector = m.CVSSData.BaseScore, m.CVSSData.VectorString
+				rec.severity = m.CVSSData.BaseSeverity
+				if rec.severity == "" {
+					rec.severity = m.BaseSeverity
+				}
+				break
+			}
+		}
+		if rec.vector != "" {
+			break
+		}
+	}
+	for _, conf := range c.Configurations {
+		for _, node := range conf.Nodes {
+			for _, m := range node.CPEMatch {
+				if m.Vulnerable {
+					rec.cpes = append(rec.cpes, cpeMatch{m.Criteria,
+						m.VersionStartIncluding, m.VersionStartExcluding, m.VersionEndIncluding, m.VersionEndExcluding})
+				}
+			}
+		}
+	}
+	return storeCVE(ctx, tx, rec)
+}
+
+// nvd11Node is a configuration node of the legacy feed; nodes nest.
+type nvd11Node struct {
+	Children []nvd11Node `json:"children"`
+	CPEMatch []struct {
+		Vulnerable            bool   `json:"vulnerable"`
+		CPE23URI              string `json:"cpe23Uri"`
+		VersionStartIncluding string `json:"versionStartIncluding"`
+		VersionStartExcluding string `json:"versionStartExcluding"`
+		VersionEndIncluding   string `json:"versionEndIncluding"`
+		VersionEndExcluding   string `json:"versionEndExcluding"`
+	} `json:"cpe_match"`
+}
+
+func (n nvd11Node) collect(out *[]cpeMatch) {
+	for _, m := range n.CPEMatch {
+		if m.Vulnerable {
+			*out = append(*out, cpeMatch{m.CPE23URI,
+				m.VersionStartIncluding, m.VersionStartExcluding, m.VersionEndIncluding, m.VersionEndExcluding})
+		}
+	}
+	for _, c := range n.Children {
+		c.collect(out)
+	}
+}
+
+func storeNVD11(ctx context.Context, tx *sql.Tx, raw json.RawMessage) error {
+	var item struct {
+		CVE struct {
+			Meta struct {
+				ID string `json:"ID"`
+			} `json:"CVE_data_meta"`
+			Description struct {
+				Data []struct {
+					Lang  string `json:"lang"`
+					Value string `json:"value"`
+				} `json:"description_data"`
+			} `json:"description"`
+		} `json:"cve"`
+		Configurations struct {
+			Nodes []nvd11Node `json:"nodes"`
+		} `json:"configurations"`
+		Impact struct {
+			V3 struct {
+				CVSS struct {
+					BaseScore    float64 `json:"baseScore"`
+					BaseSeverity string  `json:"baseSeverity"`
+					VectorString string  `json:"vectorString"`
+				} `json:"cvssV3"`
+			} `json:"baseMetricV3"`
+			V2 struct {
+				CVSS struct {
+					BaseScore    float64 `json:"baseScore"`
+					VectorString string  `json:"vectorString"`
+				} `json:"cvssV2"`
+				Severity string `json:"severity"`
+			} `json:"baseMetricV2"`
+		} `json:"impact"`
+		Published string `json:"publishedDate"`
+	}
+	if err := json.Unmarshal(raw, &item); err != nil {
+		return err
+	}
+	rec := cveRecord{id: item.CVE.Meta.ID, date: item.Published}
+	for _, d := range item.CVE.Description.Data {
+		if d.Lang == "en" {
+			rec.description = d.Value
+			break
+		}
+	}
+	if v3 := item.Impact.V3.CVSS; v3.VectorString != "" {
+		rec.score, rec.severity, rec.vector = v3.BaseScore, v3.BaseSeverity, v3.VectorString
+	} else if v2 := item.Impact.V2; v2.CVSS.VectorString != "" {
+		rec.score, rec.severity, rec.vector = v2.CVSS.BaseScore, v2.Severity, v2.CVSS.VectorString
+	}
+	for _, n := range item.Configurations.Nodes {
+		n.collect(&rec.cpes)
+	}
+	return storeCVE(ctx, tx, rec)
+}
+
+// storeCVE replaces a CVE and its CPE criteria. Criteria that aren't valid
+// CPE names are skipped.
+func storeCVE(ctx context.Context, tx *sql.Tx, rec cveRecord) error {
+	if rec.id == "" {
+		return fmt.Errorf("record has no CVE ID")
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO cves (id, description, cvss_score, cvss_severity, cvss_vector, published) VALUES (?, ?, ?, ?, ?, ?)
+		 ON CONFLICT(id) DO UPDATE SET description = excluded.description, cvss_score = excluded.cvss_score,
+		   cvss_severity = excluded.cvss_severity, cvss_vector = excluded.cvss_vector, published = excluded.published`,
+		rec.id, rec.description, rec.score, rec.severity, rec.vector, rec.date,
+	); err != nil {
+		return fmt.Errorf("store %s: %w", rec.id, err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM cve_cpes WHERE cve_id = ?`, rec.id); err != nil {
+		return fmt.Errorf("store %s: %w", rec.id, err)
+	}
+	for _, m := range rec.cpes {
+		cpe, ok := ParseCPE(m.criteria)
+		if !ok {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO cve_cpes (cve_id, part, vendor, product, version, version_update,
+			   version_start_including, version_start_excluding, version_end_including, version_end_excluding)
+			 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			rec.id, cpe.Part, cpe.Vendor, cpe.Product, cpe.Version, cpe.Update,
+			m.startIncl, m.startExcl, m.endIncl, m.endExcl,
+		); err != nil {
+			return fmt.Errorf("store %s cpe: %w", rec.id, err)
+		}
+	}
+	return nil
+}
+
+// storeKEV records one CISA KEV catalog entry.
+func storeKEV(ctx context.Context, tx *sql.Tx, raw json.RawMessage) error {
+	var e struct {
+		CVEID      string `json:"cveID"`
+		Name       string `json:"vulnerabilityName"`
+		DateAdded  string `json:"dateAdded"`
+		DueDate    string `json:"dueDate"`
+		Ransomware string `json:"knownRansomwareCampaignUse"`
+	}
+	if err := json.Unmarshal(raw, &e); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO kev (cve_id, name, date_added, due_date, ransomware) VALUES (?, ?, ?, ?, ?)
+		 ON CONFLICT(cve_id) DO UPDATE SET name = excluded.name, date_added = excluded.date_added,
+		   due_date = excluded.due_date, ransomware = excluded.ransomware`,
+		e.CVEID, e.Name, e.DateAdded, e.DueDate, e.Ransomware,
+	)
+	if err != nil {
+		return fmt.Errorf("store kev %s: %w", e.CVEID, err)
+	}
+	return nil
+}
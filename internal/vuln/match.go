@@ -0,0 +1,160 @@
+package vuln
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Match is a CVE whose CPE criteria cover a service.
+type Match struct {
+	CVE         string  `json:"cve"`
+	Description string  `json:"description"`
+	CVSS        float64 `json:"cvss"`
+	Severity    string  `json:"severity"`
+	Vector      string  `json:"vector"`
+	KEV         bool    `json:"kev"`
+}
+
+// productCPEs maps nmap product names to NVD vendor:product, for services
+// that were identified from a banner and carry no CPE of their own.
+var productCPEs = map[string]string{
+	"OpenSSH":             "openbsd:openssh",
+	"Apache httpd":        "apache:http_server",
+	"nginx":               "f5:nginx",
+	"Microsoft IIS httpd": "microsoft:internet_information_services",
+	"ProFTPD":             "proftpd:proftpd",
+	"vsftpd":              "vsftpd_project:vsftpd",
+}
+
+// productAliases lists other vendor names NVD has filed a product under.
+var productAliases = map[string][]string{
+	"f5:nginx":              {"nginx:nginx", "igor_sysoev:nginx"},
+	"igor_sysoev:nginx":     {"f5:nginx", "nginx:nginx"},
+	"nginx:nginx":           {"f5:nginx", "igor_sysoev:nginx"},
+	"vsftpd_project:vsftpd": {"beasts:vsftpd"},
+	"beasts:vsftpd":         {"vsftpd_project:vsftpd"},
+}
+
+// ServiceCPE works out the CPE to match for a service: its own CPE when
+// it has one (with the version filled in from the service if the CPE
+// lacks it), otherwise one derived from the product name. ok is false when
+// there is nothing to match on, including services without a version.
+func ServiceCPE(cpe, product, version string) (CPE, bool) {
+	version = firstField(version)
+	c, ok := ParseCPE(cpe)
+	if !ok {
+		vp, known := productCPEs[product]
+		if !known {
+			return CPE{}, false
+		}
+		vendor, prod, _ := strings.Cut(vp, ":")
+		c = CPE{Part: "a", Vendor: vendor, Product: prod, Version: "*", Update: "*"}
+	}
+	if c.Version == "*" || c.Version == "-" {
+		if version == "" {
+			return CPE{}, false
+		}
+		c.Version = strings.ToLower(version)
+	}
+	return c, true
+}
+
+// firstField drops distribution suffixes nmap appends to versions
+// ("7.2p2 Ubuntu 4ubuntu2.8" → "7.2p2").
+func firstField(v string) string {
+	if f := strings.Fields(v); len(f) > 0 {
+		return f[0]
+	}
+	return ""
+}
+
+// Match returns the CVEs affecting a service, highest CVSS first. See
+// ServiceCPE for how cpe, product and version are combined. Version
+// ranges are compared with compareVersions; distribution backports aren't
+// visible in a banner, so matches are suggestions for a tester to confirm.
+func (s *Store) Match(ctx context.Context, cpe, product, version string) ([]Match, error) {
+	c, ok := ServiceCPE(cpe, product, version)
+	if !ok {
+		return nil, nil
+	}
+	names := append([]string{c.Vendor + ":" + c.Product}, productAliases[c.Vendor+":"+c.Product]...)
+
+	found := map[string]Match{}
+	for _, name := range names {
+		vendor, prod, _ := strings.Cut(name, ":")
+		rows, err := s.db.QueryContext(ctx,
+			`SELECT c.id, c.description, c.cvss_score, c.cvss_severity, c.cvss_vector, k.cve_id IS NOT NULL,
+			        p.version, p.version_update, p.version_start_including, p.version_start_excluding,
+			        p.version_end_including, p.version_end_excluding
+			 FROM cve_cpes p
+			 JOIN cves c ON c.id = p.cve_id
+			 LEFT JOIN kev k ON k.cve_id = c.id
+			 WHERE p.vendor = ? AND p.product = ? AND p.part = ?`,
+			vendor, prod, c.Part,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("match %s: %w", name, err)
+		}
+		for rows.Next() {
+			var m Match
+			var r cpeRange
+			if err := rows.Scan(&m.CVE, &m.Description, &m.CVSS, &m.Severity, &m.Vector, &m.KEV,
+				&r.version, &r.update, &r.startIncl, &r.startExcl, &r.endIncl, &r.endExcl); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("match %s: %w", name, err)
+			}
+			if _, dup := found[m.CVE]; !dup && r.covers(c.Version) {
+				found[m.CVE] = m
+			}
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("match %s: %w", name, err)
+		}
+	}
+
+	out := make([]Match, 0, len(found))
+	for _, m := range found {
+		out = append(out, m)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].CVSS != out[j].CVSS {
+			return out[i].CVSS > out[j].CVSS
+		}
+		return out[i].CVE > out[j].CVE
+	})
+	return out, nil
+}
+
+// cpeRange is a stored CPE criterion.
+type cpeRange struct {
+	version, update                        string
+	startIncl, startExcl, endIncl, endExcl string
+}
+
+// covers reports whether version falls under the criterion: an exact
+// version (NVD splits "7.2p2" into version 7.2, update p2), or "*" with
+// range bounds. A "*" with no bounds would match every release and is
+// ignored.
+func (r cpeRange) covers(version string) bool {
+	if r.version != "*" && r.version != "" {
+		if r.update != "*" && r.update != "-" && r.update != "" {
+			return version == r.version+r.update || version == r.version+"_"+r.update
+		}
+		return version == r.version
+	}
+	if r.startIncl == "" && r.startExcl == "" && r.endIncl == "" && r.endExcl == "" {
+		return false
+	}
+	switch {
+	case r.startIncl != "" && compareVersions(version, r.startIncl) < 0,
+		r.startExcl != "" && compareVersions(version, r.startExcl) <= 0,
+		r.endIncl != "" && compareVersions(version, r.endIncl) > 0,
+		r.endExcl != "" && compareVersions(version, r.endExcl) >= 0:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,40 @@
+CREATE TABLE IF NOT EXISTS cves (
+    id            TEXT PRIMARY KEY,
+    description   TEXT DEFAULT '',
+    cvss_score    REAL DEFAULT 0,
+    cvss_severity TEXT DEFAULT '',
+    cvss_vector   TEXT DEFAULT '',
+    published     TEXT DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS cve_cpes (
+    cve_id                  TEXT NOT NULL REFERENCES cves(id) ON DELETE CASCADE,
+    part                    TEXT NOT NULL,
+    vendor                  TEXT NOT NULL,
+    product                 TEXT NOT NULL,
+    version                 TEXT DEFAULT '*',
+    version_update          TEXT DEFAULT '*',
+    version_start_including TEXT DEFAULT '',
+    version_start_excluding TEXT DEFAULT '',
+    version_end_including   TEXT DEFAULT '',
+    version_end_excluding   TEXT DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_cve_cpes_product ON cve_cpes(vendor, product);
+CREATE INDEX IF NOT EXISTS idx_cve_cpes_cve ON cve_cpes(cve_id);
+
+CREATE TABLE IF NOT EXISTS kev (
+    cve_id     TEXT PRIMARY KEY,
+    name       TEXT DEFAULT '',
+    date_added TEXT DEFAULT '',
+    due_date   TEXT DEFAULT '',
+    ransomware TEXT DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS imports (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    source      TEXT NOT NULL,
+    kind        TEXT NOT NULL,
+    records     INTEGER DEFAULT 0,
+    imported_at DATETIME NOT NULL
+);
@@ -0,0 +1,71 @@
+// Package vuln keeps an offline copy of the NVD CVE feeds and the CISA
+// Known Exploited Vulnerabilities catalog, and matches service CPEs against
+// it, so services can be enriched without network access during an
+// engagement.
+//
+// The store is its own SQLite file (~/.nser/cve.db) shared by every
+// engagement database.
+package vuln
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"fmt"
+	"os"
+
+	_ "modernc.org/sqlite"
+)
+
+//go:embed schema.sql
+var schemaSQL string
+
+// Store is the local CVE database.
+type Store struct {
+	db *sql.DB
+}
+
+// Status summarises what has been imported.
+type Status struct {
+	CVEs       int    `json:"cves"`
+	KEV        int    `json:"kev"`
+	LastImport string `json:"lastImport"` // "" if nothing was imported
+}
+
+// Open opens or creates the store at path.
+func Open(path string) (*Store, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("create cve store: %w", err)
+	}
+	f.Close()
+
+	db, err := sql.Open("sqlite", path+"?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("open cve store: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(schemaSQL); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("cve store schema: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Close closes the store.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Status reports the number of CVEs and KEV entries imported.
+func (s *Store) Status(ctx context.Context) (Status, error) {
+	var st Status
+	err := s.db.QueryRowContext(ctx,
+		`SELECT (SELECT COUNT(*) FROM cves), (SELECT COUNT(*) FROM kev),
+		        COALESCE((SELECT MAX(imported_at) FROM imports), '')`,
+	).Scan(&st.CVEs, &st.KEV, &st.LastImport)
+	if err != nil {
+		return Status{}, fmt.Errorf("cve store status: %w", err)
+	}
+	return st, nil
+}
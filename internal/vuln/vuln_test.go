@@ -0,0 +1,165 @@
+package vuln
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseCPE(t *testing.T) {
+	for in, want := range map[string]CPE{
+		"cpe:2.3:a:apache:http_server:2.4.49:*:*:*:*:*:*:*": {"a", "apache", "http_server", "2.4.49", "*"},
+		"cpe:2.3:a:openbsd:openssh:7.2:p2:*:*:*:*:*:*":      {"a", "openbsd", "openssh", "7.2", "p2"},
+		`cpe:2.3:a:foo:bar\:baz:1.0:*:*:*:*:*:*:*`:          {"a", "foo", "bar:baz", "1.0", "*"},
+		"cpe:/a:openbsd:openssh:7.2p2":                      {"a", "openbsd", "openssh", "7.2p2", "*"},
+		"cpe:/o:linux:linux_kernel":                         {"o", "linux", "linux_kernel", "*", "*"},
+	} {
+		got, ok := ParseCPE(in)
+		if !ok || got != want {
+			t.Errorf("ParseCPE(%q) = %+v, %v; want %+v", in, got, ok, want)
+		}
+	}
+	for _, in := range []string{"", "openssh", "cpe:/a:openbsd", "cpe:2.3:a::openssh"} {
+		if _, ok := ParseCPE(in); ok {
+			t.Errorf("ParseCPE(%q) ok, want rejected", in)
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	for _, c := range []struct {
+		a, b string
+		want int
+	}{
+		{"2.4.49", "2.4.49", 0},
+		{"2.4.9", "2.4.49", -1},
+		{"1.18.0", "1.2", 1},
+		{"7.2p2", "7.2", 1},
+		{"7.2p2", "7.3", -1},
+		{"8.9p1", "8.10", -1},
+		{"1.0.beta", "1.0.1", -1},
+	} {
+		got := compareVersions(c.a, c.b)
+		if got < 0 {
+			got = -1
+		} else if got > 0 {
+			got = 1
+		}
+		if got != c.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", c.a, c.b, got, c.want)
+		}
+	}
+}
+
+const nvdSample = `{"resultsPerPage": 3, "format": "NVD_CVE", "version": "2.0", "vulnerabilities": [
+{"cve": {"id": "CVE-2021-41773", "published": "2021-10-05T09:15:07.593",
+  "descriptions": [{"lang": "en", "value": "Path traversal in Apache HTTP Server 2.4.49."}],
+  "metrics": {"cvssMetricV31": [{"type": "Primary", "cvssData": {"baseScore": 7.5, "baseSeverity": "HIGH",
+    "vectorString": "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:N/A:N"}}]},
+  "configurations": [{"nodes": [{"cpeMatch": [
+    {"vulnerable": true, "criteria": "cpe:2.3:a:apache:http_server:2.4.49:*:*:*:*:*:*:*"}]}]}]}},
+{"cve": {"id": "CVE-2021-44790", "published": "2021-12-20T12:15:07.477",
+  "descriptions": [{"lang": "en", "value": "mod_lua buffer overflow."}],
+  "metrics": {"cvssMetricV31": [{"type": "Primary", "cvssData": {"baseScore": 9.8, "baseSeverity": "CRITICAL",
+    "vectorString": "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H"}}]},
+  "configurations": [{"nodes": [{"cpeMatch": [
+    {"vulnerable": true, "criteria": "cpe:2.3:a:apache:http_server:*:*:*:*:*:*:*:*", "versionEndIncluding": "2.4.51"},
+    {"vulnerable": false, "criteria": "cpe:2.3:o:debian:debian_linux:10.0:*:*:*:*:*:*:*"}]}]}]}},
+{"cve": {"id": "CVE-2016-6210", "published": "2017-02-13T18:59:00.327",
+  "descriptions": [{"lang": "en", "value": "OpenSSH user enumeration."}],
+  "metrics": {"cvssMetricV2": [{"type": "Primary", "baseSeverity": "MEDIUM", "cvssData": {"baseScore": 4.3,
+    "vectorString": "AV:N/AC:M/Au:N/C:P/I:N/A:N"}}]},
+  "configurations": [{"nodes": [{"cpeMatch": [
+    {"vulnerable": true, "criteria": "cpe:2.3:a:openbsd:openssh:*:*:*:*:*:*:*:*", "versionEndExcluding": "7.3"}]}]}]}}
+]}`
+
+const kevSample = `{"title": "CISA Catalog of Known Exploited Vulnerabilities", "catalogVersion": "2024.01.01", "count": 1,
+"vulnerabilities": [{"cveID": "CVE-2021-41773", "vulnerabilityName": "Apache HTTP Server Path Traversal",
+  "dateAdded": "2021-11-03", "dueDate": "2021-11-17", "knownRansomwareCampaignUse": "Known"}]}`
+
+func openTestStore(t *testing.T) *Store {
+	t.Helper()
+	s, err := Open(filepath.Join(t.TempDir(), "cve.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestImportAndMatch(t *testing.T) {
+	ctx := context.Background()
+	s := openTestStore(t)
+
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	w.Write([]byte(nvdSample))
+	w.Close()
+	res, err := s.Import(ctx, &gz, "nvd.json.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != (ImportResult{Kind: FeedNVD2, Records: 3}) {
+		t.Errorf("nvd import = %+v", res)
+	}
+	res, err = s.Import(ctx, strings.NewReader(kevSample), "kev.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != (ImportResult{Kind: FeedKEV, Records: 1}) {
+		t.Errorf("kev import = %+v", res)
+	}
+	// Re-importing replaces rather than duplicates.
+	if _, err := s.Import(ctx, strings.NewReader(nvdSample), "nvd.json"); err != nil {
+		t.Fatal(err)
+	}
+
+	st, err := s.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.CVEs != 3 || st.KEV != 1 || st.LastImport == "" {
+		t.Errorf("Status = %+v", st)
+	}
+
+	ids := func(ms []Match) []string {
+		var out []string
+		for _, m := range ms {
+			out = append(out, m.CVE)
+		}
+		return out
+	}
+	for _, c := range []struct {
+		cpe, product, version string
+		want                  []string
+	}{
+		{"cpe:/a:apache:http_server:2.4.49", "Apache httpd", "2.4.49", []string{"CVE-2021-44790", "CVE-2021-41773"}},
+		{"", "Apache httpd", "2.4.52", nil},
+		{"", "OpenSSH", "7.2p2 Ubuntu 4ubuntu2.8", []string{"CVE-2016-6210"}},
+		{"cpe:/a:openbsd:openssh:7.3", "OpenSSH", "7.3", nil},
+		{"", "OpenSSH", "", nil},
+	} {
+		got, err := s.Match(ctx, c.cpe, c.product, c.version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(ids(got), c.want) {
+			t.Errorf("Match(%q, %q, %q) = %v, want %v", c.cpe, c.product, c.version, ids(got), c.want)
+		}
+	}
+
+	got, _ := s.Match(ctx, "", "Apache httpd", "2.4.49")
+	if m := got[1]; !m.KEV || m.CVSS != 7.5 || m.Severity != "HIGH" {
+		t.Errorf("CVE-2021-41773 match = %+v, want KEV with CVSS 7.5", m)
+	}
+}
+
+func TestImportRejectsUnknownJSON(t *testing.T) {
+	if _, err := openTestStore(t).Import(context.Background(), strings.NewReader(`{"hello": []}`), "x.json"); err == nil {
+		t.Error("Import accepted a file that is neither NVD nor KEV")
+	}
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"embed"
+	"os"
+	"strings"
 
 	"github.com/wailsapp/wails/v2"
 	"github.com/wailsapp/wails/v2/pkg/options"
@@ -16,7 +18,7 @@ var assets embed.FS
 
 func main() {
 	// Create an instance of the app structure
-	app := NewApp()
+	app := NewApp(dbFlag(os.Args[1:]))
 
 	// Create application with options
 	err := wails.Run(&options.App{
@@ -38,3 +40,21 @@ func main() {
 		println("Error:", err.Error())
 	}
 }
+
+// dbFlag returns the value of -db/--db (as "-db path" or "-db=path"), or "".
+// Parsed by hand because Wails dev builds parse their own flags.
+func dbFlag(args []string) string {
+	for i, arg := range args {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || name != "db" {
+			continue
+		}
+		if hasValue {
+			return value
+		}
+		if i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}